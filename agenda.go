@@ -0,0 +1,257 @@
+package ics
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// AgendaTemplate is a text/template or html/template template rendering an Agenda
+type AgendaTemplate interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// Agenda is the occurrences of one or more calendars grouped by day
+type Agenda struct {
+	Title    string
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Days     []*AgendaDay
+}
+
+// AgendaDay is a day of the agenda with its occurrences sorted by start
+type AgendaDay struct {
+	Date  time.Time
+	Items []*AgendaItem
+}
+
+// AgendaItem is the part of an occurrence that falls on a day
+type AgendaItem struct {
+	Event    *Event
+	Calendar string
+	// start and end in the location of the agenda
+	Start time.Time
+	End   time.Time
+	// the occurrence started on a previous day
+	Continued bool
+	// the occurrence ends on a following day
+	Continues bool
+}
+
+// NewAgenda groups the occurrences between from and to by day in the location
+// of from. Repeating events must be expanded by the parser (RepeatRuleApply).
+func NewAgenda(from, to time.Time, calendars ...*Calendar) *Agenda {
+	loc := from.Location()
+	a := &Agenda{From: from, To: to, Location: loc}
+
+	names := []string{}
+	for _, cal := range calendars {
+		if cal.GetName() != "" {
+			names = append(names, cal.GetName())
+		}
+	}
+	a.Title = strings.Join(names, ", ")
+
+	first := truncateDay(from, loc)
+	byDay := map[string]*AgendaDay{}
+	for day := first; day.Before(to); day = day.AddDate(0, 0, 1) {
+		agendaDay := &AgendaDay{Date: day}
+		a.Days = append(a.Days, agendaDay)
+		byDay[day.Format(YmdHis)] = agendaDay
+	}
+
+	for _, cal := range calendars {
+		events := cal.GetEvents()
+		for i := range events {
+			event := &events[i]
+			start, end := event.Span(loc)
+			// instants at from are kept, occurrences ending at from are not
+			if !start.Before(to) || end.Before(from) || end.Equal(from) && end.After(start) {
+				continue
+			}
+			days := event.Days(loc)
+			for d, day := range days {
+				agendaDay, ok := byDay[day.Format(YmdHis)]
+				if !ok {
+					continue
+				}
+				agendaDay.Items = append(agendaDay.Items, &AgendaItem{
+					Event:     event,
+					Calendar:  cal.GetName(),
+					Start:     start,
+					End:       end,
+					Continued: d > 0,
+					Continues: d < len(days)-1,
+				})
+			}
+		}
+	}
+
+	for _, day := range a.Days {
+		sort.SliceStable(day.Items, func(i, j int) bool {
+			x, y := day.Items[i], day.Items[j]
+			if x.AllDay() != y.AllDay() {
+				return x.AllDay()
+			}
+			if !x.Start.Equal(y.Start) {
+				return x.Start.Before(y.Start)
+			}
+			return x.Summary() < y.Summary()
+		})
+	}
+	return a
+}
+
+// Span returns the start and end of the event in the location. Whole day
+// events are moved to the midnights of their dates and last at least a day.
+func (e *Event) Span(loc *time.Location) (time.Time, time.Time) {
+	if !e.IsWholeDay() {
+		return e.GetStart().In(loc), e.GetEnd().In(loc)
+	}
+	start := e.GetStart()
+	end := e.GetEnd()
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	// the end date of whole day events is exclusive
+	if !end.After(start) {
+		end = start.AddDate(0, 0, 1)
+	}
+	return start, end
+}
+
+// Days returns the midnights of the days the event falls on in the location.
+// An event ending at midnight doesn't fall on the day it ends.
+func (e *Event) Days(loc *time.Location) []time.Time {
+	start, end := e.Span(loc)
+	lastDay := truncateDay(end, loc)
+	if end.Equal(lastDay) && end.After(start) {
+		lastDay = lastDay.AddDate(0, 0, -1)
+	}
+	days := []time.Time{}
+	for day := truncateDay(start, loc); !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// returns the midnight of the day of t in the location
+func truncateDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// Render executes the template with the agenda
+func (a *Agenda) Render(w io.Writer, t AgendaTemplate) error {
+	return t.Execute(w, a)
+}
+
+// Empty reports whether there are no occurrences in the agenda
+func (a *Agenda) Empty() bool {
+	for _, day := range a.Days {
+		if len(day.Items) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Busy returns the days with at least one occurrence
+func (a *Agenda) Busy() []*AgendaDay {
+	days := []*AgendaDay{}
+	for _, day := range a.Days {
+		if len(day.Items) > 0 {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// AllDay reports whether the occurrence lasts the whole day
+func (i *AgendaItem) AllDay() bool {
+	return i.Event.IsWholeDay() || i.Continued && i.Continues
+}
+
+// Summary returns the summary without the iCalendar escapes
+func (i *AgendaItem) Summary() string {
+	return i.Event.plainText(i.Event.GetSummary())
+}
+
+// Description returns the description without the iCalendar escapes
+func (i *AgendaItem) Description() string {
+	return i.Event.plainText(i.Event.GetDescription())
+}
+
+// Location returns the location without the iCalendar escapes
+func (i *AgendaItem) Location() string {
+	return i.Event.plainText(i.Event.GetLocation())
+}
+
+// Time returns the time of the occurrence on the day like "09:00-10:00"
+func (i *AgendaItem) Time() string {
+	switch {
+	case i.AllDay():
+		return "all day"
+	case i.Continued:
+		return "until " + i.End.Format("15:04")
+	case i.Continues:
+		return "from " + i.Start.Format("15:04")
+	case i.Start.Equal(i.End):
+		return i.Start.Format("15:04")
+	}
+	return i.Start.Format("15:04") + "-" + i.End.Format("15:04")
+}
+
+// ======================== BUILT-IN TEMPLATES ===================
+
+// AgendaText renders the agenda as plain text
+var AgendaText = template.Must(template.New("text").Parse(
+	`{{if .Title}}{{.Title}}
+{{end}}{{range .Busy}}
+{{.Date.Format "Monday, 02 January 2006"}}
+{{range .Items}}  {{printf "%-13s" .Time}} {{.Summary}}{{if .Location}} ({{.Location}}){{end}}
+{{end}}{{else}}No events
+{{end}}`))
+
+// AgendaMarkdown renders the agenda as Markdown
+var AgendaMarkdown = template.Must(template.New("markdown").Funcs(template.FuncMap{"md": markdownEscape}).Parse(
+	`{{if .Title}}# {{md .Title}}
+{{end}}{{range .Busy}}
+## {{.Date.Format "Monday, 02 January 2006"}}
+
+{{range .Items}}- **{{.Time}}** {{md .Summary}}{{if .Location}} _({{md .Location}})_{{end}}
+{{end}}{{else}}
+_No events_
+{{end}}`))
+
+// AgendaHTML renders the agenda as a HTML fragment
+var AgendaHTML = htmltemplate.Must(htmltemplate.New("html").Parse(
+	`<div class="agenda">
+{{- if .Title}}
+<h1>{{.Title}}</h1>
+{{- end}}
+{{- range .Busy}}
+<h2><time datetime="{{.Date.Format "2006-01-02"}}">{{.Date.Format "Monday, 02 January 2006"}}</time></h2>
+<ul>
+{{- range .Items}}
+<li{{if .AllDay}} class="all-day"{{end}}><span class="time">{{.Time}}</span> <span class="summary">{{.Summary}}</span>
+{{- if .Location}} <span class="location">{{.Location}}</span>{{end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>No events</p>
+{{- end}}
+</div>
+`))
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "#", `\#`, "<", `&lt;`, "\n", " ",
+)
+
+// escapes the Markdown markup in the text
+func markdownEscape(text string) string {
+	return markdownEscaper.Replace(text)
+}
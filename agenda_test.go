@@ -0,0 +1,102 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func newAgendaCalendar(t *testing.T) *Calendar {
+	sofia, _ := time.LoadLocation("Europe/Sofia")
+	review, err := NewEventBuilder().UID("review@example.com").Summary("Design review").Location("Room 1").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, sofia), sofia).Duration(time.Hour).Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	standup, _ := NewEventBuilder().UID("standup@example.com").Summary("Stand-up *daily*").
+		Start(time.Date(2024, 6, 10, 8, 30, 0, 0, sofia), sofia).Duration(15 * time.Minute).Build()
+	release, _ := NewEventBuilder().UID("release@example.com").Summary("Release night").
+		Start(time.Date(2024, 6, 11, 22, 0, 0, 0, sofia), sofia).End(time.Date(2024, 6, 12, 2, 0, 0, 0, sofia), sofia).Build()
+	offsite, _ := NewEventBuilder().UID("offsite@example.com").Summary("Offsite").
+		AllDay(time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC), 1).Build()
+	old, _ := NewEventBuilder().UID("old@example.com").Summary("Old").
+		Start(time.Date(2024, 6, 1, 9, 0, 0, 0, sofia), sofia).Duration(time.Hour).Build()
+	cal, err := NewCalendarBuilder().Name("Team").Event(review).Event(standup).Event(release).Event(offsite).Event(old).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+	return cal
+}
+
+func TestAgendaGrouping(t *testing.T) {
+	sofia, _ := time.LoadLocation("Europe/Sofia")
+	from := time.Date(2024, 6, 10, 0, 0, 0, 0, sofia)
+	agenda := NewAgenda(from, from.AddDate(0, 0, 7), newAgendaCalendar(t))
+
+	if len(agenda.Days) != 7 {
+		t.Fatalf("Expected 7 days, found %d", len(agenda.Days))
+	}
+	for i, expected := range [][]string{
+		{"08:30-08:45 Stand-up *daily*", "09:00-10:00 Design review"},
+		{"from 22:00 Release night"},
+		{"all day Offsite", "until 02:00 Release night"},
+		{},
+	} {
+		found := []string{}
+		for _, item := range agenda.Days[i].Items {
+			found = append(found, item.Time()+" "+item.Summary())
+		}
+		if strings.Join(found, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected day %d to have %v, found %v", i, expected, found)
+		}
+	}
+	if len(agenda.Busy()) != 3 || agenda.Empty() {
+		t.Errorf("Expected 3 busy days, found %d", len(agenda.Busy()))
+	}
+
+	empty := NewAgenda(from.AddDate(1, 0, 0), from.AddDate(1, 0, 1), newAgendaCalendar(t))
+	if !empty.Empty() {
+		t.Errorf("Expected empty agenda a year later")
+	}
+}
+
+func TestAgendaTemplates(t *testing.T) {
+	sofia, _ := time.LoadLocation("Europe/Sofia")
+	from := time.Date(2024, 6, 10, 0, 0, 0, 0, sofia)
+	agenda := NewAgenda(from, from.AddDate(0, 0, 1), newAgendaCalendar(t))
+
+	for name, test := range map[string]struct {
+		template AgendaTemplate
+		expected string
+	}{
+		"text": {AgendaText, "Team\n\nMonday, 10 June 2024\n  08:30-08:45   Stand-up *daily*\n  09:00-10:00   Design review (Room 1)\n"},
+		"markdown": {AgendaMarkdown, "# Team\n\n## Monday, 10 June 2024\n\n" +
+			"- **08:30-08:45** Stand-up \\*daily\\*\n- **09:00-10:00** Design review _(Room 1)_\n"},
+		"html": {AgendaHTML, `<div class="agenda">
+<h1>Team</h1>
+<h2><time datetime="2024-06-10">Monday, 10 June 2024</time></h2>
+<ul>
+<li><span class="time">08:30-08:45</span> <span class="summary">Stand-up *daily*</span></li>
+<li><span class="time">09:00-10:00</span> <span class="summary">Design review</span> <span class="location">Room 1</span></li>
+</ul>
+</div>
+`},
+	} {
+		var b bytes.Buffer
+		if err := agenda.Render(&b, test.template); err != nil {
+			t.Errorf("Failed to render %s ( %s )", name, err)
+			continue
+		}
+		if b.String() != test.expected {
+			t.Errorf("Expected %s agenda:\n%s\nfound:\n%s", name, test.expected, b.String())
+		}
+	}
+
+	custom := template.Must(template.New("custom").Parse(`{{range .Busy}}{{len .Items}}{{end}}`))
+	var b bytes.Buffer
+	if err := agenda.Render(&b, custom); err != nil || b.String() != "2" {
+		t.Errorf("Expected custom template to render 2, found %q ( %v )", b.String(), err)
+	}
+}
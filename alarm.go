@@ -0,0 +1,65 @@
+package ics
+
+import (
+	"time"
+)
+
+// alarm actions of the VALARM ACTION property
+const (
+	AlarmDisplay = "DISPLAY"
+	AlarmAudio   = "AUDIO"
+	AlarmEmail   = "EMAIL"
+)
+
+// Alarm is a VALARM component attached to an event
+type Alarm struct {
+	action      string
+	trigger     time.Duration
+	summary     string
+	description string
+}
+
+// NewAlarm creates a new display alarm
+func NewAlarm() *Alarm {
+	a := new(Alarm)
+	a.action = AlarmDisplay
+	return a
+}
+
+func (a *Alarm) SetAction(action string) *Alarm {
+	a.action = action
+	return a
+}
+
+func (a *Alarm) GetAction() string {
+	return a.action
+}
+
+// SetTrigger sets when the alarm fires, relative to the start of the event.
+// Negative values fire before the start.
+func (a *Alarm) SetTrigger(trigger time.Duration) *Alarm {
+	a.trigger = trigger
+	return a
+}
+
+func (a *Alarm) GetTrigger() time.Duration {
+	return a.trigger
+}
+
+func (a *Alarm) SetSummary(summary string) *Alarm {
+	a.summary = summary
+	return a
+}
+
+func (a *Alarm) GetSummary() string {
+	return a.summary
+}
+
+func (a *Alarm) SetDescription(description string) *Alarm {
+	a.description = description
+	return a
+}
+
+func (a *Alarm) GetDescription() string {
+	return a.description
+}
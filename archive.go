@@ -0,0 +1,104 @@
+package ics
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// icalFile is the content of a calendar, with its path in a zip archive
+type icalFile struct {
+	path    string
+	content string
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// the most calendars of a zip archive
+const maxArchiveFiles = 1000
+
+// returns the calendars of the data of the url. The gzip data is unpacked,
+// and a zip archive gives a calendar for each .ics or .ics.gz file in it.
+// The unpacked files of an archive are limited to maxBytes together.
+func unpackICal(data []byte, maxBytes int64, url string) ([]icalFile, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		var err error
+		if data, err = gunzip(data, maxBytes, maxBytes, url); err != nil {
+			return nil, err
+		}
+	}
+	if !bytes.HasPrefix(data, zipMagic) {
+		return []icalFile{{content: string(data)}}, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open the zip archive %s ( %s )", Redact(url), err)
+	}
+	files := []icalFile{}
+	// the bytes left of maxBytes for the next files
+	remaining := maxBytes
+	for _, f := range archive.File {
+		name := strings.ToLower(f.Name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, "__macosx/") || strings.HasPrefix(path.Base(name), "._") {
+			continue
+		}
+		if !strings.HasSuffix(name, ".ics") && !strings.HasSuffix(name, ".ics.gz") {
+			continue
+		}
+		if len(files) == maxArchiveFiles {
+			return nil, fmt.Errorf("The zip archive %s has more than %d calendars", Redact(url), maxArchiveFiles)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open %s in %s ( %s )", f.Name, Redact(url), err)
+		}
+		content, err := ioutil.ReadAll(budgetBody(r, maxBytes, remaining, url))
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(content, gzipMagic) {
+			if content, err = gunzip(content, maxBytes, remaining, url); err != nil {
+				return nil, err
+			}
+		}
+		remaining -= int64(len(content))
+		files = append(files, icalFile{path: f.Name, content: string(content)})
+	}
+	return files, nil
+}
+
+// returns the body failing with the LimitError of maxBytes after remaining
+// bytes, the body itself if maxBytes is 0
+func budgetBody(body io.ReadCloser, maxBytes, remaining int64, url string) io.ReadCloser {
+	if maxBytes <= 0 {
+		return body
+	}
+	return &limitedBody{body, remaining, newLimitError("MaxBytes", maxBytes, url)}
+}
+
+// returns the unpacked gzip data, limited to the remaining bytes of maxBytes
+func gunzip(data []byte, maxBytes, remaining int64, url string) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unpack %s ( %s )", Redact(url), err)
+	}
+	defer r.Close()
+	content, err := ioutil.ReadAll(budgetBody(r, maxBytes, remaining, url))
+	if err != nil {
+		if _, ok := err.(*LimitError); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Failed to unpack %s ( %s )", Redact(url), err)
+	}
+	return content, nil
+}
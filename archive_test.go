@@ -0,0 +1,145 @@
+package ics
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func gzipped(content string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(content))
+	w.Close()
+	return b.Bytes()
+}
+
+// a Google Takeout like archive with a calendar per file
+func takeoutZip() []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	files := map[string][]byte{
+		"Takeout/Calendar/Work.ics":            []byte(strings.Replace(fetchTestCal, "Fetched", "Work", 1)),
+		"Takeout/Calendar/Home.ics.gz":         gzipped(strings.Replace(fetchTestCal, "Fetched", "Home", 1)),
+		"Takeout/archive_browser.html":         []byte("<html></html>"),
+		"__MACOSX/Takeout/Calendar/._Work.ics": []byte("junk"),
+	}
+	for name, data := range files {
+		f, _ := w.Create(name)
+		f.Write(data)
+	}
+	w.Close()
+	return b.Bytes()
+}
+
+func TestGzipAndZipFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	gz := filepath.Join(dir, "cal.ics.gz")
+	ioutil.WriteFile(gz, gzipped(fetchTestCal), 0666)
+	takeout := filepath.Join(dir, "takeout.zip")
+	ioutil.WriteFile(takeout, takeoutZip(), 0666)
+
+	calendars, errs := fetchAll(New(), gz, takeout)
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, found %v", errs)
+	}
+	found := []string{}
+	for _, cal := range calendars {
+		found = append(found, cal.GetName()+" "+cal.GetArchivePath()+" "+filepath.Base(cal.GetUrl()))
+	}
+	sort.Strings(found)
+	expected := []string{
+		"Fetched  cal.ics.gz",
+		"Home Takeout/Calendar/Home.ics.gz takeout.zip",
+		"Work Takeout/Calendar/Work.ics takeout.zip",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the calendars\n%s\nfound\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
+
+func TestGzipContentEncoding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped(fetchTestCal))
+	}))
+	defer server.Close()
+
+	// the default transport unpacks the body, without it the parser does
+	for _, client := range []*http.Client{http.DefaultClient, {Transport: &http.Transport{DisableCompression: true}}} {
+		calendars, errs := fetchAll(New().SetHTTPClient(client), server.URL+"/cal.ics")
+		if len(calendars) != 1 || calendars[0].GetName() != "Fetched" || len(errs) != 0 {
+			t.Errorf("Expected the unpacked calendar, found %d calendars and %v", len(calendars), errs)
+		}
+	}
+}
+
+func TestArchiveLimits(t *testing.T) {
+	bomb := "data:application/gzip;base64," + base64.StdEncoding.EncodeToString(gzipped(fetchTestCal+strings.Repeat("X-FILLER:0\r\n", 100000)))
+	zipped := "data:application/zip;base64," + base64.StdEncoding.EncodeToString(takeoutZip())
+	calendars, errs := fetchAll(New().SetLimits(Limits{MaxBytes: 4096}), bomb, zipped)
+	if len(calendars) != 2 || len(errs) != 1 {
+		t.Fatalf("Expected 2 calendars and 1 error, found %d and %v", len(calendars), errs)
+	}
+	if _, ok := errs[0].(*LimitError); !ok {
+		t.Errorf("Expected a LimitError, found %#v", errs[0])
+	}
+
+	_, errs = fetchAll(New(), "data:application/zip;base64,"+base64.StdEncoding.EncodeToString([]byte("PK\x03\x04broken")))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "zip") {
+		t.Errorf("Expected 1 error of the broken zip, found %v", errs)
+	}
+}
+
+// a zip archive of n copies of the calendar
+func zipOf(n int, content string) []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for i := 0; i < n; i++ {
+		f, _ := w.Create(fmt.Sprintf("cal%d.ics", i))
+		f.Write([]byte(content))
+	}
+	w.Close()
+	return b.Bytes()
+}
+
+func TestArchiveTotalLimits(t *testing.T) {
+	// every file is within MaxBytes but not all of them
+	max := int64(len(fetchTestCal)) * 3
+	if _, err := unpackICal(zipOf(3, fetchTestCal), max, "archive.zip"); err != nil {
+		t.Errorf("Expected the files within MaxBytes, found %s", err)
+	}
+	_, err := unpackICal(zipOf(4, fetchTestCal), max, "archive.zip")
+	if limitErr, ok := err.(*LimitError); !ok || limitErr.Max != max {
+		t.Errorf("Expected a LimitError of %d bytes, found %#v", max, err)
+	}
+
+	if _, err := unpackICal(zipOf(maxArchiveFiles, "BEGIN:VCALENDAR"), 0, "archive.zip"); err != nil {
+		t.Errorf("Expected %d files, found %s", maxArchiveFiles, err)
+	}
+	if _, err := unpackICal(zipOf(maxArchiveFiles+1, "BEGIN:VCALENDAR"), 0, "archive.zip"); err == nil {
+		t.Errorf("Expected an error over %d files", maxArchiveFiles)
+	}
+}
@@ -10,6 +10,7 @@ type Attendee struct {
 	status string
 	role   string
 	cutype string
+	rsvp   bool
 }
 
 func NewAttendee() *Attendee {
@@ -62,6 +63,20 @@ func (a *Attendee) GetType() string {
 	return a.cutype
 }
 
+func (a *Attendee) SetRSVP(rsvp bool) *Attendee {
+	a.rsvp = rsvp
+	return a
+}
+
+func (a *Attendee) GetRSVP() bool {
+	return a.rsvp
+}
+
+func (a *Attendee) Clone() *Attendee {
+	newA := *a
+	return &newA
+}
+
 func (a *Attendee) String() string {
 
 	return fmt.Sprintf("%s with email %s", a.name, a.email)
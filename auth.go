@@ -0,0 +1,359 @@
+package ics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials authorize the requests of the calendars of a source
+type Credentials interface {
+	Authorize(r *http.Request) error
+}
+
+// the text shown instead of the secrets
+const redacted = "xxxxx"
+
+// BasicAuth is the user name and password of the HTTP Basic authentication
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authorize sets the Authorization header of the request
+func (a BasicAuth) Authorize(r *http.Request) error {
+	r.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+func (a BasicAuth) String() string {
+	return "Basic " + a.Username + ":" + redacted
+}
+
+// BearerToken is a static token sent as "Authorization: Bearer <token>"
+type BearerToken string
+
+// Authorize sets the Authorization header of the request
+func (t BearerToken) Authorize(r *http.Request) error {
+	r.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+func (t BearerToken) String() string {
+	return "Bearer " + redacted
+}
+
+// Headers are custom headers of the requests, like X-Api-Key
+type Headers map[string]string
+
+// Authorize sets the headers of the request
+func (h Headers) Authorize(r *http.Request) error {
+	for name, value := range h {
+		r.Header.Set(name, value)
+	}
+	return nil
+}
+
+func (h Headers) String() string {
+	names := []string{}
+	for name := range h {
+		names = append(names, name+": "+redacted)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// QueryParam is a secret parameter added to the url, like ?token=...
+type QueryParam struct {
+	Name  string
+	Value string
+}
+
+// Authorize adds the parameter to the url of the request
+func (q QueryParam) Authorize(r *http.Request) error {
+	query := r.URL.Query()
+	query.Set(q.Name, q.Value)
+	r.URL.RawQuery = query.Encode()
+	return nil
+}
+
+func (q QueryParam) String() string {
+	return q.Name + "=" + redacted
+}
+
+// Token is an access token and the time it expires, zero if it does not
+type Token struct {
+	AccessToken string
+	// the type of the Authorization header, Bearer when empty
+	TokenType string
+	Expiry    time.Time
+}
+
+// valid reports whether the token is usable for a while more
+func (t *Token) valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(10*time.Second).Before(t.Expiry))
+}
+
+// TokenSource returns access tokens, like an OAuth2 client refreshing them
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// TokenSourceFunc is a function used as TokenSource
+type TokenSourceFunc func() (*Token, error)
+
+// Token calls f()
+func (f TokenSourceFunc) Token() (*Token, error) {
+	return f()
+}
+
+// OAuth2 sends the tokens of a source, a token is reused until it expires
+type OAuth2 struct {
+	source TokenSource
+	mutex  sync.Mutex
+	token  *Token
+}
+
+// NewOAuth2 creates the credentials of the token source
+func NewOAuth2(source TokenSource) *OAuth2 {
+	return &OAuth2{source: source}
+}
+
+// Token returns the current token, a new one from the source if it expired
+func (o *OAuth2) Token() (*Token, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.token.valid() {
+		return o.token, nil
+	}
+	token, err := o.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("The token source returned no token")
+	}
+	o.token = token
+	return token, nil
+}
+
+// Authorize sets the Authorization header of the request
+func (o *OAuth2) Authorize(r *http.Request) error {
+	token, err := o.Token()
+	if err != nil {
+		return err
+	}
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	r.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return nil
+}
+
+func (o *OAuth2) String() string {
+	return "OAuth2 " + redacted
+}
+
+// SetCredentials sets the credentials of the http, https, webcal and webcals
+// urls of the scheme and host of the prefix with a path under its path, the
+// longest matching prefix is used. A nil credentials removes the prefix.
+func (p *Parser) SetCredentials(prefix string, c Credentials) *Parser {
+	web := p.httpFetcher()
+	credentials := map[string]Credentials{}
+	for k, v := range web.Credentials {
+		credentials[k] = v
+	}
+	if c == nil {
+		delete(credentials, prefix)
+	} else {
+		credentials[prefix] = c
+	}
+	web.Credentials = credentials
+	p.setHTTPFetcher(web)
+	return p
+}
+
+// returns the credentials of the longest prefix of the url
+func (f *HTTPFetcher) credentialsOf(url string) Credentials {
+	var found Credentials
+	longest := -1
+	target := webcalToHTTP(url)
+	for prefix, c := range f.Credentials {
+		if len(prefix) > longest && (hasURLPrefix(url, prefix) || hasURLPrefix(target, prefix)) {
+			found, longest = c, len(prefix)
+		}
+	}
+	return found
+}
+
+// reports whether the url has the scheme and host of the prefix and its path
+// starts with the segments of the prefix path, so https://cal.example.com
+// does not match https://cal.example.com.evil.net
+func hasURLPrefix(url, prefix string) bool {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return false
+	}
+	p, err := neturl.Parse(prefix)
+	if err != nil || p.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(u.Scheme, p.Scheme) || !strings.EqualFold(u.Host, p.Host) {
+		return false
+	}
+	path := strings.TrimSuffix(p.Path, "/")
+	return path == "" || u.Path == path || strings.HasPrefix(u.Path, path+"/")
+}
+
+// the key of the names of the headers set by the credentials in the context
+// of the request
+type credentialHeadersKey struct{}
+
+// authorizes the request and keeps the names of the headers the credentials
+// set, they are removed from the redirects to other hosts
+func authorize(request *http.Request, c Credentials) (*http.Request, error) {
+	before := map[string]string{}
+	for name, values := range request.Header {
+		before[name] = strings.Join(values, "\n")
+	}
+	if err := c.Authorize(request); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name, values := range request.Header {
+		if value, ok := before[name]; !ok || value != strings.Join(values, "\n") {
+			names = append(names, name)
+		}
+	}
+	return request.WithContext(context.WithValue(request.Context(), credentialHeadersKey{}, names)), nil
+}
+
+// returns a copy of the client that sends the redirects to other hosts
+// without the headers of the credentials
+func withoutCredentialsOnRedirect(client *http.Client) *http.Client {
+	stripped := *client
+	checkRedirect := client.CheckRedirect
+	stripped.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		names, _ := request.Context().Value(credentialHeadersKey{}).([]string)
+		if len(via) > 0 && !strings.EqualFold(request.URL.Host, via[0].URL.Host) {
+			for _, name := range names {
+				request.Header.Del(name)
+			}
+		}
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &stripped
+}
+
+// matches the names of the query parameters with secrets, for the ones not
+// registered with a QueryParam
+var secretParamRegex = regexp.MustCompile(`(?i)(token|key|secret|pass|sig|auth|credential)`)
+
+// reports whether the parameter is the one of a QueryParam of the credentials
+func (f *HTTPFetcher) isSecretParam(name string) bool {
+	for _, c := range f.Credentials {
+		switch q := c.(type) {
+		case QueryParam:
+			if q.Name == name {
+				return true
+			}
+		case *QueryParam:
+			if q != nil && q.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hides the secrets of the url and the parameters of the QueryParam credentials
+func (f *HTTPFetcher) redact(url string) string {
+	return redactURL(url, f.isSecretParam)
+}
+
+// hides the secrets of the url and the parameters of the QueryParam
+// credentials of the http fetcher
+func (p *Parser) redact(url string) string {
+	if web, ok := p.GetFetcher("http").(*HTTPFetcher); ok {
+		return web.redact(url)
+	}
+	return Redact(url)
+}
+
+// Redact hides the user info and the query parameters named like secrets of
+// the url, for errors and logs
+func Redact(url string) string {
+	return redactURL(url, nil)
+}
+
+// hides the user info and the query parameters named like secrets or
+// reported by isSecret
+func redactURL(url string, isSecret func(name string) bool) string {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return url
+	}
+	changed := false
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = neturl.UserPassword(u.User.Username(), redacted)
+		} else {
+			// a user without password is a token
+			u.User = neturl.User(redacted)
+		}
+		changed = true
+	}
+	query := u.Query()
+	for name, values := range query {
+		if secretParamRegex.MatchString(name) || isSecret != nil && isSecret(name) {
+			for i := range values {
+				values[i] = redacted
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return url
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// redactedError is an error with the secrets of the urls hidden
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// returns the error with the secrets of the urls hidden
+func (f *HTTPFetcher) redactError(err error, urls ...string) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	for _, url := range urls {
+		message = strings.Replace(message, url, f.redact(url), -1)
+	}
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, message: message}
+}
@@ -0,0 +1,315 @@
+package ics
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// EventBuilder creates events from scratch with a fluent interface.
+// Setter errors are collected and returned by Build.
+type EventBuilder struct {
+	event  *Event
+	recur  *RRule
+	errors []string
+}
+
+// NewEventBuilder creates a builder for a new event
+func NewEventBuilder() *EventBuilder {
+	b := new(EventBuilder)
+	b.event = NewEvent()
+	return b
+}
+
+// UID sets the UID of the event, if not set one is generated by Build
+func (b *EventBuilder) UID(uid string) *EventBuilder {
+	b.event.SetImportedID(uid)
+	return b
+}
+
+func (b *EventBuilder) Summary(summary string) *EventBuilder {
+	b.event.SetSummary(summary)
+	return b
+}
+
+func (b *EventBuilder) Description(description string) *EventBuilder {
+	b.event.SetDescription(description)
+	return b
+}
+
+func (b *EventBuilder) Location(location string) *EventBuilder {
+	b.event.SetLocation(location)
+	return b
+}
+
+func (b *EventBuilder) Geo(lat, long float64) *EventBuilder {
+	b.event.SetGeo(NewGeo(fmt.Sprintf("%f", lat), fmt.Sprintf("%f", long)))
+	return b
+}
+
+// Start sets the start of the event, written with the TZID of loc.
+// A nil loc keeps the location of t.
+func (b *EventBuilder) Start(t time.Time, loc *time.Location) *EventBuilder {
+	if t.IsZero() {
+		b.fail("start time is zero")
+		return b
+	}
+	if loc == nil {
+		loc = t.Location()
+	}
+	b.event.SetStart(t.In(loc))
+	b.event.SetStartTZID(tzidOf(loc))
+	return b
+}
+
+// End sets the end of the event, written with the TZID of loc.
+// A nil loc keeps the location of t.
+func (b *EventBuilder) End(t time.Time, loc *time.Location) *EventBuilder {
+	if t.IsZero() {
+		b.fail("end time is zero")
+		return b
+	}
+	if loc == nil {
+		loc = t.Location()
+	}
+	b.event.SetEnd(t.In(loc))
+	b.event.SetEndTZID(tzidOf(loc))
+	return b
+}
+
+// Duration sets the end of the event relative to its start
+func (b *EventBuilder) Duration(d time.Duration) *EventBuilder {
+	if d < 0 {
+		b.fail(fmt.Sprintf("negative duration %s", d))
+		return b
+	}
+	if b.event.GetStart().IsZero() {
+		b.fail("duration set before the start")
+		return b
+	}
+	b.event.SetEnd(b.event.GetStart().Add(d))
+	b.event.SetEndTZID(b.event.GetStartTZID())
+	return b
+}
+
+// AllDay makes a whole day event lasting the given number of days
+func (b *EventBuilder) AllDay(date time.Time, days int) *EventBuilder {
+	if days < 1 {
+		b.fail(fmt.Sprintf("whole day event must last at least 1 day, got %d", days))
+		return b
+	}
+	// the parser keeps whole day events at midnight UTC
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	b.event.SetStart(start)
+	b.event.SetEnd(start.AddDate(0, 0, days))
+	b.event.SetStartTZID("")
+	b.event.SetEndTZID("")
+	b.event.SetWholeDayEvent(true)
+	return b
+}
+
+// Organizer sets the ORGANIZER with its common name
+func (b *EventBuilder) Organizer(name, email string) *EventBuilder {
+	if email == "" {
+		b.fail("organizer email is empty")
+		return b
+	}
+	b.event.SetOrganizer(NewAttendee().SetName(name).SetEmail(email))
+	return b
+}
+
+// Attendee invites an attendee, missing role and status get the RFC 5545 defaults
+func (b *EventBuilder) Attendee(a *Attendee) *EventBuilder {
+	if a == nil || a.GetEmail() == "" {
+		b.fail("attendee without email")
+		return b
+	}
+	// the defaults go on a copy so the attendee of the caller stays as it is
+	a = a.Clone()
+	if a.GetType() == "" {
+		a.SetType("INDIVIDUAL")
+	}
+	if a.GetRole() == "" {
+		a.SetRole("REQ-PARTICIPANT")
+	}
+	if a.GetStatus() == "" {
+		a.SetStatus("NEEDS-ACTION")
+	}
+	b.event.SetAttendee(a)
+	return b
+}
+
+// Recur makes the event repeat by the rule
+func (b *EventBuilder) Recur(r *RRule) *EventBuilder {
+	if r == nil {
+		b.fail("rrule is nil")
+		return b
+	}
+	if err := r.Validate(); err != nil {
+		b.fail(err.Error())
+		return b
+	}
+	b.recur = r
+	return b
+}
+
+// Alarm attaches a VALARM to the event
+func (b *EventBuilder) Alarm(a *Alarm) *EventBuilder {
+	if a == nil {
+		b.fail("alarm is nil")
+		return b
+	}
+	switch a.GetAction() {
+	case AlarmDisplay, AlarmAudio, AlarmEmail:
+	default:
+		b.fail(fmt.Sprintf("unknown alarm action %s", a.GetAction()))
+		return b
+	}
+	b.event.AddAlarm(a)
+	return b
+}
+
+func (b *EventBuilder) Status(status string) *EventBuilder {
+	b.event.SetStatus(status)
+	return b
+}
+
+func (b *EventBuilder) Class(class string) *EventBuilder {
+	b.event.SetClass(class)
+	return b
+}
+
+func (b *EventBuilder) Sequence(sq int) *EventBuilder {
+	b.event.SetSequence(sq)
+	return b
+}
+
+// Build validates the event and fills UID, DTSTAMP, CREATED and the end time
+func (b *EventBuilder) Build() (*Event, error) {
+	e := b.event
+	errs := append([]string{}, b.errors...)
+
+	if e.GetStart().IsZero() {
+		errs = append(errs, "start time is required")
+	}
+	if e.GetEnd().IsZero() {
+		e.SetEnd(e.GetStart())
+		e.SetEndTZID(e.GetStartTZID())
+	}
+	if e.GetEnd().Before(e.GetStart()) {
+		errs = append(errs, "end is before start")
+	}
+	if len(e.GetAttendees()) > 0 && e.GetOrganizer() == nil {
+		errs = append(errs, "events with attendees need an organizer")
+	}
+	if b.recur != nil && !b.recur.Until.IsZero() && b.recur.Until.Before(e.GetStart()) {
+		errs = append(errs, "rrule until is before start")
+	}
+	if len(errs) > 0 {
+		return nil, errors.New("invalid event: " + strings.Join(errs, "; "))
+	}
+
+	if e.GetImportedID() == "" {
+		e.SetImportedID(GenerateUID())
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if e.GetDTStamp().IsZero() {
+		e.SetDTStamp(now)
+	}
+	if e.GetCreated().IsZero() {
+		e.SetCreated(now)
+	}
+	if e.GetLastModified().IsZero() {
+		e.SetLastModified(now)
+	}
+	if b.recur != nil {
+		e.SetRRule(b.recur.String())
+	}
+	e.SetID(e.GenerateEventId())
+
+	// a builder builds a single event
+	b.event = e.Clone()
+	return e, nil
+}
+
+func (b *EventBuilder) fail(err string) {
+	b.errors = append(b.errors, err)
+}
+
+// CalendarBuilder creates calendars with a fluent interface
+type CalendarBuilder struct {
+	calendar *Calendar
+	events   []*Event
+}
+
+// NewCalendarBuilder creates a builder for a new calendar in UTC
+func NewCalendarBuilder() *CalendarBuilder {
+	b := new(CalendarBuilder)
+	b.calendar = NewCalendar()
+	b.calendar.SetVersion(2.0)
+	b.calendar.SetTimezone(*time.UTC)
+	return b
+}
+
+func (b *CalendarBuilder) Name(name string) *CalendarBuilder {
+	b.calendar.SetName(name)
+	return b
+}
+
+func (b *CalendarBuilder) Description(desc string) *CalendarBuilder {
+	b.calendar.SetDesc(desc)
+	return b
+}
+
+func (b *CalendarBuilder) Timezone(loc *time.Location) *CalendarBuilder {
+	b.calendar.SetTimezone(*loc)
+	return b
+}
+
+func (b *CalendarBuilder) Event(e *Event) *CalendarBuilder {
+	b.events = append(b.events, e)
+	return b
+}
+
+// Build adds the events to the calendar
+func (b *CalendarBuilder) Build() (*Calendar, error) {
+	for _, e := range b.events {
+		if e.GetStart().IsZero() {
+			return nil, fmt.Errorf("event %s has no start time", e.GetImportedID())
+		}
+		if _, err := b.calendar.SetEvent(*e); err != nil {
+			return nil, err
+		}
+	}
+	return b.calendar, nil
+}
+
+// GenerateUID returns a random globally unique UID for a new event
+func GenerateUID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// fallback to the clock when there is no randomness
+		return fmt.Sprintf("%d@%s", time.Now().UnixNano(), uidDomain())
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x@%s", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:], uidDomain())
+}
+
+func uidDomain() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "localhost"
+	}
+	return host
+}
+
+// returns the TZID written for a location
+func tzidOf(loc *time.Location) string {
+	name := loc.String()
+	if name == "UTC" || name == "Local" {
+		return ""
+	}
+	return name
+}
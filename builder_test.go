@@ -0,0 +1,256 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventBuilder(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Sofia")
+	if err != nil {
+		t.Fatalf("Failed to load location ( %s )", err)
+	}
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, loc)
+	rrule := NewRRule(FreqWeekly)
+	rrule.Count = 4
+	rrule.ByDay = []string{"MO"}
+
+	event, err := NewEventBuilder().
+		Summary("Weekly sync").
+		Description("Agenda:\nstatus; blockers").
+		Start(start, loc).
+		Duration(30*time.Minute).
+		Organizer("Boss", "boss@example.com").
+		Attendee(NewAttendee().SetName("John Smith").SetEmail("j.smith@example.com")).
+		Recur(rrule).
+		Alarm(NewAlarm().SetTrigger(-15 * time.Minute)).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+
+	if event.GetImportedID() == "" {
+		t.Errorf("Expected generated UID, found none")
+	}
+	if event.GetDTStamp().IsZero() {
+		t.Errorf("Expected DTSTAMP to be set")
+	}
+	if !event.GetEnd().Equal(start.Add(30 * time.Minute)) {
+		t.Errorf("Expected end %s, found %s", start.Add(30*time.Minute), event.GetEnd())
+	}
+	if event.GetStartTZID() != "Europe/Sofia" {
+		t.Errorf("Expected TZID %s, found %s", "Europe/Sofia", event.GetStartTZID())
+	}
+	if event.GetRRule() != "FREQ=WEEKLY;COUNT=4;BYDAY=MO" {
+		t.Errorf("Expected rrule %s, found %s", "FREQ=WEEKLY;COUNT=4;BYDAY=MO", event.GetRRule())
+	}
+	if status := event.GetAttendees()[0].GetStatus(); status != "NEEDS-ACTION" {
+		t.Errorf("Expected attendee status %s, found %s", "NEEDS-ACTION", status)
+	}
+
+	ics := event.Serialize()
+	for _, line := range []string{
+		"BEGIN:VEVENT\r\n",
+		"UID:" + event.GetImportedID() + "\r\n",
+		"DTSTART;TZID=Europe/Sofia:20240304T100000\r\n",
+		"DTEND;TZID=Europe/Sofia:20240304T103000\r\n",
+		"ORGANIZER;CN=Boss:mailto:boss@example.com\r\n",
+		"ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;CN=John Smith:mailto:j.smith@example.com\r\n",
+		"DESCRIPTION:Agenda:\\nstatus\\; blockers\r\n",
+		"RRULE:FREQ=WEEKLY;COUNT=4;BYDAY=MO\r\n",
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT15M\r\nDESCRIPTION:Weekly sync\r\nEND:VALARM\r\n",
+	} {
+		if !strings.Contains(unfold(ics), line) {
+			t.Errorf("Expected serialized event to contain %q, found:\n%s", line, ics)
+		}
+	}
+}
+
+func TestEventBuilderValidation(t *testing.T) {
+	_, err := NewEventBuilder().Summary("No start").Build()
+	if err == nil {
+		t.Errorf("Expected error for event without start")
+	}
+
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	_, err = NewEventBuilder().Start(start, nil).End(start.Add(-time.Hour), nil).Build()
+	if err == nil || !strings.Contains(err.Error(), "end is before start") {
+		t.Errorf("Expected end before start error, found %v", err)
+	}
+
+	_, err = NewEventBuilder().Start(start, nil).Attendee(NewAttendee().SetEmail("a@example.com")).Build()
+	if err == nil || !strings.Contains(err.Error(), "organizer") {
+		t.Errorf("Expected missing organizer error, found %v", err)
+	}
+
+	rrule := NewRRule(FreqDaily)
+	rrule.Count = 2
+	rrule.Until = start.AddDate(0, 0, 5)
+	_, err = NewEventBuilder().Start(start, nil).Recur(rrule).Build()
+	if err == nil || !strings.Contains(err.Error(), "COUNT and UNTIL") {
+		t.Errorf("Expected COUNT and UNTIL error, found %v", err)
+	}
+}
+
+func TestEventBuilderAllDay(t *testing.T) {
+	event, err := NewEventBuilder().UID("holiday@example.com").Summary("Holiday").AllDay(time.Date(2024, 5, 6, 15, 0, 0, 0, time.Local), 2).Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	if !event.IsWholeDay() {
+		t.Errorf("Expected whole day event")
+	}
+	ics := event.Serialize()
+	if !strings.Contains(ics, "DTSTART;VALUE=DATE:20240506\r\n") || !strings.Contains(ics, "DTEND;VALUE=DATE:20240508\r\n") {
+		t.Errorf("Expected DATE values in serialized event, found:\n%s", ics)
+	}
+}
+
+func TestEventBuilderAttendeeDefaults(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	attendee := NewAttendee().SetName("Sue").SetEmail("sue@example.com")
+	event, err := NewEventBuilder().Start(start, nil).Organizer("Boss", "boss@example.com").Attendee(attendee).Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	// the defaults used to be set on the attendee of the caller
+	if attendee.GetType() != "" || attendee.GetRole() != "" || attendee.GetStatus() != "" {
+		t.Errorf("Expected the passed attendee unchanged, found %s %s %s", attendee.GetType(), attendee.GetRole(), attendee.GetStatus())
+	}
+	added := event.GetAttendees()[0]
+	if added == attendee || added.GetRole() != "REQ-PARTICIPANT" || added.GetStatus() != "NEEDS-ACTION" {
+		t.Errorf("Expected a copy of the attendee with the defaults, found %s %s", added.GetRole(), added.GetStatus())
+	}
+}
+
+func TestCalendarBuilderSerialize(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	event, err := NewEventBuilder().UID("long@example.com").Summary(strings.Repeat("Долго име ", 12)).Start(start, nil).Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	cal, err := NewCalendarBuilder().Name("Team").Event(event).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+	if len(cal.GetEvents()) != 1 {
+		t.Errorf("Expected 1 event in calendar, found %d", len(cal.GetEvents()))
+	}
+
+	ics := cal.Serialize()
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:") {
+		t.Errorf("Expected calendar header, found:\n%s", ics)
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected lines of at most 75 octets, found %d in %q", len(line), line)
+		}
+	}
+	if !strings.Contains(unfold(ics), "SUMMARY:"+strings.Repeat("Долго име ", 12)+"\r\n") {
+		t.Errorf("Expected summary to survive folding, found:\n%s", ics)
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	r, err := ParseRRule("FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR;UNTIL=20241231T000000Z")
+	if err != nil {
+		t.Fatalf("Failed to parse rrule ( %s )", err)
+	}
+	if r.Freq != FreqMonthly || r.Interval != 2 || r.ByDay[0] != "-1FR" || r.Until.Year() != 2024 {
+		t.Errorf("Unexpected rrule %#v", r)
+	}
+	if r.String() != "FREQ=MONTHLY;INTERVAL=2;UNTIL=20241231T000000Z;BYDAY=-1FR" {
+		t.Errorf("Unexpected rrule string %s", r.String())
+	}
+	if _, err := ParseRRule("INTERVAL=2"); err == nil {
+		t.Errorf("Expected error for rrule without FREQ")
+	}
+	if _, err := ParseRRule("FREQ=DAILY;BYDAY=XX"); err == nil {
+		t.Errorf("Expected error for invalid BYDAY")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	cases := map[time.Duration]string{
+		-15 * time.Minute:             "-PT15M",
+		0:                             "PT0S",
+		24 * time.Hour:                "P1D",
+		14 * 24 * time.Hour:           "P2W",
+		26*time.Hour + 30*time.Second: "P1DT2H30S",
+	}
+	for d, expected := range cases {
+		if formatDuration(d) != expected {
+			t.Errorf("Expected duration %s, found %s", expected, formatDuration(d))
+		}
+	}
+}
+
+// joins folded content lines
+func unfold(ics string) string {
+	return strings.Replace(ics, "\r\n ", "", -1)
+}
+
+func TestSerializeEscapes(t *testing.T) {
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	event, err := NewEventBuilder().UID("escapes@example.com").Summary(`C:\new; a,b`).Start(start, nil).Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	cal, _ := NewCalendarBuilder().Name(`Team\Ops`).Event(event).Build()
+	ics := cal.Serialize()
+	for _, line := range []string{"SUMMARY:C:\\\\new\\; a\\,b\r\n", "X-WR-CALNAME:Team\\\\Ops\r\n"} {
+		if !strings.Contains(ics, line) {
+			t.Errorf("Expected the raw text escaped as %q, found:\n%s", line, ics)
+		}
+	}
+
+	// the parsed texts keep their escapes and are not escaped twice
+	parser := New()
+	parser.Load(ics)
+	calendars, _ := parser.GetCalendars()
+	parsed := calendars[0].GetEvents()[0]
+	if parsed.GetSummary() != `C:\\new\; a\,b` || parsed.plainText(parsed.GetSummary()) != `C:\new; a,b` {
+		t.Errorf("Expected the escaped summary of the parsed event, found %q", parsed.GetSummary())
+	}
+	if reserialized := calendars[0].Serialize(); !strings.Contains(reserialized, "SUMMARY:C:\\\\new\\; a\\,b\r\n") || !strings.Contains(reserialized, "X-WR-CALNAME:Team\\\\Ops\r\n") {
+		t.Errorf("Expected the parsed texts written as they are, found:\n%s", reserialized)
+	}
+}
+
+func TestSerializeTimezones(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Sofia")
+	if err != nil {
+		t.Fatalf("Failed to load location ( %s )", err)
+	}
+	event, err := NewEventBuilder().UID("tz@example.com").Summary("Sync").Start(time.Date(2024, 3, 4, 10, 0, 0, 0, loc), loc).Duration(time.Hour).Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	utc, _ := NewEventBuilder().UID("utc@example.com").Summary("UTC").Start(time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC), time.UTC).Build()
+	cal, _ := NewCalendarBuilder().Event(event).Event(utc).Build()
+	ics := cal.Serialize()
+
+	expected := "BEGIN:VTIMEZONE\r\nTZID:Europe/Sofia\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:20240101T000000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0200\r\nTZNAME:EET\r\nEND:STANDARD\r\n" +
+		"BEGIN:DAYLIGHT\r\nDTSTART:20240331T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0300\r\nTZNAME:EEST\r\nEND:DAYLIGHT\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:20241027T040000\r\nTZOFFSETFROM:+0300\r\nTZOFFSETTO:+0200\r\nTZNAME:EET\r\nEND:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n"
+	if !strings.Contains(ics, expected) || strings.Count(ics, "BEGIN:VTIMEZONE") != 1 {
+		t.Errorf("Expected the VTIMEZONE of Europe/Sofia\n%s\nfound:\n%s", expected, ics)
+	}
+	if strings.Index(ics, "BEGIN:VTIMEZONE") > strings.Index(ics, "BEGIN:VEVENT") {
+		t.Errorf("Expected the VTIMEZONE before the events")
+	}
+
+	// the events of several years give the transitions of every year
+	later, _ := NewEventBuilder().UID("later@example.com").Start(time.Date(2025, 6, 1, 10, 0, 0, 0, loc), loc).Build()
+	cal.SetEvent(*later)
+	if ics := cal.Serialize(); !strings.Contains(ics, "RDATE:20250330T030000\r\n") || !strings.Contains(ics, "RDATE:20251026T040000\r\n") {
+		t.Errorf("Expected the transitions of 2025, found:\n%s", ics)
+	}
+
+	if formatUTCOffset(-(3*3600+30*60)) != "-0330" || formatUTCOffset(5*3600+45*60+30) != "+054530" {
+		t.Errorf("Unexpected UTC offsets %s and %s", formatUTCOffset(-(3*3600 + 30*60)), formatUTCOffset(5*3600+45*60+30))
+	}
+}
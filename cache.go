@@ -0,0 +1,194 @@
+package ics
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache keeps the downloaded calendars in a directory with their ETag and
+// Last-Modified. The next downloads of an url are conditional requests and
+// a 304 Not Modified reuses the cached copy, which is also served for
+// MaxStale when the origin is down.
+type Cache struct {
+	// the directory of the cached calendars, it replaces FilePath
+	Dir string
+	// how long a copy is used without asking the origin, 0 always asks
+	TTL time.Duration
+	// how long a copy is served when the origin fails, 0 serves no copy
+	MaxStale time.Duration
+}
+
+// NewCache creates a cache in the directory
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// cacheEntry is the metadata of a cached url
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// returns the path of the cached file of the url without extension
+func (c *Cache) path(url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// returns the entry of the url, nil if it is not cached
+func (c *Cache) load(url string) *cacheEntry {
+	data, err := ioutil.ReadFile(c.path(url) + ".json")
+	if err != nil {
+		return nil
+	}
+	entry := &cacheEntry{}
+	if json.Unmarshal(data, entry) != nil || entry.URL != url || !fileExists(c.path(url)+".ics") {
+		return nil
+	}
+	return entry
+}
+
+// writes the entry, and the body if it is not nil, replacing the old ones
+func (c *Cache) store(entry *cacheEntry, body io.Reader) error {
+	if err := os.MkdirAll(c.Dir, 0777); err != nil {
+		return err
+	}
+	path := c.path(entry.URL)
+	if body != nil {
+		if err := writeFileAtomic(c.Dir, path+".ics", body); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.Dir, path+".json", bytes.NewReader(data))
+}
+
+// opens the cached copy of the url
+func (c *Cache) open(url string) (io.ReadCloser, error) {
+	file, err := os.Open(c.path(url) + ".ics")
+	if err != nil {
+		return nil, err
+	}
+	return &cachedFile{File: file}, nil
+}
+
+// Remove deletes the cached copy of the url
+func (c *Cache) Remove(url string) error {
+	path := c.path(url)
+	os.Remove(path + ".json")
+	if err := os.Remove(path + ".ics"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// cachedFile is a copy not modified since the last download of the url, or
+// a stale copy served because the origin failed
+type cachedFile struct {
+	*os.File
+	// the failure of the origin of a stale copy
+	originErr error
+}
+
+// NotModified tells the parser the calendar is the one parsed before
+func (f *cachedFile) NotModified() bool {
+	return f.originErr == nil
+}
+
+// Stale returns the failure of the origin when the copy is served because
+// of it
+func (f *cachedFile) Stale() error {
+	return f.originErr
+}
+
+// downloads the url through the cache
+func (c *Cache) fetch(send func(*http.Request) (*http.Response, error), url string, request *http.Request) (io.ReadCloser, error) {
+	entry := c.load(url)
+	if entry != nil && c.TTL > 0 && time.Since(entry.Fetched) < c.TTL {
+		return c.open(url)
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			request.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			request.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	response, err := send(request)
+	if err == nil {
+		defer response.Body.Close()
+	}
+	switch {
+	case err == nil && response.StatusCode == http.StatusNotModified && entry != nil:
+		entry.Fetched = time.Now()
+		c.store(entry, nil)
+		return c.open(url)
+	case err == nil && response.StatusCode >= 200 && response.StatusCode <= 299:
+		entry = &cacheEntry{
+			URL:          url,
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+			Fetched:      time.Now(),
+		}
+		if err := c.store(entry, response.Body); err != nil {
+			return nil, err
+		}
+		file, err := os.Open(c.path(url) + ".ics")
+		if err != nil {
+			return nil, err
+		}
+		return file, nil
+	case err == nil && response.StatusCode < 500:
+		// the origin answered, the calendar is gone or forbidden
+		return nil, fmt.Errorf("Failed to download %s: %s", request.URL, response.Status)
+	}
+
+	if err == nil {
+		err = fmt.Errorf("Failed to download %s: %s", request.URL, response.Status)
+	}
+	// the origin is down, serve the copy while it is not too old
+	if entry != nil && c.MaxStale > 0 && time.Since(entry.Fetched) < c.MaxStale {
+		file, errOpen := os.Open(c.path(url) + ".ics")
+		if errOpen != nil {
+			return nil, err
+		}
+		return &cachedFile{File: file, originErr: err}, nil
+	}
+	return nil, err
+}
+
+// writes the file through a temp file so readers never see a partial one
+func writeFileAtomic(dir, path string, r io.Reader) error {
+	tmp, err := ioutil.TempFile(dir, ".tmp_*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
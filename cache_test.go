@@ -0,0 +1,122 @@
+package ics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a server of fetchTestCal with an ETag that counts the requests
+type etagServer struct {
+	sync.Mutex
+	requests    int
+	conditional int
+	notModified int
+}
+
+func (s *etagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests++
+	if r.Header.Get("If-None-Match") != "" {
+		s.conditional++
+	}
+	if r.Header.Get("If-None-Match") == `"v1"` {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", `"v1"`)
+	w.Write([]byte(fetchTestCal))
+}
+
+func TestCacheConditionalRequests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	handler := &etagServer{}
+	server := httptest.NewServer(handler)
+	url := server.URL + "/cal.ics"
+	cache := NewCache(dir)
+
+	parser := New().SetCache(cache)
+	calendars, errs := fetchAll(parser, url)
+	if len(calendars) != 1 || len(errs) != 0 {
+		t.Errorf("Expected 1 calendar and no errors, found %d and %v", len(calendars), errs)
+	}
+	if handler.conditional != 0 {
+		t.Errorf("Expected the first request not to be conditional")
+	}
+
+	// the same parser keeps the calendar on 304
+	calendars, errs = fetchAll(parser, url)
+	if len(calendars) != 1 || len(errs) != 0 {
+		t.Errorf("Expected the calendar not to be parsed again, found %d calendars and %v", len(calendars), errs)
+	}
+	if handler.notModified != 1 {
+		t.Errorf("Expected 1 not modified response, found %d", handler.notModified)
+	}
+
+	// a new parser parses the cached copy
+	calendars, errs = fetchAll(New().SetCache(cache), url)
+	if len(calendars) != 1 || calendars[0].GetName() != "Fetched" || len(errs) != 0 {
+		t.Errorf("Expected the cached calendar, found %d calendars and %v", len(calendars), errs)
+	}
+	if handler.notModified != 2 {
+		t.Errorf("Expected 2 not modified responses, found %d", handler.notModified)
+	}
+
+	// fresh copies are used without a request
+	cache.TTL = time.Hour
+	fetchAll(New().SetCache(cache), url)
+	if handler.requests != 3 {
+		t.Errorf("Expected no request within the TTL, found %d requests", handler.requests)
+	}
+	cache.TTL = 0
+
+	// the copy is not served while the origin is down without MaxStale
+	server.Close()
+	calendars, errs = fetchAll(New().SetCache(cache), url)
+	if len(calendars) != 0 || len(errs) != 1 {
+		t.Errorf("Expected an error without MaxStale, found %d calendars and %v", len(calendars), errs)
+	}
+
+	// the stale copy is parsed once, and the failure is in the health
+	cache.MaxStale = time.Hour
+	parser = New().SetCache(cache)
+	fetchAll(parser, url)
+	calendars, errs = fetchAll(parser, url)
+	if len(calendars) != 1 || len(errs) != 0 {
+		t.Errorf("Expected the stale calendar, found %d calendars and %v", len(calendars), errs)
+	}
+	if health, _ := parser.GetHealth(url); health.ConsecutiveFailures != 2 || health.LastError == nil {
+		t.Errorf("Expected the failures of the origin in the health, found %#v", health)
+	}
+	cache.MaxStale = time.Nanosecond
+	calendars, errs = fetchAll(New().SetCache(cache), url)
+	if len(calendars) != 0 || len(errs) != 1 {
+		t.Errorf("Expected an error past MaxStale, found %d calendars and %v", len(calendars), errs)
+	}
+
+	if err := cache.Remove(url); err != nil {
+		t.Errorf("Failed to remove the cached copy ( %s )", err)
+	}
+	if entry := cache.load(url); entry != nil {
+		t.Errorf("Expected no cached copy after Remove, found %v", entry)
+	}
+}
+
+func TestCacheKeepsHTTPClient(t *testing.T) {
+	client := &http.Client{Timeout: time.Second}
+	parser := New().SetHTTPClient(client).SetCache(NewCache("cache"))
+	web, ok := parser.GetFetcher("webcals").(*HTTPFetcher)
+	if !ok || web.Client != client || web.Cache == nil || web.Cache.Dir != "cache" {
+		t.Errorf("Expected the http fetcher with the client and the cache, found %#v", parser.GetFetcher("webcals"))
+	}
+}
@@ -11,12 +11,18 @@ type Calendar struct {
 	name              string
 	description       string
 	url               string
+	method            string
+	messageID         string
+	archivePath       string
+	refreshInterval   time.Duration
 	version           float64
 	timezone          time.Location
 	events            Events
 	eventsByDate      map[string][]*Event
 	eventByID         map[string]*Event
 	eventByImportedID map[string]*Event
+	// the texts keep their iCalendar escapes, as parsed
+	escaped bool
 }
 
 type Events []Event
@@ -60,6 +66,49 @@ func (c *Calendar) GetDesc() string {
 	return c.description
 }
 
+// SetMethod sets the iTIP METHOD of the calendar
+func (c *Calendar) SetMethod(method string) *Calendar {
+	c.method = method
+	return c
+}
+
+func (c *Calendar) GetMethod() string {
+	return c.method
+}
+
+// SetMessageID sets the Message-ID of the email the calendar was found in
+func (c *Calendar) SetMessageID(id string) *Calendar {
+	c.messageID = id
+	return c
+}
+
+func (c *Calendar) GetMessageID() string {
+	return c.messageID
+}
+
+// SetArchivePath sets the path of the calendar in the zip archive of its url
+func (c *Calendar) SetArchivePath(path string) *Calendar {
+	c.archivePath = path
+	return c
+}
+
+// GetArchivePath returns the path of the calendar in the zip archive of its url
+func (c *Calendar) GetArchivePath() string {
+	return c.archivePath
+}
+
+// SetRefreshInterval sets how often the calendar should be fetched again
+func (c *Calendar) SetRefreshInterval(interval time.Duration) *Calendar {
+	c.refreshInterval = interval
+	return c
+}
+
+// GetRefreshInterval returns the REFRESH-INTERVAL or X-PUBLISHED-TTL of the
+// calendar, 0 if it has none
+func (c *Calendar) GetRefreshInterval() time.Duration {
+	return c.refreshInterval
+}
+
 func (c *Calendar) SetVersion(ver float64) *Calendar {
 	c.version = ver
 	return c
@@ -92,10 +141,17 @@ func (c *Calendar) SetEvent(event Event) (*Calendar, error) {
 
 	// pointer to the added event in the main array
 	eventPtr := &c.events[len(c.events)-1]
+	c.indexEvent(eventPtr)
+
+	mutex.Unlock()
+	return c, nil
+}
 
+//  adds the event to the search maps
+func (c *Calendar) indexEvent(eventPtr *Event) {
 	// calculate the start and end day of the event
-	eventStartTime := event.GetStart()
-	eventEndTime := event.GetEnd()
+	eventStartTime := eventPtr.GetStart()
+	eventEndTime := eventPtr.GetEnd()
 	tz := c.GetTimezone()
 	eventStartDate := time.Date(eventStartTime.Year(), eventStartTime.Month(), eventStartTime.Day(), 0, 0, 0, 0, &tz)
 	eventEndDate := time.Date(eventEndTime.Year(), eventEndTime.Month(), eventEndTime.Day(), 0, 0, 0, 0, &tz)
@@ -106,14 +162,21 @@ func (c *Calendar) SetEvent(event Event) (*Calendar, error) {
 	}
 
 	// faster search by id
-	c.eventByID[event.GetID()] = eventPtr
+	c.eventByID[eventPtr.GetID()] = eventPtr
 
-	if event.GetImportedID() != "" {
-		c.eventByImportedID[event.GetImportedID()] = eventPtr
+	if eventPtr.GetImportedID() != "" {
+		c.eventByImportedID[eventPtr.GetImportedID()] = eventPtr
 	}
+}
 
-	mutex.Unlock()
-	return c, nil
+//  rebuilds the search maps after the events array was changed in place
+func (c *Calendar) reindex() {
+	c.eventsByDate = make(map[string][]*Event)
+	c.eventByID = make(map[string]*Event)
+	c.eventByImportedID = make(map[string]*Event)
+	for i := range c.events {
+		c.indexEvent(&c.events[i])
+	}
 }
 
 //  get event by id
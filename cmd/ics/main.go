@@ -0,0 +1,38 @@
+// Command ics works with iCalendar files and feeds from the shell.
+//
+// Usage:
+//
+//	ics view [flags] <file or url>...
+//
+// The view subcommand draws a month or week grid or an agenda of the events
+// in the terminal. Run "ics view -h" for its flags.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: ics <command> [flags] <file or url>...
+
+Commands:
+  view    draws a month or week grid or an agenda of the events
+
+Run "ics <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "view":
+		os.Exit(view(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "ics: unknown command %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// the ANSI colors of the calendars, in the order of the sources
+var palette = []string{"34", "31", "32", "33", "35", "36"}
+
+// the most events shown in a day of the month grid
+const monthLines = 3
+
+// viewer draws the pages of the view
+type viewer struct {
+	out       io.Writer
+	calendars []*ics.Calendar
+	loc       *time.Location
+	today     time.Time
+	mode      string
+	days      int
+	width     int
+	color     bool
+}
+
+// runs the view subcommand and returns the exit code
+func view(args []string, in io.Reader, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("view", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	mode := flags.String("mode", "month", "the view: month, week or agenda")
+	date := flags.String("date", "", "a day of the first page as 2006-01-02, today by default")
+	page := flags.Int("page", 0, "the number of pages to move from the date, negative goes back")
+	days := flags.Int("days", 7, "the number of days of the agenda")
+	tz := flags.String("tz", "", "the time zone of the view like Europe/Sofia, the local one by default")
+	color := flags.String("color", "auto", "the colors: auto, always or never")
+	width := flags.Int("width", 0, "the width of the grids, $COLUMNS or 80 by default")
+	interactive := flags.Bool("i", false, "page with n(ext), p(revious), t(oday) and q(uit) read from the input")
+	flags.Usage = func() {
+		fmt.Fprintln(errOut, "Usage: ics view [flags] <file or url>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	if *mode != "month" && *mode != "week" && *mode != "agenda" {
+		fmt.Fprintf(errOut, "ics: unknown mode %s\n", *mode)
+		return 2
+	}
+	if *days < 1 {
+		fmt.Fprintf(errOut, "ics: the agenda must have at least 1 day, got %d\n", *days)
+		return 2
+	}
+
+	loc := time.Local
+	if *tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(*tz); err != nil {
+			fmt.Fprintf(errOut, "ics: unknown time zone %s\n", *tz)
+			return 2
+		}
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	first := today
+	if *date != "" {
+		var err error
+		if first, err = time.ParseInLocation("2006-01-02", *date, loc); err != nil {
+			fmt.Fprintf(errOut, "ics: invalid date %s\n", *date)
+			return 2
+		}
+	}
+
+	v := &viewer{
+		out:   out,
+		loc:   loc,
+		today: today,
+		mode:  *mode,
+		days:  *days,
+		width: *width,
+		color: *color == "always" || *color == "auto" && isTerminal(out),
+	}
+	if v.width <= 0 {
+		v.width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+		if v.width <= 0 {
+			v.width = 80
+		}
+	}
+
+	calendars, errs := load(flags.Args())
+	for _, err := range errs {
+		fmt.Fprintf(errOut, "ics: %s\n", err)
+	}
+	if len(calendars) == 0 {
+		return 1
+	}
+	v.calendars = calendars
+
+	current := v.move(first, *page)
+	v.render(current)
+	if !*interactive {
+		return 0
+	}
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "[n]ext [p]revious [t]oday [q]uit: ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return 0
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "n", "next", "":
+			current = v.move(current, 1)
+		case "p", "prev", "previous":
+			current = v.move(current, -1)
+		case "t", "today":
+			current = today
+		case "q", "quit":
+			return 0
+		default:
+			continue
+		}
+		v.render(current)
+	}
+}
+
+// parses the calendars of the sources, in the order of the sources
+func load(sources []string) ([]*ics.Calendar, []error) {
+	ics.FilePath = filepath.Join(os.TempDir(), "ics-golang") + string(filepath.Separator)
+	parser := ics.New()
+	input := parser.GetInputChan()
+	for _, source := range sources {
+		input <- source
+	}
+	parser.Wait()
+	calendars, _ := parser.GetCalendars()
+	errs, _ := parser.GetErrors()
+
+	order := map[string]int{}
+	for i, source := range sources {
+		order[source] = i
+	}
+	sort.SliceStable(calendars, func(i, j int) bool {
+		return order[calendars[i].GetUrl()] < order[calendars[j].GetUrl()]
+	})
+	return calendars, errs
+}
+
+// reports whether the output is a terminal and colors are wanted
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// returns the date moved by n pages
+func (v *viewer) move(date time.Time, n int) time.Time {
+	switch v.mode {
+	case "month":
+		return time.Date(date.Year(), date.Month()+time.Month(n), 1, 0, 0, 0, 0, v.loc)
+	case "week":
+		return date.AddDate(0, 0, 7*n)
+	}
+	return date.AddDate(0, 0, v.days*n)
+}
+
+func (v *viewer) render(date time.Time) {
+	switch v.mode {
+	case "month":
+		v.month(date)
+	case "week":
+		v.week(date)
+	default:
+		v.agenda(date)
+	}
+}
+
+// ======================== PAGES ===================
+
+func (v *viewer) month(date time.Time) {
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, v.loc)
+	start := weekStart(first)
+	end := weekStart(first.AddDate(0, 1, -1)).AddDate(0, 0, 7)
+	agenda := ics.NewAgenda(start, end, v.calendars...)
+	cell := v.cellWidth()
+
+	fmt.Fprintln(v.out, v.paint("1", center(first.Format("January 2006"), 7*(cell+1)+1)))
+	v.weekdays(cell, func(day time.Time) string { return day.Format("Mon") }, start)
+	for w := 0; w < len(agenda.Days); w += 7 {
+		days := agenda.Days[w : w+7]
+		fmt.Fprintln(v.out, separator(cell))
+		line := "|"
+		for _, day := range days {
+			text := fit(strconv.Itoa(day.Date.Day()), cell)
+			switch {
+			case day.Date.Equal(v.today):
+				text = v.paint("7", text)
+			case day.Date.Month() != first.Month():
+				text = v.paint("2", text)
+			}
+			line += text + "|"
+		}
+		fmt.Fprintln(v.out, line)
+		for i := 0; i < monthLines; i++ {
+			line := "|"
+			for _, day := range days {
+				switch {
+				case len(day.Items) > monthLines && i == monthLines-1:
+					line += v.paint("2", fit(fmt.Sprintf("+%d more", len(day.Items)-i), cell))
+				case i < len(day.Items):
+					line += v.item(day.Items[i], cell, false)
+				default:
+					line += strings.Repeat(" ", cell)
+				}
+				line += "|"
+			}
+			fmt.Fprintln(v.out, line)
+		}
+	}
+	fmt.Fprintln(v.out, separator(cell))
+}
+
+func (v *viewer) week(date time.Time) {
+	start := weekStart(date)
+	agenda := ics.NewAgenda(start, start.AddDate(0, 0, 7), v.calendars...)
+	cell := v.cellWidth()
+
+	fmt.Fprintln(v.out, v.paint("1", center("Week of "+start.Format("2 January 2006"), 7*(cell+1)+1)))
+	v.weekdays(cell, func(day time.Time) string { return day.Format("Mon 2") }, start)
+	fmt.Fprintln(v.out, separator(cell))
+	lines := 1
+	for _, day := range agenda.Days {
+		if len(day.Items) > lines {
+			lines = len(day.Items)
+		}
+	}
+	for i := 0; i < lines; i++ {
+		line := "|"
+		for _, day := range agenda.Days {
+			if i < len(day.Items) {
+				line += v.item(day.Items[i], cell, true)
+			} else {
+				line += strings.Repeat(" ", cell)
+			}
+			line += "|"
+		}
+		fmt.Fprintln(v.out, line)
+	}
+	fmt.Fprintln(v.out, separator(cell))
+}
+
+func (v *viewer) agenda(date time.Time) {
+	agenda := ics.NewAgenda(date, date.AddDate(0, 0, v.days), v.calendars...)
+	if agenda.Empty() {
+		fmt.Fprintf(v.out, "No events from %s to %s\n", date.Format("2 January 2006"), date.AddDate(0, 0, v.days-1).Format("2 January 2006"))
+		return
+	}
+	for i, day := range agenda.Busy() {
+		if i > 0 {
+			fmt.Fprintln(v.out)
+		}
+		title := day.Date.Format("Monday, 2 January 2006")
+		if day.Date.Equal(v.today) {
+			title += " (today)"
+		}
+		fmt.Fprintln(v.out, v.paint("1", title))
+		for _, item := range day.Items {
+			line := "  " + v.paint("2", fit(item.Time(), 13)) + " " + v.paint(v.colorOf(item.Calendar), oneLine(item.Summary()))
+			if item.Location() != "" {
+				line += v.paint("2", " ("+oneLine(item.Location())+")")
+			}
+			fmt.Fprintln(v.out, line)
+		}
+	}
+}
+
+// ======================== HELPERS ===================
+
+// writes the header of the grid with a label of each day
+func (v *viewer) weekdays(cell int, label func(time.Time) string, start time.Time) {
+	line := "|"
+	for i := 0; i < 7; i++ {
+		day := start.AddDate(0, 0, i)
+		text := fit(label(day), cell)
+		if day.Equal(v.today) {
+			text = v.paint("7", text)
+		}
+		line += text + "|"
+	}
+	fmt.Fprintln(v.out, line)
+}
+
+// returns the cell of the item, with its start time unless it lasts all day
+func (v *viewer) item(item *ics.AgendaItem, cell int, week bool) string {
+	text := oneLine(item.Summary())
+	switch {
+	case item.AllDay():
+	case week:
+		text = item.Time() + " " + text
+	case !item.Continued:
+		text = item.Start.Format("15:04") + " " + text
+	}
+	return v.paint(v.colorOf(item.Calendar), fit(text, cell))
+}
+
+// returns the color of the calendar with the name
+func (v *viewer) colorOf(name string) string {
+	for i, cal := range v.calendars {
+		if cal.GetName() == name {
+			return palette[i%len(palette)]
+		}
+	}
+	return palette[0]
+}
+
+func (v *viewer) cellWidth() int {
+	cell := (v.width-1)/7 - 1
+	if cell < 6 {
+		cell = 6
+	}
+	return cell
+}
+
+// wraps the text in the ANSI codes when colors are on
+func (v *viewer) paint(codes, text string) string {
+	if !v.color {
+		return text
+	}
+	return "\x1b[" + codes + "m" + text + "\x1b[0m"
+}
+
+// returns the monday of the week of the day
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func separator(cell int) string {
+	return "+" + strings.Repeat(strings.Repeat("-", cell)+"+", 7)
+}
+
+// cuts or pads the text to the width
+func fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		runes = append(runes[:width-1], '~')
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+func center(text string, width int) string {
+	pad := (width - len([]rune(text))) / 2
+	if pad < 0 {
+		pad = 0
+	}
+	return strings.Repeat(" ", pad) + text
+}
+
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func writeCalendar(t *testing.T, dir string) string {
+	review, _ := ics.NewEventBuilder().UID("review@example.com").Summary("Design review").Location("Room 1").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), time.UTC).Duration(time.Hour).Build()
+	offsite, _ := ics.NewEventBuilder().UID("offsite@example.com").Summary("Offsite").
+		AllDay(time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC), 2).Build()
+	cal, err := ics.NewCalendarBuilder().Name("Team").Event(review).Event(offsite).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+	path := filepath.Join(dir, "team.ics")
+	if err := ioutil.WriteFile(path, []byte(cal.Serialize()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runView(t *testing.T, input string, args ...string) (string, string, int) {
+	var out, errOut bytes.Buffer
+	code := view(args, strings.NewReader(input), &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+func TestViewModes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeCalendar(t, dir)
+
+	out, errOut, code := runView(t, "", "-mode", "agenda", "-date", "2024-06-10", "-tz", "Europe/Paris", "-color", "never", path)
+	expected := "Monday, 10 June 2024\n  11:00-12:00   Design review (Room 1)\n\n" +
+		"Wednesday, 12 June 2024\n  all day       Offsite\n\n" +
+		"Thursday, 13 June 2024\n  all day       Offsite\n"
+	if code != 0 || out != expected {
+		t.Errorf("Expected agenda:\n%s\nfound ( %d %s ):\n%s", expected, code, errOut, out)
+	}
+
+	out, _, _ = runView(t, "", "-mode", "month", "-date", "2024-06-20", "-tz", "UTC", "-width", "71", "-color", "never", path)
+	for _, fragment := range []string{
+		"June 2024",
+		"|Mon      |Tue      |Wed      |Thu      |Fri      |Sat      |Sun      |",
+		"|27       |28       |29       |30       |31       |1        |2        |",
+		"|09:00 De~|         |Offsite  |Offsite  |         |         |         |",
+	} {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("Expected the month to contain %q, found:\n%s", fragment, out)
+		}
+	}
+
+	out, _, _ = runView(t, "", "-mode", "week", "-date", "2024-06-03", "-page", "1", "-tz", "UTC", "-width", "120", "-color", "always", path)
+	if !strings.Contains(out, "Week of 10 June 2024") || !strings.Contains(out, "\x1b[34m09:00-10:00 Des~") {
+		t.Errorf("Expected the next week with colors, found:\n%s", out)
+	}
+}
+
+func TestViewInteractive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeCalendar(t, dir)
+
+	out, _, code := runView(t, "p\nn\nq\n", "-i", "-mode", "agenda", "-days", "1", "-date", "2024-06-10", "-tz", "UTC", "-color", "never", path)
+	if code != 0 || strings.Count(out, "Design review") != 2 || !strings.Contains(out, "No events from 9 June 2024 to 9 June 2024") {
+		t.Errorf("Expected the day, the previous day and the day again, found:\n%s", out)
+	}
+
+	if _, errOut, code := runView(t, "", filepath.Join(dir, "missing.ics")); code != 1 || !strings.Contains(errOut, "missing.ics") {
+		t.Errorf("Expected error for a missing file, found %d %s", code, errOut)
+	}
+	if _, _, code := runView(t, "", "-mode", "year", path); code != 2 {
+		t.Errorf("Expected usage error for an unknown mode, found %d", code)
+	}
+}
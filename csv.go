@@ -0,0 +1,405 @@
+package ics
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CSV columns known by WriteCSV and ReadCSV
+const (
+	CSVSubject     = "Subject"
+	CSVStartDate   = "Start Date"
+	CSVStartTime   = "Start Time"
+	CSVEndDate     = "End Date"
+	CSVEndTime     = "End Time"
+	CSVAllDay      = "All day event"
+	CSVLocation    = "Location"
+	CSVDescription = "Description"
+	CSVPrivate     = "Private"
+	CSVUID         = "UID"
+	CSVStatus      = "Status"
+)
+
+// columns of the Outlook CSV export
+var OutlookCSVColumns = []string{CSVSubject, CSVStartDate, CSVStartTime, CSVEndDate, CSVEndTime, CSVAllDay, CSVLocation, CSVDescription}
+
+// columns of the Google Calendar CSV import
+var GoogleCSVColumns = []string{CSVSubject, CSVStartDate, CSVStartTime, CSVEndDate, CSVEndTime, CSVAllDay, CSVDescription, CSVLocation, CSVPrivate}
+
+// date and time layouts of a locale, the first ones are used for writing
+type csvLocale struct {
+	dates []string
+	times []string
+}
+
+var csvLocales = map[string]csvLocale{
+	"en-US": {
+		dates: []string{"1/2/2006", "01/02/2006", "1/2/06", "2006-01-02"},
+		times: []string{"3:04:05 PM", "3:04 PM", "3:04:05PM", "3:04PM", "15:04:05", "15:04"},
+	},
+	"en-GB": {
+		dates: []string{"02/01/2006", "2/1/2006", "02/01/06", "2006-01-02"},
+		times: []string{"15:04:05", "15:04", "3:04:05 PM", "3:04 PM"},
+	},
+	"de-DE": {
+		dates: []string{"02.01.2006", "2.1.2006", "02.01.06", "2006-01-02"},
+		times: []string{"15:04:05", "15:04"},
+	},
+	"fr-FR": {
+		dates: []string{"02/01/2006", "2/1/2006", "2006-01-02"},
+		times: []string{"15:04:05", "15:04", "15h04"},
+	},
+	"bg-BG": {
+		dates: []string{"2.01.2006", "02.01.2006", "2.1.2006", "2006-01-02"},
+		times: []string{"15:04:05", "15:04"},
+	},
+	"ISO": {
+		dates: []string{"2006-01-02"},
+		times: []string{"15:04:05", "15:04"},
+	},
+}
+
+// CSVFormat configures the columns, locale and time zone of CSV files
+type CSVFormat struct {
+	// column headers, the known ones are the CSV* constants
+	Columns []string
+	// locale of the dates and times like en-US or de-DE
+	Locale string
+	// time zone of the dates and times. When writing it defaults to the
+	// calendar time zone, when reading to UTC.
+	Timezone *time.Location
+	// field delimiter, defaults to a comma
+	Comma rune
+}
+
+// NewCSVFormat creates the Outlook format with en-US dates
+func NewCSVFormat() *CSVFormat {
+	return &CSVFormat{Columns: OutlookCSVColumns, Locale: "en-US", Comma: ','}
+}
+
+// CSVLocales returns the supported locales
+func CSVLocales() []string {
+	locales := []string{}
+	for name := range csvLocales {
+		locales = append(locales, name)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+func (f *CSVFormat) locale() (csvLocale, error) {
+	name := f.Locale
+	if name == "" {
+		name = "en-US"
+	}
+	locale, ok := csvLocales[name]
+	if !ok {
+		return csvLocale{}, fmt.Errorf("Unknown CSV locale %s", name)
+	}
+	return locale, nil
+}
+
+func (f *CSVFormat) comma() rune {
+	if f.Comma == 0 {
+		return ','
+	}
+	return f.Comma
+}
+
+// returns the known column name of a header
+func csvColumn(header string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "\ufeff"))
+	for _, column := range []string{CSVSubject, CSVStartDate, CSVStartTime, CSVEndDate, CSVEndTime, CSVAllDay, CSVLocation, CSVDescription, CSVPrivate, CSVUID, CSVStatus} {
+		if strings.EqualFold(header, column) {
+			return column
+		}
+	}
+	return ""
+}
+
+// ======================== CSV EXPORT ===================
+
+// WriteCSV writes one row per event with a header row. A nil format
+// writes the Outlook layout.
+func (c *Calendar) WriteCSV(w io.Writer, f *CSVFormat) error {
+	if f == nil {
+		f = NewCSVFormat()
+	}
+	locale, err := f.locale()
+	if err != nil {
+		return err
+	}
+	for _, column := range f.Columns {
+		if csvColumn(column) == "" {
+			return fmt.Errorf("Unknown CSV column %s", column)
+		}
+	}
+	loc := f.Timezone
+	if loc == nil {
+		tz := c.GetTimezone()
+		loc = &tz
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = f.comma()
+	writer.UseCRLF = true
+	if err := writer.Write(f.Columns); err != nil {
+		return err
+	}
+	events := c.GetEvents()
+	for i := range events {
+		event := &events[i]
+		row := make([]string, len(f.Columns))
+		for j, column := range f.Columns {
+			row[j] = csvValue(event, csvColumn(column), locale, loc)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formats the value of a column
+func csvValue(e *Event, column string, locale csvLocale, loc *time.Location) string {
+	start, end := e.GetStart(), e.GetEnd()
+	// whole day events are kept at midnight UTC
+	if !e.IsWholeDay() {
+		start, end = start.In(loc), end.In(loc)
+	}
+	switch column {
+	case CSVSubject:
+		return e.plainText(e.GetSummary())
+	case CSVStartDate:
+		return start.Format(locale.dates[0])
+	case CSVStartTime:
+		return start.Format(locale.times[0])
+	case CSVEndDate:
+		return end.Format(locale.dates[0])
+	case CSVEndTime:
+		return end.Format(locale.times[0])
+	case CSVAllDay:
+		return csvBool(e.IsWholeDay())
+	case CSVLocation:
+		return e.plainText(e.GetLocation())
+	case CSVDescription:
+		return e.plainText(e.GetDescription())
+	case CSVPrivate:
+		return csvBool(e.GetClass() == "PRIVATE" || e.GetClass() == "CONFIDENTIAL")
+	case CSVUID:
+		return e.GetImportedID()
+	case CSVStatus:
+		return e.GetStatus()
+	}
+	return ""
+}
+
+func csvBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+// ======================== CSV IMPORT ===================
+
+// CSVRowError is an invalid row of a CSV file
+type CSVRowError struct {
+	// line of the row in the file, the header is on line 1
+	Row int
+	// column of the invalid value, empty when the whole row is invalid
+	Column string
+	Err    error
+}
+
+func (e *CSVRowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d: %s: %s", e.Row, e.Column, e.Err)
+}
+
+// CSVErrors are the invalid rows of a CSV file
+type CSVErrors []*CSVRowError
+
+func (e CSVErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ReadCSV creates an event of every row of a CSV file with a header row.
+// The columns are found by their headers, unknown ones are skipped. The
+// events of the valid rows are returned together with CSVErrors for the
+// invalid ones. A nil format reads en-US dates in UTC.
+func ReadCSV(r io.Reader, f *CSVFormat) ([]*Event, error) {
+	if f == nil {
+		f = NewCSVFormat()
+	}
+	locale, err := f.locale()
+	if err != nil {
+		return nil, err
+	}
+	loc := f.Timezone
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = f.comma()
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the CSV header ( %s )", err)
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		if column := csvColumn(h); column != "" {
+			columns[column] = i
+		}
+	}
+	for _, required := range []string{CSVSubject, CSVStartDate} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("The CSV file has no %s column", required)
+		}
+	}
+
+	events := []*Event{}
+	rowErrors := CSVErrors{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return events, err
+			}
+			rowErrors = append(rowErrors, &CSVRowError{Row: line, Err: err})
+			continue
+		}
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		event, rowErr := csvEvent(value, locale, loc)
+		if rowErr != nil {
+			rowErr.Row = line
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		events = append(events, event)
+	}
+	if len(rowErrors) > 0 {
+		return events, rowErrors
+	}
+	return events, nil
+}
+
+// builds the event of a row
+func csvEvent(value func(string) string, locale csvLocale, loc *time.Location) (*Event, *CSVRowError) {
+	b := NewEventBuilder().
+		UID(value(CSVUID)).
+		Summary(value(CSVSubject)).
+		Location(value(CSVLocation)).
+		Description(value(CSVDescription)).
+		Status(strings.ToUpper(value(CSVStatus)))
+	if parseCSVBool(value(CSVPrivate)) {
+		b.Class("PRIVATE")
+	}
+
+	startDate, err := parseCSVLayouts(value(CSVStartDate), locale.dates)
+	if err != nil {
+		return nil, &CSVRowError{Column: CSVStartDate, Err: err}
+	}
+	endDate := startDate
+	if value(CSVEndDate) != "" {
+		if endDate, err = parseCSVLayouts(value(CSVEndDate), locale.dates); err != nil {
+			return nil, &CSVRowError{Column: CSVEndDate, Err: err}
+		}
+	}
+
+	allDay := parseCSVBool(value(CSVAllDay)) || (value(CSVAllDay) == "" && value(CSVStartTime) == "")
+	if allDay {
+		// the end date is exclusive like in Outlook, a single day when it is not after the start
+		days := int(endDate.Sub(startDate).Hours()+12) / 24
+		if days < 1 {
+			days = 1
+		}
+		b.AllDay(startDate, days)
+	} else {
+		startTime, err := parseCSVTime(value(CSVStartTime), locale.times)
+		if err != nil {
+			return nil, &CSVRowError{Column: CSVStartTime, Err: err}
+		}
+		start := combineCSVDate(startDate, startTime, loc)
+		b.Start(start, loc)
+		if value(CSVEndTime) != "" {
+			endTime, err := parseCSVTime(value(CSVEndTime), locale.times)
+			if err != nil {
+				return nil, &CSVRowError{Column: CSVEndTime, Err: err}
+			}
+			b.End(combineCSVDate(endDate, endTime, loc), loc)
+		}
+	}
+
+	event, err := b.Build()
+	if err != nil {
+		return nil, &CSVRowError{Err: err}
+	}
+	return event, nil
+}
+
+// parses the value with the first matching layout
+func parseCSVLayouts(value string, layouts []string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("value is empty")
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q does not match %s", value, strings.Join(layouts, ", "))
+}
+
+// parses a time of the day, an empty time is midnight
+func parseCSVTime(value string, layouts []string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := parseCSVLayouts(value, layouts)
+	if err != nil {
+		// the AM/PM marker is only parsed in upper case
+		if upper, errUpper := parseCSVLayouts(strings.ToUpper(value), layouts); errUpper == nil {
+			return upper, nil
+		}
+	}
+	return t, err
+}
+
+func combineCSVDate(date, clock time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, loc)
+}
+
+func parseCSVBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "y", "1", "x", "on":
+		return true
+	}
+	return false
+}
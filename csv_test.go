@@ -0,0 +1,115 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSVOutlook(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Sofia")
+	if err != nil {
+		t.Fatalf("Failed to load location ( %s )", err)
+	}
+	meeting, _ := NewEventBuilder().
+		Summary("Planning, Q3").
+		Description("Bring \"the\" numbers\nand ideas").
+		Location("Room 1").
+		Start(time.Date(2024, 6, 10, 14, 30, 0, 0, loc), loc).
+		Duration(90 * time.Minute).
+		Build()
+	holiday, _ := NewEventBuilder().Summary("Holiday").AllDay(time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC), 2).Build()
+	cal, err := NewCalendarBuilder().Timezone(loc).Event(meeting).Event(holiday).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+
+	var b bytes.Buffer
+	if err := cal.WriteCSV(&b, nil); err != nil {
+		t.Fatalf("Failed to write CSV ( %s )", err)
+	}
+	expected := "Subject,Start Date,Start Time,End Date,End Time,All day event,Location,Description\r\n" +
+		"\"Planning, Q3\",6/10/2024,2:30:00 PM,6/10/2024,4:00:00 PM,False,Room 1,\"Bring \"\"the\"\" numbers\r\nand ideas\"\r\n" +
+		"Holiday,6/12/2024,12:00:00 AM,6/14/2024,12:00:00 AM,True,,\r\n"
+	if b.String() != expected {
+		t.Errorf("Expected CSV:\n%s\nfound:\n%s", expected, b.String())
+	}
+
+	format := &CSVFormat{Columns: []string{CSVSubject, CSVStartDate, CSVStartTime, CSVUID}, Locale: "de-DE", Timezone: time.UTC, Comma: ';'}
+	b.Reset()
+	if err := cal.WriteCSV(&b, format); err != nil {
+		t.Fatalf("Failed to write CSV ( %s )", err)
+	}
+	if !strings.Contains(b.String(), "Planning, Q3;10.06.2024;11:30:00;"+meeting.GetImportedID()+"\r\n") {
+		t.Errorf("Expected de-DE row in UTC, found:\n%s", b.String())
+	}
+
+	format.Columns = []string{CSVSubject, "Reminder on/off"}
+	if err := cal.WriteCSV(&b, format); err == nil {
+		t.Errorf("Expected error for unknown column")
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	content := "\ufeffSubject;Start Date;Start Time;End Date;End Time;All Day Event;Location;Private;Notes\n" +
+		"Standup;10.06.2024;09:00;10.06.2024;09:15;False;Room 1;False;ignored\n" +
+		"Offsite;12.06.2024;;13.06.2024;;True;;True;\n" +
+		"Bad date;31.02.2024;09:00;;;False;;;\n" +
+		"Backwards;11.06.2024;10:00;11.06.2024;09:00;False;;;\n" +
+		"Single day;14.06.2024;;14.06.2024;;;;;\n"
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	format := &CSVFormat{Locale: "de-DE", Timezone: loc, Comma: ';'}
+
+	events, err := ReadCSV(strings.NewReader(content), format)
+	rowErrors, ok := err.(CSVErrors)
+	if !ok || len(rowErrors) != 2 {
+		t.Fatalf("Expected 2 row errors, found %v", err)
+	}
+	if rowErrors[0].Row != 4 || rowErrors[0].Column != CSVStartDate {
+		t.Errorf("Expected invalid start date on row 4, found %s", rowErrors[0])
+	}
+	if rowErrors[1].Row != 5 || !strings.Contains(rowErrors[1].Error(), "end is before start") {
+		t.Errorf("Expected end before start on row 5, found %s", rowErrors[1])
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, found %d", len(events))
+	}
+	standup := events[0]
+	if !standup.GetStart().Equal(time.Date(2024, 6, 10, 9, 0, 0, 0, loc)) || !standup.GetEnd().Equal(time.Date(2024, 6, 10, 9, 15, 0, 0, loc)) {
+		t.Errorf("Expected standup from 09:00 to 09:15 in Berlin, found %s - %s", standup.GetStart(), standup.GetEnd())
+	}
+	if standup.GetStartTZID() != "Europe/Berlin" || standup.GetLocation() != "Room 1" {
+		t.Errorf("Expected TZID Europe/Berlin and location Room 1, found %s and %s", standup.GetStartTZID(), standup.GetLocation())
+	}
+	offsite := events[1]
+	if !offsite.IsWholeDay() || offsite.GetClass() != "PRIVATE" || !offsite.GetEnd().Equal(time.Date(2024, 6, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected private whole day offsite ending on 13.06, found %s %s", offsite.GetClass(), offsite.GetEnd())
+	}
+	single := events[2]
+	if !single.IsWholeDay() || !single.GetEnd().Equal(time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a single whole day, found %s - %s", single.GetStart(), single.GetEnd())
+	}
+
+	if _, err := ReadCSV(strings.NewReader("Title,When\nx,y\n"), nil); err == nil {
+		t.Errorf("Expected error for missing columns")
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	event, _ := NewEventBuilder().Summary("Review").Start(time.Date(2024, 6, 10, 16, 0, 0, 0, time.UTC), nil).Duration(time.Hour).Build()
+	cal, _ := NewCalendarBuilder().Event(event).Build()
+
+	var b bytes.Buffer
+	if err := cal.WriteCSV(&b, nil); err != nil {
+		t.Fatalf("Failed to write CSV ( %s )", err)
+	}
+	events, err := ReadCSV(&b, nil)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected 1 event, found %d ( %v )", len(events), err)
+	}
+	if !events[0].GetStart().Equal(event.GetStart()) || !events[0].GetEnd().Equal(event.GetEnd()) || events[0].GetSummary() != "Review" {
+		t.Errorf("Expected %s, found %s", event, events[0])
+	}
+}
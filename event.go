@@ -13,6 +13,8 @@ type Event struct {
 	endTZID       string
 	created       time.Time
 	modified      time.Time
+	stamp         time.Time
+	recurrenceID  time.Time
 	alarmTime     time.Duration
 	importedId    string
 	status        string
@@ -20,6 +22,7 @@ type Event struct {
 	description   string
 	location      string
 	geo           *Geo
+	conference    string
 	summary       string
 	rrule         string
 	class         string
@@ -27,9 +30,12 @@ type Event struct {
 	sequence      int
 	attendees     []*Attendee
 	organizer     *Attendee
+	alarms        []*Alarm
 	wholeDayEvent bool
 	inCalendar    *Calendar
 	alarmCallback func(*Event)
+	// the texts keep their iCalendar escapes, as parsed
+	escaped bool
 }
 
 func NewEvent() *Event {
@@ -137,6 +143,25 @@ func (e *Event) GetLastModified() time.Time {
 	return e.modified
 }
 
+func (e *Event) SetDTStamp(stamp time.Time) *Event {
+	e.stamp = stamp
+	return e
+}
+
+func (e *Event) GetDTStamp() time.Time {
+	return e.stamp
+}
+
+// SetRecurrenceID marks the event as a single occurrence of a repeating event
+func (e *Event) SetRecurrenceID(recurrenceID time.Time) *Event {
+	e.recurrenceID = recurrenceID
+	return e
+}
+
+func (e *Event) GetRecurrenceID() time.Time {
+	return e.recurrenceID
+}
+
 func (e *Event) SetSequence(sq int) *Event {
 	e.sequence = sq
 	return e
@@ -208,6 +233,16 @@ func (e *Event) SetAlarm(alarmAfter time.Duration, callback func(*Event)) *Event
 	return e
 }
 
+// AddAlarm attaches a VALARM component to the event
+func (e *Event) AddAlarm(a *Alarm) *Event {
+	e.alarms = append(e.alarms, a)
+	return e
+}
+
+func (e *Event) GetAlarms() []*Alarm {
+	return e.alarms
+}
+
 func (e *Event) GetAlarmFunction() func(*Event) {
 	return e.alarmCallback
 }
@@ -268,6 +303,16 @@ func (e *Event) GetGeo() *Geo {
 	return e.geo
 }
 
+// SetConference sets the URI of the online meeting (RFC 7986 CONFERENCE)
+func (e *Event) SetConference(uri string) *Event {
+	e.conference = uri
+	return e
+}
+
+func (e *Event) GetConference() string {
+	return e.conference
+}
+
 func (e *Event) String() string {
 	from := e.GetStart().Format(YmdHis)
 	to := e.GetEnd().Format(YmdHis)
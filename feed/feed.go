@@ -0,0 +1,332 @@
+// Package feed publishes the events of github.com/PuloV/ics-golang calendars
+// as Atom 1.0 and RSS 2.0 documents for feed readers.
+//
+// The id of an entry is the urn:uuid form of Event.GetID(), so it stays the
+// same while the event keeps its UID, start and end. The updated time of an
+// entry is the LAST-MODIFIED of the event, or its CREATED or DTSTAMP.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// Feed is the events published as a feed
+type Feed struct {
+	Title       string
+	Description string
+	// the link of the site of the feed
+	Link string
+	// the link of the feed document itself
+	Self string
+	// the id of the Atom feed, the link by default
+	ID     string
+	Author string
+	// the link of an event, no link by default
+	EventLink func(*ics.Event) string
+
+	events []*ics.Event
+}
+
+// Upcoming creates the feed of the next n events of the calendars
+func Upcoming(n int, calendars ...*ics.Calendar) *Feed {
+	events := []*ics.Event{}
+	for _, cal := range calendars {
+		for _, event := range cal.GetUpcomingEvents(n) {
+			event := event
+			events = append(events, &event)
+		}
+	}
+	f := newFeed(events, calendars)
+	if len(f.events) > n {
+		f.events = f.events[:n]
+	}
+	return f
+}
+
+// Range creates the feed of the events of the calendars between from and to
+func Range(from, to time.Time, calendars ...*ics.Calendar) *Feed {
+	events := []*ics.Event{}
+	for _, cal := range calendars {
+		calEvents := cal.GetEvents()
+		for i := range calEvents {
+			start, end := calEvents[i].Span(from.Location())
+			if start.Before(to) && (end.After(from) || start.Equal(end) && !start.Before(from)) {
+				events = append(events, &calEvents[i])
+			}
+		}
+	}
+	return newFeed(events, calendars)
+}
+
+func newFeed(events []*ics.Event, calendars []*ics.Calendar) *Feed {
+	names := []string{}
+	descs := []string{}
+	for _, cal := range calendars {
+		if cal.GetName() != "" {
+			names = append(names, cal.GetName())
+		}
+		if cal.GetDesc() != "" {
+			descs = append(descs, cal.GetDesc())
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].GetStart().Before(events[j].GetStart())
+	})
+	return &Feed{
+		Title:       strings.Join(names, ", "),
+		Description: strings.Join(descs, " "),
+		events:      events,
+	}
+}
+
+// Updated returns the latest updated time of the events
+func (f *Feed) Updated() time.Time {
+	updated := time.Time{}
+	for _, event := range f.events {
+		if t := updatedTime(event); t.After(updated) {
+			updated = t
+		}
+	}
+	return updated
+}
+
+// returns the last change of the event
+func updatedTime(e *ics.Event) time.Time {
+	for _, t := range []time.Time{e.GetLastModified(), e.GetCreated(), e.GetDTStamp()} {
+		if !t.IsZero() {
+			return t.UTC()
+		}
+	}
+	return e.GetStart().UTC()
+}
+
+// returns the stable id of the event as urn:uuid
+func entryID(e *ics.Event) string {
+	id := e.GetID()
+	if len(id) != 32 {
+		return "urn:ics-golang:" + id
+	}
+	return fmt.Sprintf("urn:uuid:%s-%s-%s-%s-%s", id[:8], id[8:12], id[12:16], id[16:20], id[20:])
+}
+
+// returns the text of the entry with the time, place and description
+func entryContent(e *ics.Event) string {
+	lines := []string{}
+	start, end := e.GetStart(), e.GetEnd()
+	if tz := e.GetStartTZID(); tz != "" {
+		if loc, err := ics.LoadLocation(tz); err == nil {
+			start, end = start.In(loc), end.In(loc)
+		}
+	}
+	if e.IsWholeDay() {
+		lines = append(lines, "When: "+start.Format("Monday, 2 January 2006"))
+	} else {
+		lines = append(lines, "When: "+start.Format("Monday, 2 January 2006 15:04 MST")+" - "+end.Format("15:04 MST"))
+	}
+	if location := e.GetPlainLocation(); location != "" {
+		lines = append(lines, "Where: "+location)
+	}
+	if description := e.GetPlainDescription(); description != "" {
+		lines = append(lines, "", description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ======================== ATOM ===================
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   *atomPerson `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// WriteAtom writes the feed as an Atom 1.0 document
+func (f *Feed) WriteAtom(w io.Writer) error {
+	id := f.ID
+	if id == "" {
+		id = f.Link
+	}
+	if id == "" {
+		id = "urn:ics-golang:feed:" + strings.ToLower(strings.Replace(f.Title, " ", "-", -1))
+	}
+	doc := atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       id,
+		Updated:  f.updated().Format(time.RFC3339),
+	}
+	if f.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "alternate", Href: f.Link})
+	}
+	if f.Self != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "self", Href: f.Self})
+	}
+	// an Atom feed needs an author when not every entry has one
+	author := f.Author
+	if author == "" {
+		author = f.Title
+	}
+	doc.Author = &atomPerson{Name: author}
+
+	for _, event := range f.events {
+		entry := atomEntry{
+			Title:   event.GetPlainSummary(),
+			ID:      entryID(event),
+			Updated: updatedTime(event).Format(time.RFC3339),
+			Content: atomContent{Type: "text", Text: entryContent(event)},
+		}
+		if f.EventLink != nil {
+			if link := f.EventLink(event); link != "" {
+				entry.Links = append(entry.Links, atomLink{Rel: "alternate", Href: link})
+			}
+		}
+		if o := event.GetOrganizer(); o != nil {
+			name := o.GetName()
+			if name == "" {
+				name = o.GetEmail()
+			}
+			entry.Author = &atomPerson{Name: name, Email: o.GetEmail()}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return writeXML(w, doc)
+}
+
+// Atom returns the feed as an Atom 1.0 document
+func (f *Feed) Atom() ([]byte, error) {
+	var b bytes.Buffer
+	err := f.WriteAtom(&b)
+	return b.Bytes(), err
+}
+
+// ======================== RSS ===================
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description"`
+	Author      string  `xml:"author,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSS writes the feed as a RSS 2.0 document
+func (f *Feed) WriteRSS(w io.Writer) error {
+	doc := rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+		},
+	}
+	if doc.Channel.Description == "" {
+		doc.Channel.Description = f.Title
+	}
+	for _, event := range f.events {
+		item := rssItem{
+			Title:       event.GetPlainSummary(),
+			Description: entryContent(event),
+			GUID:        rssGUID{Value: entryID(event)},
+			PubDate:     updatedTime(event).Format(time.RFC1123Z),
+		}
+		if f.EventLink != nil {
+			item.Link = f.EventLink(event)
+		}
+		// the author of a RSS item is an email address
+		if o := event.GetOrganizer(); o != nil && o.GetEmail() != "" {
+			item.Author = o.GetEmail()
+			if o.GetName() != "" {
+				item.Author += " (" + o.GetName() + ")"
+			}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
+
+// RSS returns the feed as a RSS 2.0 document
+func (f *Feed) RSS() ([]byte, error) {
+	var b bytes.Buffer
+	err := f.WriteRSS(&b)
+	return b.Bytes(), err
+}
+
+// returns the updated time of the feed, now when it has no events
+func (f *Feed) updated() time.Time {
+	updated := f.Updated()
+	if updated.IsZero() {
+		updated = time.Now().UTC()
+	}
+	return updated
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
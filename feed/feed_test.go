@@ -0,0 +1,140 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func newCalendar(t *testing.T, start time.Time) *ics.Calendar {
+	review, err := ics.NewEventBuilder().UID("review@example.com").Summary("Design review").Location("Room 1").
+		Description("Bring the slides").Start(start, time.UTC).Duration(time.Hour).
+		Organizer("Boss", "boss@example.com").Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	review.SetLastModified(time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC))
+	offsite, _ := ics.NewEventBuilder().UID("offsite@example.com").Summary("Offsite & party").
+		AllDay(start.AddDate(0, 0, 2), 1).Build()
+	// without LAST-MODIFIED the entry is updated when the event was created
+	offsite.SetLastModified(time.Time{})
+	offsite.SetCreated(time.Date(2024, 6, 2, 8, 0, 0, 0, time.UTC))
+	past, _ := ics.NewEventBuilder().UID("past@example.com").Summary("Past").
+		Start(time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC), time.UTC).Duration(time.Hour).Build()
+	cal, err := ics.NewCalendarBuilder().Name("Team").Event(review).Event(offsite).Event(past).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+	return cal
+}
+
+func TestAtom(t *testing.T) {
+	start := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	cal := newCalendar(t, start)
+	f := Range(start.AddDate(0, 0, -1), start.AddDate(0, 0, 7), cal)
+	f.Link = "https://example.com/team/"
+	f.EventLink = func(e *ics.Event) string { return "https://example.com/team/events/" + e.GetID() + ".html" }
+	data, err := f.Atom()
+	if err != nil {
+		t.Fatalf("Failed to write Atom ( %s )", err)
+	}
+	atom := string(data)
+
+	review, _ := cal.GetEventByImportedID("review@example.com")
+	id := review.GetID()
+	for _, fragment := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<title>Team</title>`,
+		`<id>https://example.com/team/</id>`,
+		`<updated>2024-06-02T08:00:00Z</updated>`,
+		`<id>urn:uuid:` + id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:] + `</id>`,
+		`<updated>2024-06-01T08:00:00Z</updated>`,
+		`<link rel="alternate" href="https://example.com/team/events/` + id + `.html"></link>`,
+		`<name>Boss</name>`,
+		`<content type="text">When: Monday, 10 June 2024 09:00 UTC - 10:00 UTC&#xA;Where: Room 1&#xA;&#xA;Bring the slides</content>`,
+		`<title>Offsite &amp; party</title>`,
+	} {
+		if !strings.Contains(atom, fragment) {
+			t.Errorf("Expected Atom to contain %s, found:\n%s", fragment, atom)
+		}
+	}
+	if strings.Contains(atom, "Past") {
+		t.Errorf("Expected the past event out of the range, found:\n%s", atom)
+	}
+
+	// the ids are the same for the same events
+	again, _ := Range(start.AddDate(0, 0, -1), start.AddDate(0, 0, 7), newCalendar(t, start)).Atom()
+	var first, second struct {
+		Entries []struct {
+			ID string `xml:"id"`
+		} `xml:"entry"`
+	}
+	xml.Unmarshal(data, &first)
+	xml.Unmarshal(again, &second)
+	if len(first.Entries) != 2 || len(second.Entries) != 2 || first.Entries[0].ID != second.Entries[0].ID || first.Entries[1].ID != second.Entries[1].ID {
+		t.Errorf("Expected the same 2 entry ids, found %v and %v", first.Entries, second.Entries)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	// upcoming events are after now
+	start := time.Now().UTC().AddDate(1, 0, 0).Truncate(time.Hour)
+	f := Upcoming(1, newCalendar(t, start))
+	data, err := f.RSS()
+	if err != nil {
+		t.Fatalf("Failed to write RSS ( %s )", err)
+	}
+	rss := string(data)
+	for _, fragment := range []string{
+		`<rss version="2.0">`,
+		`<channel>`,
+		`<title>Design review</title>`,
+		`<author>boss@example.com (Boss)</author>`,
+		`<guid isPermaLink="false">urn:uuid:`,
+		`<pubDate>Sat, 01 Jun 2024 08:00:00 +0000</pubDate>`,
+		`<lastBuildDate>Sat, 01 Jun 2024 08:00:00 +0000</lastBuildDate>`,
+	} {
+		if !strings.Contains(rss, fragment) {
+			t.Errorf("Expected RSS to contain %s, found:\n%s", fragment, rss)
+		}
+	}
+	if strings.Count(rss, "<item>") != 1 {
+		t.Errorf("Expected 1 item, found:\n%s", rss)
+	}
+}
+
+func TestEntryTexts(t *testing.T) {
+	start := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	built := ics.NewEvent().SetStart(start).SetEnd(start.Add(time.Hour)).SetLocation(`\\fileserver`).SetDescription(`regex \d+`)
+	parser := ics.New()
+	parser.Load("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:texts@example.com\r\n" +
+		"DTSTART:20240610T090000Z\r\nDTEND:20240610T100000Z\r\nLOCATION:Room 1\\, floor 2\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	calendars, _ := parser.GetCalendars()
+	parsed := calendars[0].GetEvents()[0]
+
+	for _, test := range []struct {
+		event    *ics.Event
+		expected string
+	}{
+		// the texts of the events that were never escaped used to lose their backslashes
+		{built, "Where: \\\\fileserver\n\nregex \\d+"},
+		{&parsed, "Where: Room 1, floor 2"},
+	} {
+		if content := entryContent(test.event); !strings.HasSuffix(content, test.expected) {
+			t.Errorf("Expected the entry to end with %q, found %q", test.expected, content)
+		}
+	}
+}
+
+func TestEntryWindowsTimeZone(t *testing.T) {
+	start := time.Date(2024, 6, 10, 7, 0, 0, 0, time.UTC)
+	event := ics.NewEvent().SetStart(start).SetEnd(start.Add(time.Hour))
+	event.SetStartTZID("Romance Standard Time")
+	// the Windows names of Outlook used to be shown in UTC
+	if content := entryContent(event); !strings.HasPrefix(content, "When: Monday, 10 June 2024 09:00 CEST - 10:00 CEST") {
+		t.Errorf("Expected the time in Paris, found %q", content)
+	}
+}
@@ -0,0 +1,253 @@
+package ics
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Fetcher gets the content of the calendars of an url. The parser closes
+// the returned reader after reading it. A reader with a NotModified() bool
+// method returning true has the content of the last fetch of the url, and
+// the calendar parsed before from the url is kept instead of parsing it again.
+// A reader with a Stale() error method returning an error is a copy served
+// because the fetch failed with the error, the copy is parsed when no
+// calendar was parsed before from the url and the error is recorded in the
+// health of the url.
+type Fetcher interface {
+	Fetch(url string) (io.ReadCloser, error)
+}
+
+// FetcherFunc is a function used as Fetcher
+type FetcherFunc func(url string) (io.ReadCloser, error)
+
+// Fetch calls f(url)
+func (f FetcherFunc) Fetch(url string) (io.ReadCloser, error) {
+	return f(url)
+}
+
+// matches the scheme of an url, single letters are drives like C:\
+var schemeRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]+):`)
+
+// returns the lowercase scheme of the url, or "" for a path
+func urlScheme(url string) string {
+	matches := schemeRegex.FindStringSubmatch(url)
+	if matches == nil {
+		return ""
+	}
+	return strings.ToLower(matches[1])
+}
+
+// HTTPFetcher downloads the http, https, webcal and webcals urls. The
+// webcal urls are fetched over http and the webcals urls over https.
+type HTTPFetcher struct {
+	// the client of the requests, http.DefaultClient when nil
+	Client *http.Client
+	// the cache of the downloads, temp files in FilePath when nil
+	Cache *Cache
+	// the credentials of the urls by prefix
+	Credentials map[string]Credentials
+	// the retries of the failed downloads, none when nil
+	Retry *RetryPolicy
+	// the largest body downloaded, 0 is no limit
+	MaxBytes int64
+	// the hosts and addresses allowed, all when nil
+	Policy *FetchPolicy
+}
+
+// Fetch downloads the url to the cache or a temp file in FilePath. The
+// secrets of the url are hidden in the errors.
+func (f *HTTPFetcher) Fetch(url string) (io.ReadCloser, error) {
+	request, err := http.NewRequest("GET", webcalToHTTP(url), nil)
+	if err != nil {
+		return nil, f.redactError(err, url)
+	}
+	if c := f.credentialsOf(url); c != nil {
+		if request, err = authorize(request, c); err != nil {
+			return nil, f.redactError(err, url)
+		}
+	}
+	target := request.URL.String()
+
+	if f.Cache != nil {
+		body, err := f.Cache.fetch(f.send, url, request)
+		return body, f.redactError(err, target, url)
+	}
+	fileName, err := downloadFromUrl(f.send, request)
+	if err != nil {
+		return nil, f.redactError(err, target, url)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &tempFile{file}, nil
+}
+
+// replaces the webcal and webcals schemes with http and https
+func webcalToHTTP(url string) string {
+	switch urlScheme(url) {
+	case "webcal":
+		return "http" + url[len("webcal"):]
+	case "webcals":
+		return "https" + url[len("webcals"):]
+	}
+	return url
+}
+
+// tempFile is a downloaded file, removed on close if DeleteTempFiles is true
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	if DeleteTempFiles {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// FileFetcher reads the local files, given as a path or a file: url
+type FileFetcher struct{}
+
+// Fetch opens the file of the url
+func (FileFetcher) Fetch(url string) (io.ReadCloser, error) {
+	path := url
+	if urlScheme(url) == "file" {
+		u, err := neturl.Parse(url)
+		if err != nil {
+			return nil, err
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return nil, fmt.Errorf("File %s is not on this host", url)
+		}
+		path = u.Path
+		if u.Opaque != "" {
+			// file:relative/path
+			path, _ = neturl.PathUnescape(u.Opaque)
+		}
+	}
+	if !fileExists(path) {
+		return nil, fmt.Errorf("File %s does not exists", url)
+	}
+	return os.Open(path)
+}
+
+// DataFetcher decodes the data: urls (RFC 2397), percent encoded or base64
+type DataFetcher struct{}
+
+// Fetch returns the data of the url
+func (DataFetcher) Fetch(url string) (io.ReadCloser, error) {
+	comma := strings.Index(url, ",")
+	if urlScheme(url) != "data" || comma < 0 {
+		return nil, errors.New("Invalid data url")
+	}
+	mediaType, data := url[len("data:"):comma], url[comma+1:]
+	content, err := neturl.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(mediaType), ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+		if err != nil {
+			return nil, err
+		}
+		content = string(decoded)
+	}
+	return ioutil.NopCloser(strings.NewReader(content)), nil
+}
+
+// returns the built in fetchers by scheme
+func defaultFetchers(client *http.Client) map[string]Fetcher {
+	web := &HTTPFetcher{Client: client}
+	return map[string]Fetcher{
+		"http":    web,
+		"https":   web,
+		"webcal":  web,
+		"webcals": web,
+		"file":    FileFetcher{},
+		"data":    DataFetcher{},
+	}
+}
+
+// RegisterFetcher sets the fetcher of the urls with the scheme, like "s3" for
+// s3://bucket/cal.ics. The "file" fetcher also reads the paths without a
+// scheme. A nil fetcher removes the scheme.
+func (p *Parser) RegisterFetcher(scheme string, f Fetcher) {
+	p.fetchMutex.Lock()
+	defer p.fetchMutex.Unlock()
+	scheme = strings.ToLower(scheme)
+	if f == nil {
+		delete(p.fetchers, scheme)
+		return
+	}
+	p.fetchers[scheme] = f
+}
+
+// GetFetcher returns the fetcher of the scheme, nil if there is none
+func (p *Parser) GetFetcher(scheme string) Fetcher {
+	p.fetchMutex.Lock()
+	defer p.fetchMutex.Unlock()
+	return p.fetchers[strings.ToLower(scheme)]
+}
+
+// SetHTTPClient sets the client of the http, https, webcal and webcals urls,
+// it replaces the fetchers of these schemes
+func (p *Parser) SetHTTPClient(client *http.Client) *Parser {
+	web := p.httpFetcher()
+	web.Client = client
+	p.setHTTPFetcher(web)
+	return p
+}
+
+// SetCache sets the cache of the http, https, webcal and webcals urls,
+// it replaces the fetchers of these schemes
+func (p *Parser) SetCache(cache *Cache) *Parser {
+	web := p.httpFetcher()
+	web.Cache = cache
+	p.setHTTPFetcher(web)
+	return p
+}
+
+// returns a copy of the http fetcher
+func (p *Parser) httpFetcher() *HTTPFetcher {
+	web := &HTTPFetcher{}
+	if current, ok := p.GetFetcher("http").(*HTTPFetcher); ok {
+		*web = *current
+	}
+	return web
+}
+
+func (p *Parser) setHTTPFetcher(web *HTTPFetcher) {
+	for _, scheme := range []string{"http", "https", "webcal", "webcals"} {
+		p.RegisterFetcher(scheme, web)
+	}
+}
+
+// returns the fetcher of the url
+func (p *Parser) fetcherOf(url string) (Fetcher, error) {
+	scheme := urlScheme(url)
+	local := scheme == "file" || scheme == "" || p.GetFetcher(scheme) == nil && fileExists(url)
+	if policy := p.GetFetchPolicy(); local && policy != nil && policy.DenyLocalFiles {
+		return nil, &BlockedError{Target: p.redact(url), Reason: "the local files are denied"}
+	}
+	if f := p.GetFetcher(scheme); f != nil {
+		return f, nil
+	}
+	// paths without a scheme and files like "a:b.ics" are local
+	if local {
+		if f := p.GetFetcher("file"); f != nil {
+			return f, nil
+		}
+		return nil, fmt.Errorf("Local files are not allowed, can't read %s", p.redact(url))
+	}
+	return nil, fmt.Errorf("No fetcher for the scheme %s of %s", scheme, p.redact(url))
+}
@@ -0,0 +1,168 @@
+package ics
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const fetchTestCal = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nX-WR-CALNAME:Fetched\r\nBEGIN:VEVENT\r\nUID:fetch-1\r\nDTSTART:20200102T100000Z\r\nDTEND:20200102T110000Z\r\nSUMMARY:Fetched event\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+// parses the urls and returns the calendars and the errors
+func fetchAll(parser *Parser, urls ...string) ([]*Calendar, []error) {
+	input := parser.GetInputChan()
+	for _, url := range urls {
+		input <- url
+	}
+	parser.Wait()
+	calendars, _ := parser.GetCalendars()
+	errs, _ := parser.GetErrors()
+	return calendars, errs
+}
+
+func TestFetchHTTPAndWebcal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.ics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(fetchTestCal))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "ics-fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	webcal := "webcal" + strings.TrimPrefix(server.URL, "http") + "/cal.ics"
+	calendars, errs := fetchAll(New(), server.URL+"/cal.ics", webcal, server.URL+"/missing.ics")
+	if len(calendars) != 2 {
+		t.Errorf("Expected 2 calendars, found %d", len(calendars))
+	}
+	for _, cal := range calendars {
+		if cal.GetName() != "Fetched" {
+			t.Errorf("Expected calendar Fetched from %s, found %s", cal.GetUrl(), cal.GetName())
+		}
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "404") {
+		t.Errorf("Expected 1 error with the status 404, found %v", errs)
+	}
+	files, _ := filepath.Glob(FilePath + "*")
+	if len(files) != 0 {
+		t.Errorf("Expected the temp files to be deleted, found %v", files)
+	}
+}
+
+type headerTransport struct {
+	requests int32
+}
+
+func (h *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&h.requests, 1)
+	if r.Header.Get("X-Test") != "" {
+		return nil, errors.New("unexpected header")
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestFetchWithHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fetchTestCal))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "ics-fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	transport := &headerTransport{}
+	parser := New().SetHTTPClient(&http.Client{Transport: transport})
+	calendars, errs := fetchAll(parser, server.URL+"/a.ics", server.URL+"/b.ics")
+	if len(calendars) != 2 || len(errs) != 0 {
+		t.Errorf("Expected 2 calendars and no errors, found %d and %v", len(calendars), errs)
+	}
+	if transport.requests != 2 {
+		t.Errorf("Expected 2 requests through the client, found %d", transport.requests)
+	}
+}
+
+func TestFetchFileAndData(t *testing.T) {
+	path, err := filepath.Abs("testCalendars/2eventsCal.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{
+		"testCalendars/2eventsCal.ics",
+		"file://" + filepath.ToSlash(path),
+		"data:text/calendar;base64," + base64.StdEncoding.EncodeToString([]byte(fetchTestCal)),
+		"data:text/calendar," + neturl.PathEscape(fetchTestCal),
+	}
+	calendars, errs := fetchAll(New(), urls...)
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, found %v", errs)
+	}
+	names := map[string]int{}
+	for _, cal := range calendars {
+		names[cal.GetName()]++
+	}
+	if names["2 Events Cal"] != 2 || names["Fetched"] != 2 {
+		t.Errorf("Expected 2 calendars of each source, found %v", names)
+	}
+}
+
+func TestRegisterFetcher(t *testing.T) {
+	parser := New()
+	fetched := ""
+	parser.RegisterFetcher("S3", FetcherFunc(func(url string) (io.ReadCloser, error) {
+		fetched = url
+		return ioutil.NopCloser(strings.NewReader(fetchTestCal)), nil
+	}))
+	calendars, errs := fetchAll(parser, "s3://bucket/team.ics", "ftp://example.com/cal.ics")
+	if len(calendars) != 1 || calendars[0].GetName() != "Fetched" {
+		t.Errorf("Expected the calendar of the s3 fetcher, found %d calendars", len(calendars))
+	}
+	if fetched != "s3://bucket/team.ics" {
+		t.Errorf("Expected the s3 fetcher to get s3://bucket/team.ics, found %s", fetched)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "ftp") {
+		t.Errorf("Expected 1 error for the ftp scheme, found %v", errs)
+	}
+
+	parser = New()
+	parser.RegisterFetcher("file", nil)
+	_, errs = fetchAll(parser, "testCalendars/2eventsCal.ics")
+	if len(errs) != 1 {
+		t.Errorf("Expected 1 error without a file fetcher, found %v", errs)
+	}
+}
+
+func TestURLScheme(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/a.ics": "https",
+		"WEBCAL://example.com":      "webcal",
+		"data:,BEGIN":               "data",
+		"testCalendars/a.ics":       "",
+		`C:\cals\a.ics`:             "",
+		"/tmp/a:b.ics":              "",
+	}
+	for url, expected := range cases {
+		if scheme := urlScheme(url); scheme != expected {
+			t.Errorf("Expected the scheme of %s to be %q, found %q", url, expected, scheme)
+		}
+	}
+}
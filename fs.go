@@ -0,0 +1,58 @@
+//go:build go1.16
+// +build go1.16
+
+package ics
+
+import (
+	"io"
+	"io/fs"
+)
+
+// FSFetcher reads the files of a file system, like an embed.FS, a zip.Reader
+// or a fstest.MapFS
+type FSFetcher struct {
+	FS fs.FS
+}
+
+// Fetch opens the file of the path in the file system
+func (f FSFetcher) Fetch(path string) (io.ReadCloser, error) {
+	return f.FS.Open(path)
+}
+
+// ParseFS parses the files of the file system matching the patterns, "*.ics"
+// by default, like the urls sent to the input chan: each file is parsed in
+// its own goroutine, its events are sent to the output chan and Wait waits
+// for them. The calendars have the path of their file as url.
+func (p *Parser) ParseFS(fsys fs.FS, patterns ...string) error {
+	if len(patterns) == 0 {
+		patterns = []string{"*.ics"}
+	}
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			if info, err := fs.Stat(fsys, path); seen[path] || err == nil && info.IsDir() {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	fetcher := FSFetcher{FS: fsys}
+	getICal := func(path string) ([]icalFile, bool, error) {
+		return p.readICal(fetcher, path)
+	}
+	for _, path := range paths {
+		p.startParsing()
+		go func(path string) {
+			defer p.doneParsing()
+			p.parseLink(path, getICal)
+		}(path)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build go1.16
+// +build go1.16
+
+package ics
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testCalendars/*.ics
+var embeddedCalendars embed.FS
+
+// returns the names and urls of the calendars of the parser
+func calendarNames(parser *Parser) []string {
+	calendars, _ := parser.GetCalendars()
+	found := []string{}
+	for _, cal := range calendars {
+		found = append(found, cal.GetName()+" "+cal.GetUrl())
+	}
+	sort.Strings(found)
+	return found
+}
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.ics":           {Data: []byte(strings.Replace(fetchTestCal, "Fetched", "A", 1))},
+		"team/b.ics.gz":   {Data: gzipped(strings.Replace(fetchTestCal, "Fetched", "B", 1))},
+		"team/big.ics.gz": {Data: gzipped(fetchTestCal + strings.Repeat("X-FILLER:0\r\n", 1000))},
+		"notes.txt":       {Data: []byte("not a calendar")},
+		"dir.ics/c.ics":   {Data: []byte(fetchTestCal)},
+	}
+	parser := New().SetLimits(Limits{MaxBytes: 4096})
+	if err := parser.ParseFS(fsys, "*.ics", "team/*.ics.gz", "a.ics"); err != nil {
+		t.Fatal(err)
+	}
+	events := 0
+	done := make(chan struct{})
+	go func() {
+		parser.Wait()
+		close(done)
+	}()
+	for events < 2 {
+		<-parser.GetOutputChan()
+		events++
+	}
+	<-done
+
+	expected := []string{"A a.ics", "B team/b.ics.gz"}
+	if found := calendarNames(parser); strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the calendars\n%s\nfound\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+	errs, _ := parser.GetErrors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, found %v", errs)
+	}
+	if _, ok := errs[0].(*LimitError); !ok {
+		t.Errorf("Expected a LimitError, found %#v", errs[0])
+	}
+
+	if err := New().ParseFS(fsys, "[.ics"); err == nil {
+		t.Errorf("Expected an error of the bad pattern")
+	}
+}
+
+func TestParseEmbedAndZipFS(t *testing.T) {
+	parser := New()
+	if err := parser.ParseFS(embeddedCalendars, "testCalendars/*.ics"); err != nil {
+		t.Fatal(err)
+	}
+	parser.Wait()
+	files, _ := embeddedCalendars.ReadDir("testCalendars")
+	calendars, _ := parser.GetCalendars()
+	if errs, _ := parser.GetErrors(); len(calendars) != len(files) || len(errs) != 0 {
+		t.Errorf("Expected a calendar for each of the %d embedded files, found %d and %v", len(files), len(calendars), errs)
+	}
+
+	data := takeoutZip()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser = New()
+	parser.ParseFS(archive, "Takeout/Calendar/*")
+	parser.Wait()
+	expected := []string{"Home Takeout/Calendar/Home.ics.gz", "Work Takeout/Calendar/Work.ics"}
+	if found := calendarNames(parser); strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the calendars\n%s\nfound\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
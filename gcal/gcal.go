@@ -0,0 +1,444 @@
+// Package gcal converts between the events of github.com/PuloV/ics-golang
+// and the Event resource of the Google Calendar API v3. It works on JSON
+// dumps of the API and makes no API calls.
+package gcal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// Event is the Google Calendar API v3 Event resource
+type Event struct {
+	Kind              string          `json:"kind,omitempty"`
+	ID                string          `json:"id,omitempty"`
+	ICalUID           string          `json:"iCalUID,omitempty"`
+	Status            string          `json:"status,omitempty"`
+	HTMLLink          string          `json:"htmlLink,omitempty"`
+	Created           *time.Time      `json:"created,omitempty"`
+	Updated           *time.Time      `json:"updated,omitempty"`
+	Summary           string          `json:"summary,omitempty"`
+	Description       string          `json:"description,omitempty"`
+	Location          string          `json:"location,omitempty"`
+	Creator           *Person         `json:"creator,omitempty"`
+	Organizer         *Person         `json:"organizer,omitempty"`
+	Start             *EventDateTime  `json:"start,omitempty"`
+	End               *EventDateTime  `json:"end,omitempty"`
+	Recurrence        []string        `json:"recurrence,omitempty"`
+	RecurringEventID  string          `json:"recurringEventId,omitempty"`
+	OriginalStartTime *EventDateTime  `json:"originalStartTime,omitempty"`
+	Transparency      string          `json:"transparency,omitempty"`
+	Visibility        string          `json:"visibility,omitempty"`
+	Sequence          int             `json:"sequence,omitempty"`
+	Attendees         []*Attendee     `json:"attendees,omitempty"`
+	HangoutLink       string          `json:"hangoutLink,omitempty"`
+	ConferenceData    *ConferenceData `json:"conferenceData,omitempty"`
+}
+
+// Person is the creator or organizer of an event
+type Person struct {
+	Email       string `json:"email,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Self        bool   `json:"self,omitempty"`
+}
+
+// EventDateTime is a date for whole day events or a time with its zone
+type EventDateTime struct {
+	Date     string `json:"date,omitempty"`
+	DateTime string `json:"dateTime,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Attendee is a guest of an event
+type Attendee struct {
+	Email          string `json:"email,omitempty"`
+	DisplayName    string `json:"displayName,omitempty"`
+	ResponseStatus string `json:"responseStatus,omitempty"`
+	Optional       bool   `json:"optional,omitempty"`
+	Organizer      bool   `json:"organizer,omitempty"`
+	Resource       bool   `json:"resource,omitempty"`
+	Self           bool   `json:"self,omitempty"`
+	Comment        string `json:"comment,omitempty"`
+}
+
+// ConferenceData is the online meeting of an event
+type ConferenceData struct {
+	ConferenceID       string              `json:"conferenceId,omitempty"`
+	ConferenceSolution *ConferenceSolution `json:"conferenceSolution,omitempty"`
+	EntryPoints        []*EntryPoint       `json:"entryPoints,omitempty"`
+}
+
+// ConferenceSolution is the product of the online meeting like Google Meet
+type ConferenceSolution struct {
+	Name    string `json:"name,omitempty"`
+	IconURI string `json:"iconUri,omitempty"`
+}
+
+// EntryPoint is a way to join the online meeting
+type EntryPoint struct {
+	EntryPointType string `json:"entryPointType,omitempty"`
+	URI            string `json:"uri,omitempty"`
+	Label          string `json:"label,omitempty"`
+}
+
+// Events is the Events list resource, the usual form of a dump
+type Events struct {
+	Kind        string   `json:"kind,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	Description string   `json:"description,omitempty"`
+	TimeZone    string   `json:"timeZone,omitempty"`
+	Items       []*Event `json:"items"`
+}
+
+// the layout of whole day dates
+const dateLayout = "2006-01-02"
+
+// ======================== DUMPS ===================
+
+// Decode reads an Events list, an array of events or a single event
+func Decode(r io.Reader) (*Events, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	events := &Events{}
+	switch {
+	case len(data) == 0:
+		return nil, errors.New("gcal: empty dump")
+	case data[0] == '[':
+		err = json.Unmarshal(data, &events.Items)
+	default:
+		var probe struct {
+			Items json.RawMessage `json:"items"`
+		}
+		if err = json.Unmarshal(data, &probe); err != nil {
+			return nil, err
+		}
+		if probe.Items != nil {
+			err = json.Unmarshal(data, events)
+		} else {
+			event := &Event{}
+			err = json.Unmarshal(data, event)
+			events.Items = []*Event{event}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReadFile reads a dump and converts it to a calendar
+func ReadFile(path string) (*ics.Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("gcal: failed to read %s ( %s )", path, err)
+	}
+	cal, err := events.ToCalendar()
+	if err != nil {
+		return nil, err
+	}
+	cal.SetUrl(path)
+	return cal, nil
+}
+
+// WriteFile writes the events of the calendar as an Events list
+func WriteFile(path string, cal *ics.Calendar) error {
+	events, err := FromCalendar(cal)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ToCalendar converts the events of the list to a calendar
+func (l *Events) ToCalendar() (*ics.Calendar, error) {
+	cal := ics.NewCalendar()
+	cal.SetName(l.Summary)
+	cal.SetDesc(l.Description)
+	cal.SetVersion(2.0)
+	if l.TimeZone != "" {
+		loc, err := time.LoadLocation(l.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("gcal: invalid calendar time zone %s ( %s )", l.TimeZone, err)
+		}
+		cal.SetTimezone(*loc)
+	}
+	for i, item := range l.Items {
+		event, err := item.ToEvent()
+		if err != nil {
+			return nil, fmt.Errorf("gcal: event %d ( %s )", i, err)
+		}
+		event.SetCalendar(cal)
+		if _, err := cal.SetEvent(*event); err != nil {
+			return nil, err
+		}
+	}
+	return cal, nil
+}
+
+// FromCalendar converts the events of the calendar to an Events list.
+// Repeating events are written once with their recurrence.
+func FromCalendar(cal *ics.Calendar) (*Events, error) {
+	tz := cal.GetTimezone()
+	l := &Events{
+		Kind:        "calendar#events",
+		Summary:     cal.GetName(),
+		Description: cal.GetDesc(),
+		TimeZone:    tz.String(),
+		Items:       []*Event{},
+	}
+	repeated := map[string]bool{}
+	events := cal.GetEvents()
+	for i := range events {
+		event := &events[i]
+		if event.GetRRule() != "" {
+			if repeated[event.GetImportedID()] {
+				continue
+			}
+			repeated[event.GetImportedID()] = true
+		}
+		item, err := FromEvent(event)
+		if err != nil {
+			return nil, err
+		}
+		l.Items = append(l.Items, item)
+	}
+	return l, nil
+}
+
+// ======================== EVENTS ===================
+
+var statuses = map[string]string{
+	"confirmed": "CONFIRMED",
+	"tentative": "TENTATIVE",
+	"cancelled": "CANCELLED",
+}
+
+var visibilities = map[string]string{
+	"public":       "PUBLIC",
+	"private":      "PRIVATE",
+	"confidential": "CONFIDENTIAL",
+}
+
+var responses = map[string]string{
+	"needsAction": "NEEDS-ACTION",
+	"accepted":    "ACCEPTED",
+	"declined":    "DECLINED",
+	"tentative":   "TENTATIVE",
+}
+
+// returns the key of the value in the map
+func reverse(m map[string]string, value string) string {
+	for k, v := range m {
+		if v == strings.ToUpper(value) {
+			return k
+		}
+	}
+	return ""
+}
+
+// ToEvent converts the Google event to an event
+func (g *Event) ToEvent() (*ics.Event, error) {
+	e := ics.NewEvent()
+	uid := g.ICalUID
+	if uid == "" && g.ID != "" {
+		uid = g.ID + "@google.com"
+	}
+	e.SetImportedID(uid)
+	e.SetSummary(g.Summary)
+	e.SetDescription(g.Description)
+	e.SetLocation(g.Location)
+	e.SetStatus(statuses[g.Status])
+	e.SetClass(visibilities[g.Visibility])
+	if g.Transparency == "transparent" {
+		e.SetBusyStatus("FREE")
+	}
+	e.SetSequence(g.Sequence)
+	if g.Created != nil {
+		e.SetCreated(*g.Created)
+	}
+	if g.Updated != nil {
+		e.SetLastModified(*g.Updated)
+		e.SetDTStamp(*g.Updated)
+	}
+
+	if g.Start == nil {
+		return nil, errors.New("event has no start")
+	}
+	start, tzid, wholeDay, err := g.Start.time()
+	if err != nil {
+		return nil, err
+	}
+	end := start
+	endTZID := tzid
+	if g.End != nil {
+		if end, endTZID, _, err = g.End.time(); err != nil {
+			return nil, err
+		}
+	}
+	e.SetStart(start)
+	e.SetEnd(end)
+	e.SetStartTZID(tzid)
+	e.SetEndTZID(endTZID)
+	e.SetWholeDayEvent(wholeDay)
+	if g.OriginalStartTime != nil {
+		recurrenceID, _, _, err := g.OriginalStartTime.time()
+		if err != nil {
+			return nil, err
+		}
+		e.SetRecurrenceID(recurrenceID)
+	}
+	for _, line := range g.Recurrence {
+		if strings.HasPrefix(strings.ToUpper(line), "RRULE:") {
+			e.SetRRule(line[len("RRULE:"):])
+		}
+	}
+
+	if g.Organizer != nil {
+		e.SetOrganizer(ics.NewAttendee().SetName(g.Organizer.DisplayName).SetEmail(g.Organizer.Email))
+	}
+	for _, a := range g.Attendees {
+		attendee := ics.NewAttendee().
+			SetName(a.DisplayName).
+			SetEmail(a.Email).
+			SetStatus(responses[a.ResponseStatus]).
+			SetType("INDIVIDUAL").
+			SetRole("REQ-PARTICIPANT")
+		if a.Optional {
+			attendee.SetRole("OPT-PARTICIPANT")
+		}
+		if a.Resource {
+			attendee.SetType("RESOURCE")
+		}
+		e.SetAttendee(attendee)
+	}
+
+	if g.HangoutLink != "" {
+		e.SetConference(g.HangoutLink)
+	}
+	if g.ConferenceData != nil {
+		for _, entry := range g.ConferenceData.EntryPoints {
+			if entry.EntryPointType == "video" {
+				e.SetConference(entry.URI)
+				break
+			}
+		}
+	}
+
+	e.SetID(e.GenerateEventId())
+	return e, nil
+}
+
+// FromEvent converts the event to a Google event
+func FromEvent(e *ics.Event) (*Event, error) {
+	g := &Event{
+		Kind:        "calendar#event",
+		ICalUID:     e.GetImportedID(),
+		Status:      reverse(statuses, e.GetStatus()),
+		Summary:     e.GetPlainSummary(),
+		Description: e.GetPlainDescription(),
+		Location:    e.GetPlainLocation(),
+		Visibility:  reverse(visibilities, e.GetClass()),
+		Sequence:    e.GetSequence(),
+		Start:       newEventDateTime(e.GetStart(), e.GetStartTZID(), e.IsWholeDay()),
+		End:         newEventDateTime(e.GetEnd(), e.GetEndTZID(), e.IsWholeDay()),
+	}
+	if g.ICalUID == "" {
+		g.ICalUID = e.GetID()
+	}
+	if strings.EqualFold(e.GetBusyStatus(), "FREE") {
+		g.Transparency = "transparent"
+	}
+	if created := e.GetCreated(); !created.IsZero() {
+		g.Created = &created
+	}
+	if modified := e.GetLastModified(); !modified.IsZero() {
+		g.Updated = &modified
+	}
+	if !e.GetRecurrenceID().IsZero() {
+		g.OriginalStartTime = newEventDateTime(e.GetRecurrenceID(), e.GetStartTZID(), e.IsWholeDay())
+	}
+	if e.GetRRule() != "" {
+		if _, err := ics.ParseRRule(e.GetRRule()); err != nil {
+			return nil, err
+		}
+		g.Recurrence = []string{"RRULE:" + e.GetRRule()}
+	}
+
+	if o := e.GetOrganizer(); o != nil {
+		g.Organizer = &Person{Email: o.GetEmail(), DisplayName: o.GetName()}
+	}
+	for _, a := range e.GetAttendees() {
+		status := reverse(responses, a.GetStatus())
+		if status == "" {
+			status = "needsAction"
+		}
+		g.Attendees = append(g.Attendees, &Attendee{
+			Email:          a.GetEmail(),
+			DisplayName:    a.GetName(),
+			ResponseStatus: status,
+			Optional:       a.GetRole() == "OPT-PARTICIPANT",
+			Resource:       a.GetType() == "RESOURCE" || a.GetType() == "ROOM",
+		})
+	}
+
+	if e.GetConference() != "" {
+		g.ConferenceData = &ConferenceData{
+			EntryPoints: []*EntryPoint{{EntryPointType: "video", URI: e.GetConference()}},
+		}
+	}
+	return g, nil
+}
+
+// returns the time, its TZID and whether it is a whole day
+func (d *EventDateTime) time() (time.Time, string, bool, error) {
+	if d.Date != "" {
+		// whole day events are kept at midnight UTC like the parser does
+		t, err := time.Parse(dateLayout, d.Date)
+		return t, "", true, err
+	}
+	t, err := time.Parse(time.RFC3339, d.DateTime)
+	if err != nil {
+		return t, "", false, err
+	}
+	if d.TimeZone == "" {
+		return t, "", false, nil
+	}
+	loc, err := time.LoadLocation(d.TimeZone)
+	if err != nil {
+		return t, "", false, fmt.Errorf("invalid time zone %s ( %s )", d.TimeZone, err)
+	}
+	return t.In(loc), d.TimeZone, false, nil
+}
+
+func newEventDateTime(t time.Time, tzid string, wholeDay bool) *EventDateTime {
+	if wholeDay {
+		return &EventDateTime{Date: t.Format(dateLayout)}
+	}
+	if tzid != "" {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			return &EventDateTime{DateTime: t.In(loc).Format(time.RFC3339), TimeZone: tzid}
+		}
+	}
+	return &EventDateTime{DateTime: t.Format(time.RFC3339)}
+}
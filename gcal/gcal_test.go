@@ -0,0 +1,166 @@
+package gcal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func TestReadFile(t *testing.T) {
+	cal, err := ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatalf("Failed to read the dump ( %s )", err)
+	}
+	if cal.GetName() != "Team" {
+		t.Errorf("Expected calendar name Team, found %s", cal.GetName())
+	}
+	events := cal.GetEvents()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, found %d", len(events))
+	}
+
+	review := events[0]
+	if review.GetImportedID() != "review@example.com" {
+		t.Errorf("Expected UID review@example.com, found %s", review.GetImportedID())
+	}
+	start := time.Date(2024, 6, 10, 7, 0, 0, 0, time.UTC)
+	if !review.GetStart().Equal(start) || review.GetStartTZID() != "Europe/Paris" {
+		t.Errorf("Expected start %s in Europe/Paris, found %s in %s", start, review.GetStart(), review.GetStartTZID())
+	}
+	if review.GetStatus() != "CONFIRMED" || review.GetSequence() != 2 {
+		t.Errorf("Expected CONFIRMED sequence 2, found %s sequence %d", review.GetStatus(), review.GetSequence())
+	}
+	if review.GetRRule() != "FREQ=WEEKLY;COUNT=4;BYDAY=MO" {
+		t.Errorf("Expected weekly rrule, found %s", review.GetRRule())
+	}
+	if review.GetConference() != "https://meet.google.com/abc-defg-hij" {
+		t.Errorf("Expected the Meet link as conference, found %s", review.GetConference())
+	}
+	if review.GetOrganizer() == nil || review.GetOrganizer().GetEmail() != "boss@example.com" {
+		t.Errorf("Expected organizer boss@example.com, found %v", review.GetOrganizer())
+	}
+	attendees := review.GetAttendees()
+	if len(attendees) != 3 {
+		t.Fatalf("Expected 3 attendees, found %d", len(attendees))
+	}
+	for i, expected := range [][3]string{
+		{"ACCEPTED", "REQ-PARTICIPANT", "INDIVIDUAL"},
+		{"TENTATIVE", "OPT-PARTICIPANT", "INDIVIDUAL"},
+		{"NEEDS-ACTION", "REQ-PARTICIPANT", "RESOURCE"},
+	} {
+		a := attendees[i]
+		if a.GetStatus() != expected[0] || a.GetRole() != expected[1] || a.GetType() != expected[2] {
+			t.Errorf("Expected attendee %d to be %v, found %s %s %s", i, expected, a.GetStatus(), a.GetRole(), a.GetType())
+		}
+	}
+
+	offsite := events[1]
+	if !offsite.IsWholeDay() || !offsite.GetStart().Equal(time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected whole day event on 2024-06-20, found %s", offsite.GetStart())
+	}
+	if offsite.GetImportedID() != "7h2j4k6l8m0n@google.com" {
+		t.Errorf("Expected UID from the Google id, found %s", offsite.GetImportedID())
+	}
+	if offsite.GetBusyStatus() != "FREE" || offsite.GetClass() != "PRIVATE" || offsite.GetStatus() != "TENTATIVE" {
+		t.Errorf("Expected FREE PRIVATE TENTATIVE, found %s %s %s", offsite.GetBusyStatus(), offsite.GetClass(), offsite.GetStatus())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cal, err := ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatalf("Failed to read the dump ( %s )", err)
+	}
+	dir, err := ioutil.TempDir("", "gcal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.json")
+	if err := WriteFile(path, cal); err != nil {
+		t.Fatalf("Failed to write the dump ( %s )", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	for _, fragment := range []string{
+		`"iCalUID": "review@example.com"`,
+		`"dateTime": "2024-06-10T09:00:00+02:00"`,
+		`"timeZone": "Europe/Paris"`,
+		`"date": "2024-06-20"`,
+		`"RRULE:FREQ=WEEKLY;COUNT=4;BYDAY=MO"`,
+		`"responseStatus": "tentative"`,
+		`"uri": "https://meet.google.com/abc-defg-hij"`,
+		`"description": "Bring the slides, please"`,
+	} {
+		if !strings.Contains(string(data), fragment) {
+			t.Errorf("Expected the dump to contain %s, found:\n%s", fragment, data)
+		}
+	}
+
+	back, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the written dump ( %s )", err)
+	}
+	if back.Serialize() != cal.Serialize() {
+		t.Errorf("Expected the same calendar after the round trip:\n%s\nfound:\n%s", cal.Serialize(), back.Serialize())
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, dump := range []string{
+		`[{"id": "a", "start": {"date": "2024-06-20"}}]`,
+		`{"id": "a", "start": {"date": "2024-06-20"}}`,
+		`{"items": [{"id": "a", "start": {"date": "2024-06-20"}}]}`,
+	} {
+		events, err := Decode(bytes.NewBufferString(dump))
+		if err != nil {
+			t.Errorf("Failed to decode %s ( %s )", dump, err)
+			continue
+		}
+		if len(events.Items) != 1 || events.Items[0].ID != "a" {
+			t.Errorf("Expected 1 event with id a in %s, found %v", dump, events.Items)
+		}
+	}
+
+	events, _ := Decode(bytes.NewBufferString(`{"id": "a"}`))
+	if _, err := events.ToCalendar(); err == nil {
+		t.Errorf("Expected error for event without start")
+	}
+	if _, err := FromEvent(ics.NewEvent().SetRRule("FREQ=SOMETIMES")); err == nil {
+		t.Errorf("Expected error for invalid rrule")
+	}
+}
+
+func TestFromEventTexts(t *testing.T) {
+	// the texts of the events that were never escaped used to lose their backslashes
+	built := ics.NewEvent().SetSummary(`regex \d+\n`).SetDescription(`a\b`).SetLocation(`C:\rooms`)
+	parser := ics.New()
+	parser.Load("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:texts@example.com\r\nDTSTART:20240610T090000Z\r\n" +
+		"SUMMARY:regex \\\\d+\\\\n\r\nDESCRIPTION:Bring the slides\\, please\r\nLOCATION:a\\\\b\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 || len(calendars[0].GetEvents()) != 1 {
+		t.Fatalf("Expected 1 parsed event")
+	}
+	parsed := calendars[0].GetEvents()[0]
+	for _, test := range []struct {
+		event                          *ics.Event
+		summary, description, location string
+	}{
+		{built, `regex \d+\n`, `a\b`, `C:\rooms`},
+		{&parsed, `regex \d+\n`, "Bring the slides, please", `a\b`},
+	} {
+		g, err := FromEvent(test.event)
+		if err != nil {
+			t.Fatalf("Failed to convert the event ( %s )", err)
+		}
+		if g.Summary != test.summary || g.Description != test.description || g.Location != test.location {
+			t.Errorf("Expected %q, %q and %q, found %q, %q and %q", test.summary, test.description, test.location, g.Summary, g.Description, g.Location)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package imip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func newMeeting(t *testing.T) *ics.Event {
+	event, err := ics.NewEventBuilder().
+		UID("review@example.com").
+		Summary("Design review").
+		Description("Bring the slides\nand coffee").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), nil).
+		Duration(time.Hour).
+		Organizer("Boss", "boss@example.com").
+		Attendee(ics.NewAttendee().SetName("John Smith").SetEmail("j.smith@example.com")).
+		Attendee(ics.NewAttendee().SetEmail("sue@example.com")).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	return event
+}
+
+// smtpStandIn is a minimal SMTP server that records the received mail
+type smtpStandIn struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     chan []byte
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen ( %s )", err)
+	}
+	s := &smtpStandIn{listener: l, data: make(chan []byte, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(strings.TrimSpace(line)[10:], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.to = append(s.to, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data <- data.Bytes()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestNewMessageRequest(t *testing.T) {
+	msg, err := ics.NewRequest(newMeeting(t))
+	if err != nil {
+		t.Fatalf("Failed to create request ( %s )", err)
+	}
+	m, err := NewMessage(msg)
+	if err != nil {
+		t.Fatalf("Failed to create message ( %s )", err)
+	}
+	if m.From.Address != "boss@example.com" {
+		t.Errorf("Expected sender %s, found %s", "boss@example.com", m.From.Address)
+	}
+	if strings.Join(m.Recipients(), ",") != "j.smith@example.com,sue@example.com" {
+		t.Errorf("Unexpected recipients %v", m.Recipients())
+	}
+	if m.Subject != "Invitation: Design review" {
+		t.Errorf("Unexpected subject %s", m.Subject)
+	}
+	if !strings.Contains(m.Text, "When: Mon Jun 10, 2024 09:00 - 10:00 (UTC)") {
+		t.Errorf("Expected event time in text body, found:\n%s", m.Text)
+	}
+}
+
+func TestNewMessageReply(t *testing.T) {
+	msg, err := ics.NewReply(newMeeting(t), "sue@example.com", ics.PartStatTentative)
+	if err != nil {
+		t.Fatalf("Failed to create reply ( %s )", err)
+	}
+	m, err := NewMessage(msg)
+	if err != nil {
+		t.Fatalf("Failed to create message ( %s )", err)
+	}
+	if m.From.Address != "sue@example.com" || strings.Join(m.Recipients(), ",") != "boss@example.com" {
+		t.Errorf("Expected reply from sue to boss, found %s to %v", m.From.Address, m.Recipients())
+	}
+	if m.Subject != "Tentatively accepted: Design review" {
+		t.Errorf("Unexpected subject %s", m.Subject)
+	}
+	if _, err := NewMessage(ics.NewCalendar()); err == nil {
+		t.Errorf("Expected error for calendar without method")
+	}
+}
+
+func TestInviteThroughSMTP(t *testing.T) {
+	server := newSMTPStandIn(t)
+	defer server.listener.Close()
+
+	client := NewSMTPClient(server.listener.Addr().String(), nil)
+	client.Timeout = 5 * time.Second
+	if _, err := Invite(client, newMeeting(t)); err != nil {
+		t.Fatalf("Failed to send invitation ( %s )", err)
+	}
+
+	var data []byte
+	select {
+	case data = <-server.data:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The stand-in received no mail")
+	}
+	if server.from != "boss@example.com" || len(server.to) != 2 {
+		t.Errorf("Unexpected envelope %s -> %v", server.from, server.to)
+	}
+
+	email, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read mail ( %s )", err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(email.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, found %s", mediaType)
+	}
+	mixed := multipart.NewReader(email.Body, params["boundary"])
+
+	alternative, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read alternative part ( %s )", err)
+	}
+	_, altParams, _ := mime.ParseMediaType(alternative.Header.Get("Content-Type"))
+	parts := multipart.NewReader(alternative, altParams["boundary"])
+	types := []string{}
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/calendar") {
+			body, _ := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+			if !strings.Contains(string(body), "METHOD:REQUEST\r\n") {
+				t.Errorf("Expected METHOD:REQUEST in the calendar part, found:\n%s", body)
+			}
+		}
+	}
+	expected := "text/plain; charset=UTF-8|text/html; charset=UTF-8|text/calendar; method=REQUEST; charset=UTF-8"
+	if strings.Join(types, "|") != expected {
+		t.Errorf("Expected parts %s, found %s", expected, strings.Join(types, "|"))
+	}
+
+	attachment, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read attachment ( %s )", err)
+	}
+	if attachment.FileName() != "invite.ics" {
+		t.Errorf("Expected attachment %s, found %s", "invite.ics", attachment.FileName())
+	}
+}
+
+func TestWindowsTimeZoneInText(t *testing.T) {
+	meeting := newMeeting(t)
+	// a TZID of Outlook, 09:00 UTC is 11:00 in Paris in June
+	meeting.SetStartTZID("Romance Standard Time")
+	msg, _ := ics.NewRequest(meeting)
+	m, err := NewMessage(msg)
+	if err != nil {
+		t.Fatalf("Failed to create message ( %s )", err)
+	}
+	if !strings.Contains(m.Text, "When: Mon Jun 10, 2024 11:00 - 12:00") {
+		t.Errorf("Expected the event time in Paris in text body, found:\n%s", m.Text)
+	}
+}
+
+func TestSMTPWithoutAuth(t *testing.T) {
+	server := newSMTPStandIn(t)
+	defer server.listener.Close()
+
+	// the stand-in doesn't advertise AUTH
+	client := NewSMTPClient(server.listener.Addr().String(), smtp.PlainAuth("", "boss", "secret", "127.0.0.1"))
+	client.Timeout = 5 * time.Second
+	if _, err := Invite(client, newMeeting(t)); err == nil || !strings.Contains(err.Error(), "AUTH") {
+		t.Errorf("Expected an error of the missing AUTH, found %v", err)
+	}
+	select {
+	case <-server.data:
+		t.Errorf("Expected no mail sent without authentication")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if server.from != "" {
+		t.Errorf("Expected no MAIL FROM, found %s", server.from)
+	}
+}
+
+func TestMessageTexts(t *testing.T) {
+	event := newMeeting(t)
+	event.SetSummary(`Review of a\b`).SetLocation(`\\fileserver`).SetDescription(`Slides in C:\share\n`)
+	msg, _ := ics.NewRequest(event)
+	m, err := NewMessage(msg)
+	if err != nil {
+		t.Fatalf("Failed to create message ( %s )", err)
+	}
+	// the texts of the events that were never escaped used to lose their backslashes
+	if m.Subject != `Invitation: Review of a\b` {
+		t.Errorf("Unexpected subject %s", m.Subject)
+	}
+	for _, fragment := range []string{`\\fileserver`, `Slides in C:\share\n`} {
+		if !strings.Contains(m.Text, fragment) {
+			t.Errorf("Expected %s in the text body, found:\n%s", fragment, m.Text)
+		}
+		if !strings.Contains(m.HTML, fragment) {
+			t.Errorf("Expected %s in the html body, found:\n%s", fragment, m.HTML)
+		}
+	}
+}
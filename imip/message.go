@@ -0,0 +1,331 @@
+// Package imip builds and sends iMIP (RFC 6047) emails carrying iTIP
+// scheduling messages of github.com/PuloV/ics-golang.
+package imip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// name of the .ics attachment
+var AttachmentName = "invite.ics"
+
+// Message is an email carrying an iTIP message
+type Message struct {
+	From      *mail.Address
+	To        []*mail.Address
+	Subject   string
+	Text      string
+	HTML      string
+	Method    string
+	Calendar  string
+	MessageID string
+	Date      time.Time
+}
+
+// NewMessage creates the email for an iTIP message created by ics.NewRequest,
+// ics.NewReply and the other scheduling functions. Requests, cancels and adds
+// go from the organizer to the attendees, replies, refreshes and counters
+// from the attendee to the organizer.
+func NewMessage(msg *ics.Calendar) (*Message, error) {
+	method := strings.ToUpper(msg.GetMethod())
+	if method == "" {
+		return nil, errors.New("imip: calendar has no METHOD")
+	}
+	events := msg.GetEvents()
+	if len(events) == 0 {
+		return nil, errors.New("imip: calendar has no events")
+	}
+	event := &events[0]
+	organizer := event.GetOrganizer()
+	if organizer == nil || organizer.GetEmail() == "" {
+		return nil, errors.New("imip: event has no organizer")
+	}
+
+	m := &Message{
+		Subject:  subject(method, event),
+		Method:   method,
+		Calendar: msg.Serialize(),
+		Date:     time.Now(),
+	}
+	switch method {
+	case ics.MethodReply, ics.MethodRefresh, ics.MethodCounter:
+		if len(event.GetAttendees()) == 0 {
+			return nil, fmt.Errorf("imip: %s has no attendee", method)
+		}
+		m.From = address(event.GetAttendees()[0])
+		m.To = []*mail.Address{address(organizer)}
+	default:
+		m.From = address(organizer)
+		for _, a := range event.GetAttendees() {
+			if a.GetEmail() != "" && !strings.EqualFold(a.GetEmail(), organizer.GetEmail()) {
+				m.To = append(m.To, address(a))
+			}
+		}
+	}
+	if len(m.To) == 0 {
+		return nil, errors.New("imip: message has no recipients")
+	}
+	m.Text = textBody(method, event)
+	m.HTML = htmlBody(method, event)
+	id, err := newMessageID(m.From.Address)
+	if err != nil {
+		return nil, err
+	}
+	m.MessageID = id
+	return m, nil
+}
+
+// Recipients returns the envelope recipients of the message
+func (m *Message) Recipients() []string {
+	to := make([]string, len(m.To))
+	for i, a := range m.To {
+		to[i] = a.Address
+	}
+	return to
+}
+
+// Bytes returns the message in the RFC 5322 format
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the message as multipart/mixed with a multipart/alternative
+// text, html and text/calendar body and the .ics attachment
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	to := make([]string, len(m.To))
+	for i, a := range m.To {
+		to[i] = a.String()
+	}
+	headers := []string{
+		"From: " + m.From.String(),
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + m.Date.Format(time.RFC1123Z),
+		"Message-ID: " + m.MessageID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + mixed.Boundary(),
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	// the alternative body parts
+	altHeader := textproto.MIMEHeader{}
+	var altBuf bytes.Buffer
+	alt := multipart.NewWriter(&altBuf)
+	altHeader.Set("Content-Type", "multipart/alternative; boundary="+alt.Boundary())
+	if err := writeQuotedPrintable(alt, "text/plain; charset=UTF-8", m.Text); err != nil {
+		return 0, err
+	}
+	if err := writeQuotedPrintable(alt, "text/html; charset=UTF-8", m.HTML); err != nil {
+		return 0, err
+	}
+	calHeader := textproto.MIMEHeader{}
+	calHeader.Set("Content-Type", fmt.Sprintf("text/calendar; method=%s; charset=UTF-8", m.Method))
+	if err := writeBase64(alt, calHeader, m.Calendar); err != nil {
+		return 0, err
+	}
+	if err := alt.Close(); err != nil {
+		return 0, err
+	}
+	altPart, err := mixed.CreatePart(altHeader)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := altPart.Write(altBuf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	// the attachment for clients that ignore text/calendar parts
+	attHeader := textproto.MIMEHeader{}
+	attHeader.Set("Content-Type", fmt.Sprintf("application/ics; name=%q", AttachmentName))
+	attHeader.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", AttachmentName))
+	if err := writeBase64(mixed, attHeader, m.Calendar); err != nil {
+		return 0, err
+	}
+	if err := mixed.Close(); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func writeQuotedPrintable(mw *multipart.Writer, contentType, body string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeBase64(mw *multipart.Writer, header textproto.MIMEHeader, body string) error {
+	header.Set("Content-Transfer-Encoding", "base64")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	// lines of at most 76 characters (RFC 2045)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func address(a *ics.Attendee) *mail.Address {
+	return &mail.Address{Name: a.GetName(), Address: a.GetEmail()}
+}
+
+func subject(method string, e *ics.Event) string {
+	summary := e.GetPlainSummary()
+	switch method {
+	case ics.MethodRequest:
+		if e.GetSequence() > 0 {
+			return "Updated invitation: " + summary
+		}
+		return "Invitation: " + summary
+	case ics.MethodCancel:
+		return "Cancelled: " + summary
+	case ics.MethodAdd:
+		return "New occurrence: " + summary
+	case ics.MethodReply:
+		return replyStatus(e) + ": " + summary
+	case ics.MethodRefresh:
+		return "Refresh request: " + summary
+	case ics.MethodCounter:
+		return "New time proposed: " + summary
+	case ics.MethodDeclineCounter:
+		return "Proposal declined: " + summary
+	}
+	return summary
+}
+
+func replyStatus(e *ics.Event) string {
+	if len(e.GetAttendees()) == 0 {
+		return "Reply"
+	}
+	switch e.GetAttendees()[0].GetStatus() {
+	case ics.PartStatAccepted:
+		return "Accepted"
+	case ics.PartStatDeclined:
+		return "Declined"
+	case ics.PartStatTentative:
+		return "Tentatively accepted"
+	case ics.PartStatDelegated:
+		return "Delegated"
+	}
+	return "Reply"
+}
+
+// the event details shown in the text and html bodies
+func details(method string, e *ics.Event) [][2]string {
+	rows := [][2]string{{"What", e.GetPlainSummary()}, {"When", when(e)}}
+	if e.GetLocation() != "" {
+		rows = append(rows, [2]string{"Where", e.GetPlainLocation()})
+	}
+	if o := e.GetOrganizer(); o != nil {
+		rows = append(rows, [2]string{"Organizer", address(o).String()})
+	}
+	if method != ics.MethodReply && method != ics.MethodRefresh {
+		names := []string{}
+		for _, a := range e.GetAttendees() {
+			names = append(names, address(a).String())
+		}
+		if len(names) > 0 {
+			rows = append(rows, [2]string{"Attendees", strings.Join(names, ", ")})
+		}
+	}
+	return rows
+}
+
+func when(e *ics.Event) string {
+	if e.IsWholeDay() {
+		return e.GetStart().Format("Mon Jan 2, 2006") + " (all day)"
+	}
+	loc := time.UTC
+	if tzid := e.GetStartTZID(); tzid != "" {
+		if l, err := ics.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	start := e.GetStart().In(loc)
+	end := e.GetEnd().In(loc)
+	if start.YearDay() == end.YearDay() && start.Year() == end.Year() {
+		return fmt.Sprintf("%s - %s (%s)", start.Format("Mon Jan 2, 2006 15:04"), end.Format("15:04"), loc)
+	}
+	return fmt.Sprintf("%s - %s (%s)", start.Format("Mon Jan 2, 2006 15:04"), end.Format("Mon Jan 2, 2006 15:04"), loc)
+}
+
+func textBody(method string, e *ics.Event) string {
+	var b strings.Builder
+	b.WriteString(subject(method, e) + "\r\n\r\n")
+	for _, row := range details(method, e) {
+		fmt.Fprintf(&b, "%s: %s\r\n", row[0], row[1])
+	}
+	if e.GetDescription() != "" {
+		b.WriteString("\r\n" + e.GetPlainDescription() + "\r\n")
+	}
+	return b.String()
+}
+
+func htmlBody(method string, e *ics.Event) string {
+	var b strings.Builder
+	b.WriteString("<html><body>\r\n")
+	fmt.Fprintf(&b, "<h2>%s</h2>\r\n<table>\r\n", html.EscapeString(subject(method, e)))
+	for _, row := range details(method, e) {
+		fmt.Fprintf(&b, "<tr><th align=\"left\">%s</th><td>%s</td></tr>\r\n", html.EscapeString(row[0]), html.EscapeString(row[1]))
+	}
+	b.WriteString("</table>\r\n")
+	if e.GetDescription() != "" {
+		desc := html.EscapeString(e.GetPlainDescription())
+		fmt.Fprintf(&b, "<p>%s</p>\r\n", strings.Replace(desc, "\n", "<br>\r\n", -1))
+	}
+	b.WriteString("</body></html>\r\n")
+	return b.String()
+}
+
+func newMessageID(from string) (string, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	} else if host, err := os.Hostname(); err == nil && host != "" {
+		domain = host
+	}
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("imip: failed to generate the Message-ID ( %s )", err)
+	}
+	return fmt.Sprintf("<%x.%d@%s>", buf, time.Now().UnixNano(), domain), nil
+}
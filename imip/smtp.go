@@ -0,0 +1,129 @@
+package imip
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// Sender delivers messages
+type Sender interface {
+	Send(m *Message) error
+}
+
+// SMTPClient sends messages through an SMTP server
+type SMTPClient struct {
+	// host:port of the server
+	Addr string
+	// optional authentication, sending fails when the server doesn't support AUTH
+	Auth smtp.Auth
+	// name sent with HELO/EHLO, defaults to localhost
+	LocalName string
+	// TLS configuration for STARTTLS, defaults to the server host name
+	TLSConfig *tls.Config
+	// do not upgrade the connection with STARTTLS
+	DisableSTARTTLS bool
+	// timeout for connecting to the server
+	Timeout time.Duration
+}
+
+// NewSMTPClient creates a client for the server at addr
+func NewSMTPClient(addr string, auth smtp.Auth) *SMTPClient {
+	return &SMTPClient{Addr: addr, Auth: auth, Timeout: 30 * time.Second}
+}
+
+// Send delivers the message to all its recipients
+func (c *SMTPClient) Send(m *Message) error {
+	if m.From == nil || len(m.To) == 0 {
+		return errors.New("imip: message without sender or recipients")
+	}
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return err
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", c.Addr, timeout)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if c.LocalName != "" {
+		if err := client.Hello(c.LocalName); err != nil {
+			return err
+		}
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok && !c.DisableSTARTTLS {
+		config := c.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: host}
+		}
+		if err := client.StartTLS(config); err != nil {
+			return err
+		}
+	}
+	if c.Auth != nil {
+		// the credentials are never skipped silently
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("imip: the server does not support AUTH")
+		}
+		if err := client.Auth(c.Auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.From.Address); err != nil {
+		return err
+	}
+	for _, to := range m.Recipients() {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Send builds the email of an iTIP message and delivers it
+func Send(s Sender, msg *ics.Calendar) (*Message, error) {
+	m, err := NewMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	return m, s.Send(m)
+}
+
+// Invite sends a METHOD:REQUEST for the event to the email of each attendee
+func Invite(s Sender, e *ics.Event) (*Message, error) {
+	msg, err := ics.NewRequest(e)
+	if err != nil {
+		return nil, err
+	}
+	return Send(s, msg)
+}
@@ -0,0 +1,281 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// iTIP (RFC 5546) methods of a scheduling message
+const (
+	MethodPublish        = "PUBLISH"
+	MethodRequest        = "REQUEST"
+	MethodReply          = "REPLY"
+	MethodAdd            = "ADD"
+	MethodCancel         = "CANCEL"
+	MethodRefresh        = "REFRESH"
+	MethodCounter        = "COUNTER"
+	MethodDeclineCounter = "DECLINECOUNTER"
+)
+
+// participation statuses of the PARTSTAT parameter
+const (
+	PartStatNeedsAction = "NEEDS-ACTION"
+	PartStatAccepted    = "ACCEPTED"
+	PartStatDeclined    = "DECLINED"
+	PartStatTentative   = "TENTATIVE"
+	PartStatDelegated   = "DELEGATED"
+)
+
+// properties a message may carry besides UID and DTSTAMP, per method
+var itipProperties = map[string][]string{
+	MethodReply:   {"SEQUENCE", "RECURRENCE-ID", "ORGANIZER", "ATTENDEE", "DTSTART", "DTEND", "SUMMARY"},
+	MethodCancel:  {"SEQUENCE", "RECURRENCE-ID", "ORGANIZER", "ATTENDEE", "DTSTART", "DTEND", "SUMMARY", "LOCATION", "STATUS"},
+	MethodRefresh: {"RECURRENCE-ID", "ORGANIZER", "ATTENDEE"},
+}
+
+// NewRequest creates a METHOD:REQUEST message inviting the attendees of the event.
+// Attendees without a status are asked to reply with RSVP=TRUE.
+// Set a recurrence id on the event to send a single changed occurrence.
+func NewRequest(e *Event) (*Calendar, error) {
+	msg := cloneEvent(e)
+	if err := checkOrganized(msg); err != nil {
+		return nil, err
+	}
+	for _, a := range msg.GetAttendees() {
+		if a.GetStatus() == "" || a.GetStatus() == PartStatNeedsAction {
+			a.SetStatus(PartStatNeedsAction)
+			a.SetRSVP(true)
+		}
+	}
+	return newITIPMessage(MethodRequest, msg), nil
+}
+
+// NewUpdateRequest creates a METHOD:REQUEST message for an event that was
+// already sent. When the time or recurrence changed the SEQUENCE is bumped
+// and the attendees have to reply again.
+func NewUpdateRequest(previous, updated *Event) (*Calendar, error) {
+	if previous.GetImportedID() != updated.GetImportedID() {
+		return nil, fmt.Errorf("itip: update of %s has a different UID %s", previous.GetImportedID(), updated.GetImportedID())
+	}
+	msg := cloneEvent(updated)
+	if err := checkOrganized(msg); err != nil {
+		return nil, err
+	}
+	sequence := previous.GetSequence()
+	if msg.GetSequence() > sequence {
+		sequence = msg.GetSequence()
+	}
+	if IsSignificantChange(previous, updated) {
+		if msg.GetSequence() <= previous.GetSequence() {
+			sequence = previous.GetSequence() + 1
+		}
+		for _, a := range msg.GetAttendees() {
+			a.SetStatus(PartStatNeedsAction)
+			a.SetRSVP(true)
+		}
+	}
+	msg.SetSequence(sequence)
+	return newITIPMessage(MethodRequest, msg), nil
+}
+
+// NewReply creates a METHOD:REPLY message of the attendee with the given
+// email answering the invitation with partstat.
+func NewReply(e *Event, email, partstat string) (*Calendar, error) {
+	switch partstat {
+	case PartStatAccepted, PartStatDeclined, PartStatTentative, PartStatDelegated:
+	default:
+		return nil, fmt.Errorf("itip: invalid reply PARTSTAT %s", partstat)
+	}
+	msg := cloneEvent(e)
+	attendee := findAttendee(msg, email)
+	if attendee == nil {
+		return nil, fmt.Errorf("itip: %s is not invited to %s", email, msg.GetImportedID())
+	}
+	if msg.GetOrganizer() == nil {
+		return nil, errors.New("itip: event has no organizer")
+	}
+	attendee.SetStatus(partstat)
+	attendee.SetRSVP(false)
+	msg.attendees = []*Attendee{attendee}
+	return newITIPMessage(MethodReply, msg), nil
+}
+
+// NewCancel creates a METHOD:CANCEL message. Without attendees the whole
+// event, or the occurrence of its recurrence id, is cancelled for everyone,
+// otherwise only the given attendees are removed.
+func NewCancel(e *Event, attendees ...*Attendee) (*Calendar, error) {
+	msg := cloneEvent(e)
+	if err := checkOrganized(msg); err != nil {
+		return nil, err
+	}
+	if len(attendees) > 0 {
+		removed := []*Attendee{}
+		for _, a := range attendees {
+			found := findAttendee(msg, a.GetEmail())
+			if found == nil {
+				return nil, fmt.Errorf("itip: %s is not invited to %s", a.GetEmail(), msg.GetImportedID())
+			}
+			removed = append(removed, found)
+		}
+		msg.attendees = removed
+	} else {
+		msg.SetStatus("CANCELLED")
+	}
+	msg.SetSequence(msg.GetSequence() + 1)
+	return newITIPMessage(MethodCancel, msg), nil
+}
+
+// NewAdd creates a METHOD:ADD message adding the instance to a repeating
+// event that was already sent
+func NewAdd(e *Event) (*Calendar, error) {
+	msg := cloneEvent(e)
+	if err := checkOrganized(msg); err != nil {
+		return nil, err
+	}
+	msg.SetRRule("")
+	msg.SetRecurrenceID(time.Time{})
+	msg.SetSequence(msg.GetSequence() + 1)
+	return newITIPMessage(MethodAdd, msg), nil
+}
+
+// NewRefresh creates a METHOD:REFRESH message of the attendee asking the
+// organizer for the latest version of the event
+func NewRefresh(e *Event, email string) (*Calendar, error) {
+	msg := cloneEvent(e)
+	if msg.GetOrganizer() == nil {
+		return nil, errors.New("itip: event has no organizer")
+	}
+	attendee := findAttendee(msg, email)
+	if attendee == nil {
+		attendee = NewAttendee().SetEmail(email)
+	}
+	msg.attendees = []*Attendee{attendee}
+	return newITIPMessage(MethodRefresh, msg), nil
+}
+
+// NewCounter creates a METHOD:COUNTER message in which the attendee with the
+// given email proposes the changes of proposed to the organizer
+func NewCounter(original, proposed *Event, email string) (*Calendar, error) {
+	if original.GetImportedID() != proposed.GetImportedID() {
+		return nil, fmt.Errorf("itip: counter of %s has a different UID %s", original.GetImportedID(), proposed.GetImportedID())
+	}
+	if findAttendee(original, email) == nil {
+		return nil, fmt.Errorf("itip: %s is not invited to %s", email, original.GetImportedID())
+	}
+	msg := cloneEvent(proposed)
+	if err := checkOrganized(msg); err != nil {
+		return nil, err
+	}
+	// the counter refers to the version the attendee has seen
+	msg.SetSequence(original.GetSequence())
+	return newITIPMessage(MethodCounter, msg), nil
+}
+
+// IsSignificantChange reports whether an update changes the time or the
+// recurrence of an event, which requires a new SEQUENCE (RFC 5546 2.1.4)
+func IsSignificantChange(previous, updated *Event) bool {
+	return !previous.GetStart().Equal(updated.GetStart()) ||
+		!previous.GetEnd().Equal(updated.GetEnd()) ||
+		previous.IsWholeDay() != updated.IsWholeDay() ||
+		previous.GetRRule() != updated.GetRRule() ||
+		!previous.GetRecurrenceID().Equal(updated.GetRecurrenceID()) ||
+		(updated.GetStatus() == "CANCELLED" && previous.GetStatus() != "CANCELLED")
+}
+
+// Instance returns a copy of a repeating event for the single occurrence
+// starting at recurrenceID, ready to be changed and sent
+func (e *Event) Instance(recurrenceID time.Time) *Event {
+	instance := cloneEvent(e)
+	duration := e.GetEnd().Sub(e.GetStart())
+	instance.SetRRule("")
+	instance.SetRecurrenceID(recurrenceID)
+	instance.SetStart(recurrenceID)
+	instance.SetEnd(recurrenceID.Add(duration))
+	instance.SetID(instance.GenerateEventId())
+	return instance
+}
+
+// wraps the event into a calendar with the given method
+func newITIPMessage(method string, e *Event) *Calendar {
+	e.SetDTStamp(time.Now().UTC().Truncate(time.Second))
+	if e.GetID() == "" {
+		e.SetID(e.GenerateEventId())
+	}
+	cal := NewCalendar()
+	cal.SetVersion(2.0)
+	cal.SetTimezone(*time.UTC)
+	cal.SetMethod(method)
+	cal.SetEvent(*e)
+	return cal
+}
+
+// builds the component of a message restricted to the properties its method allows
+func itipComponent(method string, e *Event) *component {
+	c := e.component()
+	allowed, ok := itipProperties[method]
+	if !ok {
+		return c
+	}
+	props := []*property{}
+	for _, prop := range c.props {
+		if prop.name == "UID" || prop.name == "DTSTAMP" || inStrings(allowed, prop.name) {
+			props = append(props, prop)
+		}
+	}
+	c.props = props
+	c.comps = nil
+	return c
+}
+
+// the organizer and attendees required by REQUEST, CANCEL, ADD and COUNTER
+func checkOrganized(e *Event) error {
+	if e.GetImportedID() == "" {
+		return errors.New("itip: event has no UID")
+	}
+	if e.GetOrganizer() == nil || e.GetOrganizer().GetEmail() == "" {
+		return errors.New("itip: event has no organizer")
+	}
+	if len(e.GetAttendees()) == 0 {
+		return errors.New("itip: event has no attendees")
+	}
+	if e.GetStart().IsZero() {
+		return errors.New("itip: event has no start")
+	}
+	return nil
+}
+
+// copies the event together with its attendees
+func cloneEvent(e *Event) *Event {
+	newE := e.Clone()
+	newE.attendees = make([]*Attendee, len(e.attendees))
+	for i, a := range e.attendees {
+		newE.attendees[i] = a.Clone()
+	}
+	if e.organizer != nil {
+		newE.organizer = e.organizer.Clone()
+	}
+	newE.alarms = append([]*Alarm{}, e.alarms...)
+	newE.inCalendar = nil
+	return newE
+}
+
+// finds an attendee of the event by email, ignoring the case
+func findAttendee(e *Event, email string) *Attendee {
+	for _, a := range e.GetAttendees() {
+		if strings.EqualFold(a.GetEmail(), email) {
+			return a
+		}
+	}
+	return nil
+}
+
+func inStrings(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package ics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// kinds of the changes reported by ApplyITIP
+const (
+	ITIPAdded            = "ADDED"
+	ITIPUpdated          = "UPDATED"
+	ITIPCancelled        = "CANCELLED"
+	ITIPPartStat         = "PARTSTAT"
+	ITIPRefreshRequested = "REFRESH"
+	ITIPCounterProposed  = "COUNTER"
+	ITIPCounterDeclined  = "DECLINECOUNTER"
+)
+
+// ITIPChange describes a single change ApplyITIP made, or a request it received
+type ITIPChange struct {
+	Kind         string
+	UID          string
+	RecurrenceID time.Time
+	Sequence     int
+	// set for PARTSTAT, REFRESH and COUNTER changes
+	Attendee  string
+	OldStatus string
+	NewStatus string
+	// the proposed event of a COUNTER
+	Proposed *Event
+}
+
+// ITIPRejection describes an event of a message that was not applied
+type ITIPRejection struct {
+	UID          string
+	RecurrenceID time.Time
+	Reason       string
+	// the message is older than the local copy of the event
+	Stale bool
+}
+
+func (r ITIPRejection) Error() string {
+	if r.RecurrenceID.IsZero() {
+		return fmt.Sprintf("itip: event %s rejected: %s", r.UID, r.Reason)
+	}
+	return fmt.Sprintf("itip: occurrence %s of event %s rejected: %s", r.RecurrenceID.Format(IcsFormat), r.UID, r.Reason)
+}
+
+// ITIPReport is the result of applying a scheduling message to a calendar
+type ITIPReport struct {
+	Method   string
+	Changes  []ITIPChange
+	Rejected []ITIPRejection
+}
+
+// ApplyITIP applies an incoming iTIP message to the calendar. Replies update
+// the PARTSTAT of the attendees, requests add events or replace them when
+// their SEQUENCE (or DTSTAMP) is newer, cancels mark events and single
+// occurrences as CANCELLED. Messages older than the local copy are rejected
+// and listed in the report. An error is returned for messages without a
+// supported method.
+func (c *Calendar) ApplyITIP(msg *Calendar) (*ITIPReport, error) {
+	method := strings.ToUpper(msg.GetMethod())
+	switch method {
+	case MethodPublish, MethodRequest, MethodReply, MethodAdd, MethodCancel, MethodRefresh, MethodCounter, MethodDeclineCounter:
+	case "":
+		return nil, fmt.Errorf("itip: message has no METHOD")
+	default:
+		return nil, fmt.Errorf("itip: unsupported METHOD %s", method)
+	}
+	report := &ITIPReport{Method: method}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, group := range groupMessageEvents(msg.GetEvents()) {
+		incoming := group[0]
+		if incoming.GetImportedID() == "" {
+			report.reject(incoming, "no UID", false)
+			continue
+		}
+		switch method {
+		case MethodPublish, MethodRequest:
+			c.applyRequest(report, group)
+		case MethodReply:
+			c.applyReply(report, incoming)
+		case MethodAdd:
+			c.applyAdd(report, group)
+		case MethodCancel:
+			c.applyCancel(report, incoming)
+		case MethodRefresh, MethodCounter, MethodDeclineCounter:
+			c.applyNotice(report, method, incoming)
+		}
+	}
+
+	c.reindex()
+	return report, nil
+}
+
+// the organizer sends a new or updated event
+func (c *Calendar) applyRequest(report *ITIPReport, group []*Event) {
+	incoming := group[0]
+	local := c.findTarget(incoming.GetImportedID(), incoming.GetRecurrenceID())
+	if local >= 0 && !c.isNewerVersion(incoming, local) {
+		report.reject(incoming, fmt.Sprintf("SEQUENCE %d is not newer than %d", incoming.GetSequence(), c.sequenceAt(local)), true)
+		return
+	}
+
+	kind := ITIPAdded
+	if incoming.GetRecurrenceID().IsZero() {
+		// a new version of the whole series replaces all its occurrences
+		if c.removeEvents(incoming.GetImportedID()) > 0 {
+			kind = ITIPUpdated
+		}
+		for _, e := range group {
+			c.appendEvent(e)
+		}
+	} else if local >= 0 {
+		kind = ITIPUpdated
+		c.events[local] = *c.adopt(incoming)
+	} else {
+		c.appendEvent(incoming)
+	}
+	report.change(kind, incoming)
+}
+
+// an attendee answers an invitation
+func (c *Calendar) applyReply(report *ITIPReport, incoming *Event) {
+	uid := incoming.GetImportedID()
+	rid := incoming.GetRecurrenceID()
+	target := c.findTarget(uid, rid)
+	if target < 0 {
+		report.reject(incoming, "unknown event", false)
+		return
+	}
+	if incoming.GetSequence() < c.sequenceAt(target) {
+		report.reject(incoming, fmt.Sprintf("reply to SEQUENCE %d, current is %d", incoming.GetSequence(), c.sequenceAt(target)), true)
+		return
+	}
+
+	// a reply for the series updates all its occurrences, a reply for
+	// an occurrence only that one
+	targets := []int{target}
+	if rid.IsZero() {
+		targets = c.seriesEvents(uid)
+	} else {
+		e := &c.events[target]
+		cloned := cloneEvent(e)
+		e.attendees = cloned.attendees
+	}
+
+	for _, replied := range incoming.GetAttendees() {
+		oldStatus := ""
+		for _, i := range targets {
+			e := &c.events[i]
+			local := findAttendee(e, replied.GetEmail())
+			if local == nil {
+				// delegates and uninvited attendees are added
+				local = replied.Clone()
+				local.SetStatus("")
+				e.attendees = append(e.attendees, local)
+			}
+			if i == target {
+				oldStatus = local.GetStatus()
+			}
+			local.SetStatus(replied.GetStatus())
+			local.SetRSVP(false)
+		}
+		change := newChange(ITIPPartStat, incoming)
+		change.Attendee = replied.GetEmail()
+		change.OldStatus = oldStatus
+		change.NewStatus = replied.GetStatus()
+		report.Changes = append(report.Changes, change)
+	}
+}
+
+// the organizer adds occurrences to a repeating event
+func (c *Calendar) applyAdd(report *ITIPReport, group []*Event) {
+	incoming := group[0]
+	series := c.seriesEvents(incoming.GetImportedID())
+	if len(series) == 0 {
+		report.reject(incoming, "unknown event", false)
+		return
+	}
+	if incoming.GetSequence() < c.events[series[0]].GetSequence() {
+		report.reject(incoming, fmt.Sprintf("SEQUENCE %d is older than %d", incoming.GetSequence(), c.events[series[0]].GetSequence()), true)
+		return
+	}
+	for _, i := range series {
+		c.events[i].SetSequence(incoming.GetSequence())
+	}
+	for _, e := range group {
+		c.appendEvent(e)
+	}
+	report.change(ITIPAdded, incoming)
+}
+
+// the organizer cancels the event or a single occurrence
+func (c *Calendar) applyCancel(report *ITIPReport, incoming *Event) {
+	uid := incoming.GetImportedID()
+	rid := incoming.GetRecurrenceID()
+	series := c.seriesEvents(uid)
+	target := c.findTarget(uid, rid)
+	reference := target
+	if reference < 0 && len(series) > 0 {
+		reference = series[0]
+	}
+	if reference < 0 {
+		report.reject(incoming, "unknown event", false)
+		return
+	}
+	if incoming.GetSequence() < c.sequenceAt(reference) {
+		report.reject(incoming, fmt.Sprintf("SEQUENCE %d is older than %d", incoming.GetSequence(), c.sequenceAt(reference)), true)
+		return
+	}
+
+	targets := []int{}
+	if rid.IsZero() {
+		// the whole series including the overrides of single occurrences
+		for i := range c.events {
+			if c.events[i].GetImportedID() == uid {
+				targets = append(targets, i)
+			}
+		}
+	} else if target >= 0 {
+		targets = append(targets, target)
+	} else {
+		// the occurrence was not expanded, add it as a cancelled override
+		c.events = append(c.events, *c.events[series[0]].Instance(rid))
+		targets = append(targets, len(c.events)-1)
+	}
+	for _, i := range targets {
+		c.events[i].SetStatus("CANCELLED")
+		c.events[i].SetSequence(incoming.GetSequence())
+		c.events[i].SetCalendar(c)
+	}
+	report.change(ITIPCancelled, incoming)
+}
+
+// requests that don't change the calendar but need an answer of the organizer
+func (c *Calendar) applyNotice(report *ITIPReport, method string, incoming *Event) {
+	if c.findTarget(incoming.GetImportedID(), incoming.GetRecurrenceID()) < 0 {
+		report.reject(incoming, "unknown event", false)
+		return
+	}
+	kind := map[string]string{
+		MethodRefresh:        ITIPRefreshRequested,
+		MethodCounter:        ITIPCounterProposed,
+		MethodDeclineCounter: ITIPCounterDeclined,
+	}[method]
+	change := newChange(kind, incoming)
+	if len(incoming.GetAttendees()) > 0 {
+		change.Attendee = incoming.GetAttendees()[0].GetEmail()
+	}
+	if method == MethodCounter {
+		change.Proposed = cloneEvent(incoming)
+	}
+	report.Changes = append(report.Changes, change)
+}
+
+// returns the index of the event a message about uid and rid refers to
+func (c *Calendar) findTarget(uid string, rid time.Time) int {
+	if rid.IsZero() {
+		if series := c.seriesEvents(uid); len(series) > 0 {
+			return series[0]
+		}
+		return -1
+	}
+	// an override of the occurrence wins over the expanded occurrence
+	expanded := -1
+	for i := range c.events {
+		e := &c.events[i]
+		if e.GetImportedID() != uid {
+			continue
+		}
+		if e.GetRecurrenceID().Equal(rid) {
+			return i
+		}
+		if expanded < 0 && e.GetRecurrenceID().IsZero() && e.GetStart().Equal(rid) {
+			expanded = i
+		}
+	}
+	return expanded
+}
+
+// returns the SEQUENCE of the event at i, the expanded occurrences carry the
+// SEQUENCE of their master since the parser numbers them in it
+func (c *Calendar) sequenceAt(i int) int {
+	e := &c.events[i]
+	if e.GetRecurrenceID().IsZero() {
+		if series := c.seriesEvents(e.GetImportedID()); len(series) > 0 {
+			return c.events[series[0]].GetSequence()
+		}
+	}
+	return e.GetSequence()
+}
+
+// returns the indexes of the events of a series, the master first
+func (c *Calendar) seriesEvents(uid string) []int {
+	series := []int{}
+	for i := range c.events {
+		if c.events[i].GetImportedID() == uid && c.events[i].GetRecurrenceID().IsZero() {
+			series = append(series, i)
+		}
+	}
+	return series
+}
+
+// removes all events with the uid and returns how many were removed
+func (c *Calendar) removeEvents(uid string) int {
+	kept := c.events[:0]
+	for _, e := range c.events {
+		if e.GetImportedID() != uid {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(c.events) - len(kept)
+	c.events = kept
+	return removed
+}
+
+// adds a copy of an event of a message to the events array
+func (c *Calendar) appendEvent(e *Event) {
+	c.events = append(c.events, *c.adopt(e))
+}
+
+// copies an event of a message so it belongs to the calendar
+func (c *Calendar) adopt(e *Event) *Event {
+	newE := cloneEvent(e)
+	newE.SetCalendar(c)
+	if newE.GetID() == "" {
+		newE.SetID(newE.GenerateEventId())
+	}
+	return newE
+}
+
+// groups the events of a message by UID and RECURRENCE-ID, the parser
+// expands repeating events so one message event can become many
+func groupMessageEvents(events []Event) [][]*Event {
+	groups := [][]*Event{}
+	index := map[string]int{}
+	for i := range events {
+		e := &events[i]
+		key := e.GetImportedID() + "|" + e.GetRecurrenceID().UTC().Format(IcsFormat)
+		g, ok := index[key]
+		if !ok || e.GetImportedID() == "" {
+			index[key] = len(groups)
+			groups = append(groups, []*Event{e})
+			continue
+		}
+		groups[g] = append(groups[g], e)
+	}
+	return groups
+}
+
+// reports whether the incoming event is newer than the local one at i (RFC 5546 2.1.5)
+func (c *Calendar) isNewerVersion(incoming *Event, i int) bool {
+	if sequence := c.sequenceAt(i); incoming.GetSequence() != sequence {
+		return incoming.GetSequence() > sequence
+	}
+	return incoming.GetDTStamp().After(c.events[i].GetDTStamp())
+}
+
+func newChange(kind string, e *Event) ITIPChange {
+	return ITIPChange{
+		Kind:         kind,
+		UID:          e.GetImportedID(),
+		RecurrenceID: e.GetRecurrenceID(),
+		Sequence:     e.GetSequence(),
+	}
+}
+
+func (r *ITIPReport) change(kind string, e *Event) {
+	r.Changes = append(r.Changes, newChange(kind, e))
+}
+
+func (r *ITIPReport) reject(e *Event, reason string, stale bool) {
+	r.Rejected = append(r.Rejected, ITIPRejection{
+		UID:          e.GetImportedID(),
+		RecurrenceID: e.GetRecurrenceID(),
+		Reason:       reason,
+		Stale:        stale,
+	})
+}
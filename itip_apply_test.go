@@ -0,0 +1,168 @@
+package ics
+
+import (
+	"testing"
+	"time"
+)
+
+// parses a serialized message like an incoming mail would be
+func parseMessage(t *testing.T, msg *Calendar) *Calendar {
+	parser := New()
+	parser.Load(msg.Serialize())
+	calendars, err := parser.GetCalendars()
+	if err != nil || len(calendars) != 1 {
+		t.Fatalf("Failed to parse message ( %v )", err)
+	}
+	return calendars[0]
+}
+
+func TestApplyITIPReply(t *testing.T) {
+	event := newMeeting(t)
+	organizer := NewCalendar()
+	organizer.SetEvent(*event)
+
+	reply, err := NewReply(event, "sue@example.com", PartStatDeclined)
+	if err != nil {
+		t.Fatalf("Failed to create reply ( %s )", err)
+	}
+	report, err := organizer.ApplyITIP(parseMessage(t, reply))
+	if err != nil {
+		t.Fatalf("Failed to apply reply ( %s )", err)
+	}
+	if len(report.Changes) != 1 || len(report.Rejected) != 0 {
+		t.Fatalf("Expected 1 change, found %#v", report)
+	}
+	change := report.Changes[0]
+	if change.Kind != ITIPPartStat || change.Attendee != "sue@example.com" || change.OldStatus != PartStatNeedsAction || change.NewStatus != PartStatDeclined {
+		t.Errorf("Unexpected change %#v", change)
+	}
+
+	local, err := organizer.GetEventByImportedID("standup@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get event ( %s )", err)
+	}
+	if status := findAttendee(local, "sue@example.com").GetStatus(); status != PartStatDeclined {
+		t.Errorf("Expected attendee status %s, found %s", PartStatDeclined, status)
+	}
+}
+
+func TestApplyITIPRequestSequence(t *testing.T) {
+	event := newMeeting(t)
+	mirror := NewCalendar()
+
+	request, _ := NewRequest(event)
+	report, err := mirror.ApplyITIP(parseMessage(t, request))
+	if err != nil {
+		t.Fatalf("Failed to apply request ( %s )", err)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Kind != ITIPAdded {
+		t.Fatalf("Expected event to be added, found %#v", report)
+	}
+	if len(mirror.GetEvents()) != 5 {
+		t.Errorf("Expected 5 occurrences in the mirror, found %d", len(mirror.GetEvents()))
+	}
+
+	moved := cloneEvent(event)
+	moved.SetStart(event.GetStart().Add(time.Hour)).SetEnd(event.GetEnd().Add(time.Hour))
+	update, _ := NewUpdateRequest(event, moved)
+	report, _ = mirror.ApplyITIP(parseMessage(t, update))
+	if len(report.Changes) != 1 || report.Changes[0].Kind != ITIPUpdated || report.Changes[0].Sequence != 1 {
+		t.Fatalf("Expected event to be updated to sequence 1, found %#v", report)
+	}
+	local, _ := mirror.GetEventByImportedID("standup@example.com")
+	if local.GetStart().Hour() != 10 {
+		t.Errorf("Expected updated start hour 10, found %d", local.GetStart().Hour())
+	}
+
+	// the first request arrives again
+	report, _ = mirror.ApplyITIP(parseMessage(t, request))
+	if len(report.Changes) != 0 || len(report.Rejected) != 1 || !report.Rejected[0].Stale {
+		t.Errorf("Expected stale request to be rejected, found %#v", report)
+	}
+}
+
+func TestApplyITIPCancelOccurrence(t *testing.T) {
+	event := newMeeting(t)
+	mirror := NewCalendar()
+	request, _ := NewRequest(event)
+	mirror.ApplyITIP(parseMessage(t, request))
+
+	occurrence := event.GetStart().AddDate(0, 0, 1)
+	cancel, _ := NewCancel(event.Instance(occurrence))
+	report, err := mirror.ApplyITIP(parseMessage(t, cancel))
+	if err != nil {
+		t.Fatalf("Failed to apply cancel ( %s )", err)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Kind != ITIPCancelled {
+		t.Fatalf("Expected occurrence to be cancelled, found %#v", report)
+	}
+
+	cancelled := 0
+	for _, e := range mirror.GetEvents() {
+		if e.GetStatus() == "CANCELLED" {
+			cancelled++
+			if !e.GetStart().Equal(occurrence) {
+				t.Errorf("Expected cancelled occurrence at %s, found %s", occurrence, e.GetStart())
+			}
+		}
+	}
+	if cancelled != 1 {
+		t.Errorf("Expected 1 cancelled occurrence, found %d", cancelled)
+	}
+}
+
+func TestApplyITIPInvalid(t *testing.T) {
+	cal := NewCalendar()
+	if _, err := cal.ApplyITIP(NewCalendar()); err == nil {
+		t.Errorf("Expected error for message without method")
+	}
+
+	reply, _ := NewReply(newMeeting(t), "sue@example.com", PartStatAccepted)
+	report, err := cal.ApplyITIP(reply)
+	if err != nil {
+		t.Fatalf("Failed to apply reply ( %s )", err)
+	}
+	if len(report.Rejected) != 1 || report.Rejected[0].Stale {
+		t.Errorf("Expected reply for unknown event to be rejected, found %#v", report)
+	}
+}
+
+func TestApplyITIPOccurrenceOfParsedSeries(t *testing.T) {
+	event := newMeeting(t)
+	request, _ := NewRequest(event)
+	organizer := parseMessage(t, request)
+
+	// the parser numbers the expanded occurrences in their SEQUENCE, the
+	// messages about an occurrence used to be rejected as stale
+	occurrence := event.GetStart().AddDate(0, 0, 2)
+	reply, _ := NewReply(event.Instance(occurrence), "sue@example.com", PartStatAccepted)
+	report, err := organizer.ApplyITIP(parseMessage(t, reply))
+	if err != nil {
+		t.Fatalf("Failed to apply reply ( %s )", err)
+	}
+	if len(report.Changes) != 1 || len(report.Rejected) != 0 {
+		t.Fatalf("Expected 1 change, found %#v", report)
+	}
+	for _, e := range organizer.GetEvents() {
+		expected := PartStatNeedsAction
+		if e.GetStart().Equal(occurrence) {
+			expected = PartStatAccepted
+		}
+		if status := findAttendee(&e, "sue@example.com").GetStatus(); status != expected {
+			t.Errorf("Expected attendee status %s at %s, found %s", expected, e.GetStart(), status)
+		}
+	}
+
+	moved := event.Instance(occurrence)
+	moved.SetSequence(1)
+	moved.SetStart(occurrence.Add(time.Hour))
+	moved.SetEnd(occurrence.Add(time.Hour + 15*time.Minute))
+	update, _ := NewRequest(moved)
+	report, err = organizer.ApplyITIP(parseMessage(t, update))
+	if err != nil {
+		t.Fatalf("Failed to apply request ( %s )", err)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Kind != ITIPUpdated || len(report.Rejected) != 0 {
+		t.Fatalf("Expected occurrence to be updated, found %#v", report)
+	}
+}
@@ -0,0 +1,174 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMeeting(t *testing.T) *Event {
+	start := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	rrule := NewRRule(FreqDaily)
+	rrule.Count = 5
+	event, err := NewEventBuilder().
+		UID("standup@example.com").
+		Summary("Standup").
+		Location("Room 1").
+		Start(start, nil).
+		Duration(15*time.Minute).
+		Organizer("Boss", "boss@example.com").
+		Attendee(NewAttendee().SetName("John Smith").SetEmail("j.smith@example.com")).
+		Attendee(NewAttendee().SetName("Sue").SetEmail("sue@example.com")).
+		Recur(rrule).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	return event
+}
+
+func messageEvent(t *testing.T, msg *Calendar) string {
+	events := msg.GetEvents()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event in message, found %d", len(events))
+	}
+	return unfold(msg.Serialize())
+}
+
+func TestITIPRequest(t *testing.T) {
+	event := newMeeting(t)
+	msg, err := NewRequest(event)
+	if err != nil {
+		t.Fatalf("Failed to create request ( %s )", err)
+	}
+	if msg.GetMethod() != MethodRequest {
+		t.Errorf("Expected method %s, found %s", MethodRequest, msg.GetMethod())
+	}
+	ics := messageEvent(t, msg)
+	for _, line := range []string{
+		"METHOD:REQUEST\r\n",
+		"ORGANIZER;CN=Boss:mailto:boss@example.com\r\n",
+		"ATTENDEE;CUTYPE=INDIVIDUAL;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE;CN=Sue:mailto:sue@example.com\r\n",
+		"RRULE:FREQ=DAILY;COUNT=5\r\n",
+	} {
+		if !strings.Contains(ics, line) {
+			t.Errorf("Expected request to contain %q, found:\n%s", line, ics)
+		}
+	}
+	if event.GetAttendees()[0].GetRSVP() {
+		t.Errorf("Expected request to leave the original attendees untouched")
+	}
+
+	if _, err := NewRequest(NewEvent().SetImportedID("x")); err == nil {
+		t.Errorf("Expected error for request without organizer")
+	}
+}
+
+func TestITIPUpdateRequestBumpsSequence(t *testing.T) {
+	event := newMeeting(t)
+	event.SetSequence(2)
+	event.GetAttendees()[0].SetStatus(PartStatAccepted)
+
+	renamed := cloneEvent(event).SetSummary("Daily standup")
+	msg, err := NewUpdateRequest(event, renamed)
+	if err != nil {
+		t.Fatalf("Failed to create update ( %s )", err)
+	}
+	updated := msg.GetEvents()[0]
+	if updated.GetSequence() != 2 {
+		t.Errorf("Expected sequence %d for minor change, found %d", 2, updated.GetSequence())
+	}
+	if updated.GetAttendees()[0].GetStatus() != PartStatAccepted {
+		t.Errorf("Expected accepted attendee to stay accepted, found %s", updated.GetAttendees()[0].GetStatus())
+	}
+
+	moved := cloneEvent(event)
+	moved.SetStart(event.GetStart().Add(time.Hour)).SetEnd(event.GetEnd().Add(time.Hour))
+	msg, err = NewUpdateRequest(event, moved)
+	if err != nil {
+		t.Fatalf("Failed to create update ( %s )", err)
+	}
+	updated = msg.GetEvents()[0]
+	if updated.GetSequence() != 3 {
+		t.Errorf("Expected sequence %d for rescheduled event, found %d", 3, updated.GetSequence())
+	}
+	if updated.GetAttendees()[0].GetStatus() != PartStatNeedsAction {
+		t.Errorf("Expected attendee to reply again, found %s", updated.GetAttendees()[0].GetStatus())
+	}
+}
+
+func TestITIPReply(t *testing.T) {
+	event := newMeeting(t)
+	msg, err := NewReply(event, "SUE@example.com", PartStatAccepted)
+	if err != nil {
+		t.Fatalf("Failed to create reply ( %s )", err)
+	}
+	ics := messageEvent(t, msg)
+	if !strings.Contains(ics, "METHOD:REPLY\r\n") || !strings.Contains(ics, "PARTSTAT=ACCEPTED;CN=Sue:mailto:sue@example.com\r\n") {
+		t.Errorf("Expected accepted reply of sue, found:\n%s", ics)
+	}
+	if strings.Contains(ics, "j.smith@example.com") || strings.Contains(ics, "RRULE") || strings.Contains(ics, "LOCATION") {
+		t.Errorf("Expected reply to carry only the replying attendee, found:\n%s", ics)
+	}
+	if _, err := NewReply(event, "nobody@example.com", PartStatAccepted); err == nil {
+		t.Errorf("Expected error for reply of uninvited attendee")
+	}
+	if _, err := NewReply(event, "sue@example.com", "MAYBE"); err == nil {
+		t.Errorf("Expected error for invalid PARTSTAT")
+	}
+}
+
+func TestITIPCancelInstance(t *testing.T) {
+	event := newMeeting(t)
+	occurrence := event.GetStart().AddDate(0, 0, 2)
+	msg, err := NewCancel(event.Instance(occurrence))
+	if err != nil {
+		t.Fatalf("Failed to create cancel ( %s )", err)
+	}
+	ics := messageEvent(t, msg)
+	for _, line := range []string{
+		"METHOD:CANCEL\r\n",
+		"RECURRENCE-ID:20240612T090000Z\r\n",
+		"SEQUENCE:1\r\n",
+		"STATUS:CANCELLED\r\n",
+	} {
+		if !strings.Contains(ics, line) {
+			t.Errorf("Expected cancel to contain %q, found:\n%s", line, ics)
+		}
+	}
+	if strings.Contains(ics, "RRULE") {
+		t.Errorf("Expected instance cancel without RRULE, found:\n%s", ics)
+	}
+}
+
+func TestITIPAddRefreshCounter(t *testing.T) {
+	event := newMeeting(t)
+
+	extra := cloneEvent(event)
+	extra.SetStart(event.GetStart().AddDate(0, 0, 7)).SetEnd(event.GetEnd().AddDate(0, 0, 7))
+	msg, err := NewAdd(extra)
+	if err != nil {
+		t.Fatalf("Failed to create add ( %s )", err)
+	}
+	if ics := messageEvent(t, msg); !strings.Contains(ics, "METHOD:ADD\r\n") || strings.Contains(ics, "RRULE") {
+		t.Errorf("Expected add without RRULE, found:\n%s", ics)
+	}
+
+	msg, err = NewRefresh(event, "j.smith@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create refresh ( %s )", err)
+	}
+	if ics := messageEvent(t, msg); strings.Contains(ics, "DTSTART") || strings.Contains(ics, "SUMMARY") {
+		t.Errorf("Expected refresh without event details, found:\n%s", ics)
+	}
+
+	proposed := cloneEvent(event)
+	proposed.SetStart(event.GetStart().Add(time.Hour)).SetEnd(event.GetEnd().Add(time.Hour))
+	msg, err = NewCounter(event, proposed, "sue@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create counter ( %s )", err)
+	}
+	if ics := messageEvent(t, msg); !strings.Contains(ics, "METHOD:COUNTER\r\n") || !strings.Contains(ics, "DTSTART:20240610T100000Z\r\n") {
+		t.Errorf("Expected counter with the proposed start, found:\n%s", ics)
+	}
+}
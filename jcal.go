@@ -0,0 +1,587 @@
+package ics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ======================== VALUE TYPES ===================
+
+// default value types of the properties (RFC 5545 section 3.8), the
+// properties that are not listed have the "unknown" type
+var valueTypes = map[string]string{
+	"CALSCALE":         "text",
+	"METHOD":           "text",
+	"PRODID":           "text",
+	"VERSION":          "text",
+	"ATTACH":           "uri",
+	"CATEGORIES":       "text",
+	"CLASS":            "text",
+	"COMMENT":          "text",
+	"DESCRIPTION":      "text",
+	"GEO":              "float",
+	"LOCATION":         "text",
+	"PERCENT-COMPLETE": "integer",
+	"PRIORITY":         "integer",
+	"RESOURCES":        "text",
+	"STATUS":           "text",
+	"SUMMARY":          "text",
+	"COMPLETED":        "date-time",
+	"DTEND":            "date-time",
+	"DUE":              "date-time",
+	"DTSTART":          "date-time",
+	"DURATION":         "duration",
+	"FREEBUSY":         "period",
+	"TRANSP":           "text",
+	"TZID":             "text",
+	"TZNAME":           "text",
+	"TZOFFSETFROM":     "utc-offset",
+	"TZOFFSETTO":       "utc-offset",
+	"TZURL":            "uri",
+	"ATTENDEE":         "cal-address",
+	"CONTACT":          "text",
+	"ORGANIZER":        "cal-address",
+	"RECURRENCE-ID":    "date-time",
+	"RELATED-TO":       "text",
+	"URL":              "uri",
+	"UID":              "text",
+	"EXDATE":           "date-time",
+	"RDATE":            "date-time",
+	"RRULE":            "recur",
+	"ACTION":           "text",
+	"REPEAT":           "integer",
+	"TRIGGER":          "duration",
+	"CREATED":          "date-time",
+	"DTSTAMP":          "date-time",
+	"LAST-MODIFIED":    "date-time",
+	"SEQUENCE":         "integer",
+	"REQUEST-STATUS":   "text",
+}
+
+// properties whose value is a comma separated list
+var multiValueProps = map[string]bool{
+	"CATEGORIES": true,
+	"RESOURCES":  true,
+	"EXDATE":     true,
+	"RDATE":      true,
+	"FREEBUSY":   true,
+}
+
+// properties whose value is a semicolon separated structure
+var structuredProps = map[string]bool{
+	"GEO":            true,
+	"REQUEST-STATUS": true,
+}
+
+// parameters whose value is a list of calendar addresses
+var multiValueParams = map[string]bool{
+	"MEMBER":         true,
+	"DELEGATED-TO":   true,
+	"DELEGATED-FROM": true,
+}
+
+// recurrence rule parts with integer values
+var recurIntParts = map[string]bool{
+	"count":      true,
+	"interval":   true,
+	"bysecond":   true,
+	"byminute":   true,
+	"byhour":     true,
+	"bymonthday": true,
+	"byyearday":  true,
+	"byweekno":   true,
+	"bymonth":    true,
+	"bysetpos":   true,
+}
+
+// returns the value type of the property, taken from the VALUE parameter
+// or the default of the property
+func (p *property) valueType() string {
+	if value := p.param("VALUE"); value != "" {
+		return strings.ToLower(value)
+	}
+	if valueType, ok := valueTypes[p.name]; ok {
+		return valueType
+	}
+	return "unknown"
+}
+
+// returns the values of the property split in lists and structures
+func (p *property) values() [][]string {
+	var values []string
+	if multiValueProps[p.name] {
+		values = splitEscaped(p.value, ',')
+	} else {
+		values = []string{p.value}
+	}
+	result := make([][]string, len(values))
+	for i, value := range values {
+		if structuredProps[p.name] {
+			result[i] = splitEscaped(value, ';')
+		} else {
+			result[i] = []string{value}
+		}
+	}
+	return result
+}
+
+// ======================== VALUE FORMATS ===================
+
+// 20240610 -> 2024-06-10
+func extendedDate(value string) string {
+	if len(value) != 8 {
+		return value
+	}
+	return value[:4] + "-" + value[4:6] + "-" + value[6:]
+}
+
+// 090000Z -> 09:00:00Z
+func extendedTime(value string) string {
+	if len(value) < 6 {
+		return value
+	}
+	return value[:2] + ":" + value[2:4] + ":" + value[4:]
+}
+
+// 20240610T090000Z -> 2024-06-10T09:00:00Z
+func extendedDateTime(value string) string {
+	if len(value) < 15 || value[8] != 'T' {
+		return value
+	}
+	return extendedDate(value[:8]) + "T" + extendedTime(value[9:])
+}
+
+// +0200 -> +02:00
+func extendedUTCOffset(value string) string {
+	if len(value) < 5 {
+		return value
+	}
+	result := value[:3] + ":" + value[3:5]
+	if len(value) >= 7 {
+		result += ":" + value[5:7]
+	}
+	return result
+}
+
+// 1997-03-08T16:00:00Z/PT8H30M -> 19970308T160000Z/PT8H30M
+func basicPeriod(value string) string {
+	parts := strings.Split(value, "/")
+	for i, part := range parts {
+		if !isDuration(part) {
+			parts[i] = basicFormat(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func extendedPeriod(value string) string {
+	parts := strings.Split(value, "/")
+	for i, part := range parts {
+		if !isDuration(part) {
+			parts[i] = extendedDateTime(part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func isDuration(value string) bool {
+	return strings.HasPrefix(strings.TrimLeft(value, "+-"), "P")
+}
+
+// removes the separators of the extended date and time formats
+func basicFormat(value string) string {
+	return strings.NewReplacer("-", "", ":", "").Replace(value)
+}
+
+// formats a value of the given type in the RFC 7265 / RFC 6321 notation
+func extendedValue(valueType, value string) string {
+	switch valueType {
+	case "text":
+		return unescapeText(value)
+	case "date":
+		return extendedDate(value)
+	case "date-time":
+		return extendedDateTime(value)
+	case "time":
+		return extendedTime(value)
+	case "utc-offset":
+		return extendedUTCOffset(value)
+	case "period":
+		return extendedPeriod(value)
+	}
+	return value
+}
+
+// reverts extendedValue
+func basicValue(valueType, value string) string {
+	switch valueType {
+	case "text":
+		return escapeText(value)
+	case "date", "date-time", "time":
+		return basicFormat(value)
+	case "utc-offset":
+		return strings.Replace(value, ":", "", -1)
+	case "period":
+		return basicPeriod(value)
+	case "boolean":
+		return strings.ToUpper(value)
+	}
+	return value
+}
+
+// ======================== JSON OBJECTS ===================
+
+// jsonObject is a JSON object that keeps the order of its members
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(m.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// reads a JSON value keeping the order of the object members
+func readJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '[':
+		list := []interface{}{}
+		for dec.More() {
+			value, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	case '{':
+		obj := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{fmt.Sprint(key), value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	}
+	return nil, fmt.Errorf("Unexpected %s", delim)
+}
+
+// ======================== JCAL ENCODING ===================
+
+// builds the jCal array of the component
+func (c *component) jcal() []interface{} {
+	props := make([]interface{}, 0, len(c.props))
+	for _, prop := range c.props {
+		props = append(props, prop.jcal())
+	}
+	comps := make([]interface{}, 0, len(c.comps))
+	for _, sub := range c.comps {
+		comps = append(comps, sub.jcal())
+	}
+	return []interface{}{strings.ToLower(c.name), props, comps}
+}
+
+// builds the jCal array of the property
+func (p *property) jcal() []interface{} {
+	valueType := p.valueType()
+	params := jsonObject{}
+	for _, prm := range p.params {
+		if prm.name == "VALUE" {
+			continue
+		}
+		var value interface{} = prm.value
+		if multiValueParams[prm.name] {
+			value = strings.Split(prm.value, ",")
+		}
+		params = append(params, jsonMember{strings.ToLower(prm.name), value})
+	}
+
+	prop := []interface{}{strings.ToLower(p.name), params, valueType}
+	for _, parts := range p.values() {
+		if structuredProps[p.name] {
+			structured := make([]interface{}, len(parts))
+			for i, part := range parts {
+				structured[i] = jcalValue(valueType, part)
+			}
+			prop = append(prop, structured)
+			continue
+		}
+		prop = append(prop, jcalValue(valueType, parts[0]))
+	}
+	return prop
+}
+
+// converts a single value to its JSON type
+func jcalValue(valueType, value string) interface{} {
+	switch valueType {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return json.Number(strings.TrimPrefix(value, "+"))
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(strings.TrimPrefix(value, "+"))
+		}
+	case "boolean":
+		return strings.EqualFold(value, "TRUE")
+	case "recur":
+		return jcalRecur(value)
+	}
+	return extendedValue(valueType, value)
+}
+
+// converts a RRULE value to a jCal object
+func jcalRecur(value string) jsonObject {
+	recur := jsonObject{}
+	for _, part := range strings.Split(value, ";") {
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		name := strings.ToLower(pair[0])
+		items := strings.Split(pair[1], ",")
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			switch {
+			case name == "until" && len(item) == 8:
+				values[i] = extendedDate(item)
+			case name == "until":
+				values[i] = extendedDateTime(item)
+			case recurIntParts[name]:
+				values[i] = jcalValue("integer", item)
+			default:
+				values[i] = item
+			}
+		}
+		if len(values) == 1 {
+			recur = append(recur, jsonMember{name, values[0]})
+		} else {
+			recur = append(recur, jsonMember{name, values})
+		}
+	}
+	return recur
+}
+
+// ======================== JCAL DECODING ===================
+
+// builds a component from its jCal array
+func componentFromJCal(value interface{}) (*component, error) {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) != 3 {
+		return nil, errors.New("jCal component must be an array of name, properties and components")
+	}
+	name, ok := arr[0].(string)
+	props, okProps := arr[1].([]interface{})
+	comps, okComps := arr[2].([]interface{})
+	if !ok || !okProps || !okComps {
+		return nil, errors.New("jCal component must be an array of name, properties and components")
+	}
+
+	c := newComponent(strings.ToUpper(name))
+	for _, p := range props {
+		prop, err := propertyFromJCal(p)
+		if err != nil {
+			return nil, err
+		}
+		c.props = append(c.props, prop)
+	}
+	for _, sub := range comps {
+		comp, err := componentFromJCal(sub)
+		if err != nil {
+			return nil, err
+		}
+		c.comps = append(c.comps, comp)
+	}
+	return c, nil
+}
+
+// builds a property from its jCal array
+func propertyFromJCal(value interface{}) (*property, error) {
+	arr, ok := value.([]interface{})
+	if !ok || len(arr) < 4 {
+		return nil, errors.New("jCal property must be an array of name, parameters, type and values")
+	}
+	name, ok := arr[0].(string)
+	params, okParams := arr[1].(jsonObject)
+	valueType, okType := arr[2].(string)
+	if !ok || !okParams || !okType {
+		return nil, fmt.Errorf("Invalid jCal property %v", arr[0])
+	}
+
+	prop := &property{name: strings.ToUpper(name)}
+	for _, m := range params {
+		prm := param{name: strings.ToUpper(m.name)}
+		if list, ok := m.value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			prm.value = strings.Join(items, ",")
+		} else {
+			prm.value = fmt.Sprint(m.value)
+		}
+		prop.params = append(prop.params, prm)
+	}
+	valueType = strings.ToLower(valueType)
+	defaultType, ok := valueTypes[prop.name]
+	if !ok {
+		defaultType = "unknown"
+	}
+	if valueType != defaultType && valueType != "unknown" {
+		prop.params = append(prop.params, param{"VALUE", strings.ToUpper(valueType)})
+	}
+
+	values := []string{}
+	for _, v := range arr[3:] {
+		if parts, ok := v.([]interface{}); ok && structuredProps[prop.name] {
+			structured := make([]string, len(parts))
+			for i, part := range parts {
+				structured[i] = icalValue(valueType, part)
+			}
+			values = append(values, strings.Join(structured, ";"))
+			continue
+		}
+		values = append(values, icalValue(valueType, v))
+	}
+	prop.value = strings.Join(values, ",")
+	return prop, nil
+}
+
+// converts a single JSON value to its iCalendar notation
+func icalValue(valueType string, value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return basicValue(valueType, v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case jsonObject:
+		return icalRecur(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// converts a jCal recur object to a RRULE value
+func icalRecur(recur jsonObject) string {
+	// FREQ is written first as some clients require it
+	sort.SliceStable(recur, func(i, j int) bool {
+		return recur[i].name == "freq" && recur[j].name != "freq"
+	})
+	parts := make([]string, 0, len(recur))
+	for _, m := range recur {
+		list, ok := m.value.([]interface{})
+		if !ok {
+			list = []interface{}{m.value}
+		}
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+			if m.name == "until" {
+				items[i] = basicFormat(items[i])
+			}
+		}
+		parts = append(parts, strings.ToUpper(m.name)+"="+strings.Join(items, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ======================== PUBLIC API ===================
+
+// ICalToJCal converts an iCalendar stream to jCal (RFC 7265). All
+// properties, parameters and sub components are kept.
+func ICalToJCal(iCalContent string) ([]byte, error) {
+	c, err := readComponent(iCalContent)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.jcal())
+}
+
+// JCalToICal converts a jCal (RFC 7265) object to an iCalendar stream.
+// A single component other than VCALENDAR is wrapped in a calendar.
+func JCalToICal(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := readJSON(dec)
+	if err != nil {
+		return "", err
+	}
+	c, err := componentFromJCal(value)
+	if err != nil {
+		return "", err
+	}
+	if c.name != "VCALENDAR" {
+		cal := newComponent("VCALENDAR")
+		cal.addProp("VERSION", "2.0")
+		cal.addProp("PRODID", ProdID)
+		cal.comps = append(cal.comps, c)
+		c = cal
+	}
+	return c.String(), nil
+}
+
+// MarshalJCal returns the calendar and its events as jCal (RFC 7265)
+func (c *Calendar) MarshalJCal() ([]byte, error) {
+	return json.Marshal(c.component().jcal())
+}
+
+// MarshalJCal returns the event as a jCal (RFC 7265) vevent
+func (e *Event) MarshalJCal() ([]byte, error) {
+	return json.Marshal(e.component().jcal())
+}
+
+// LoadJCal parses a jCal (RFC 7265) calendar like Load does for the
+// iCalendar format
+func (p *Parser) LoadJCal(data []byte) error {
+	content, err := JCalToICal(data)
+	if err != nil {
+		return err
+	}
+	p.Load(content)
+	return nil
+}
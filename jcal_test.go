@@ -0,0 +1,193 @@
+package ics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compactJSON(t *testing.T, data string) string {
+	var b bytes.Buffer
+	if err := json.Compact(&b, []byte(data)); err != nil {
+		t.Fatalf("Invalid JSON ( %s ):\n%s", err, data)
+	}
+	return b.String()
+}
+
+// the example of RFC 7265 appendix B.1
+func TestJCalRFCExample(t *testing.T) {
+	ical := crlf(
+		"BEGIN:VCALENDAR",
+		"CALSCALE:GREGORIAN",
+		"PRODID:-//Example Inc.//Example Calendar//EN",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTAMP:20080205T191224Z",
+		"DTSTART;VALUE=DATE:20081006",
+		"SUMMARY:Planning meeting",
+		"UID:4088E990AD89CB3DBB484909",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	)
+	jcal := `["vcalendar",
+	  [
+	    ["calscale", {}, "text", "GREGORIAN"],
+	    ["prodid", {}, "text", "-//Example Inc.//Example Calendar//EN"],
+	    ["version", {}, "text", "2.0"]
+	  ],
+	  [
+	    ["vevent",
+	      [
+	        ["dtstamp", {}, "date-time", "2008-02-05T19:12:24Z"],
+	        ["dtstart", {}, "date", "2008-10-06"],
+	        ["summary", {}, "text", "Planning meeting"],
+	        ["uid", {}, "text", "4088E990AD89CB3DBB484909"]
+	      ],
+	      []
+	    ]
+	  ]
+	]`
+
+	data, err := ICalToJCal(ical)
+	if err != nil {
+		t.Fatalf("Failed to convert to jCal ( %s )", err)
+	}
+	if string(data) != compactJSON(t, jcal) {
+		t.Errorf("Expected jCal:\n%s\nfound:\n%s", compactJSON(t, jcal), data)
+	}
+
+	back, err := JCalToICal([]byte(jcal))
+	if err != nil {
+		t.Fatalf("Failed to convert to iCalendar ( %s )", err)
+	}
+	if back != ical {
+		t.Errorf("Expected iCalendar:\n%s\nfound:\n%s", ical, back)
+	}
+}
+
+// a calendar with every kind of value, parameter and sub component
+var valueTypesICal = crlf(
+	"BEGIN:VCALENDAR",
+	"VERSION:2.0",
+	"PRODID:-//Example//Test//EN",
+	"X-WR-CALNAME:Team\\, with comma",
+	"BEGIN:VTIMEZONE",
+	"TZID:Europe/Sofia",
+	"BEGIN:STANDARD",
+	"DTSTART:19701025T040000",
+	"TZOFFSETFROM:+0300",
+	"TZOFFSETTO:+0200",
+	"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+	"BEGIN:VEVENT",
+	"UID:types@example.com",
+	"DTSTART;TZID=Europe/Sofia:20240610T090000",
+	"DURATION:PT1H",
+	"RRULE:FREQ=WEEKLY;UNTIL=20240701T000000Z;BYDAY=MO,WE;INTERVAL=2",
+	"EXDATE:20240612T060000Z,20240617T060000Z",
+	"SUMMARY;LANGUAGE=en:Review\\; part 1\\nbring notes",
+	"CATEGORIES:WORK,MEETING\\, WEEKLY",
+	"GEO:37.386013;-122.082932",
+	"SEQUENCE:2",
+	"ATTENDEE;MEMBER=\"mailto:dev@example.com\";PARTSTAT=ACCEPTED;CN=\"Smith, John\":mailto:j.smith@example.com",
+	"REQUEST-STATUS:2.0;Success",
+	"X-CUSTOM;X-PARAM=yes:raw\\,value",
+	"BEGIN:VALARM",
+	"ACTION:DISPLAY",
+	"TRIGGER;VALUE=DATE-TIME:20240610T054500Z",
+	"DESCRIPTION:Soon",
+	"END:VALARM",
+	"END:VEVENT",
+	"END:VCALENDAR",
+)
+
+func TestJCalValueTypes(t *testing.T) {
+	ical := valueTypesICal
+
+	data, err := ICalToJCal(ical)
+	if err != nil {
+		t.Fatalf("Failed to convert to jCal ( %s )", err)
+	}
+	for _, fragment := range []string{
+		`["x-wr-calname",{},"unknown","Team\\, with comma"]`,
+		`["tzoffsetto",{},"utc-offset","+02:00"]`,
+		`["rrule",{},"recur",{"freq":"YEARLY","bymonth":10,"byday":"-1SU"}]`,
+		`["dtstart",{"tzid":"Europe/Sofia"},"date-time","2024-06-10T09:00:00"]`,
+		`["rrule",{},"recur",{"freq":"WEEKLY","until":"2024-07-01T00:00:00Z","byday":["MO","WE"],"interval":2}]`,
+		`["exdate",{},"date-time","2024-06-12T06:00:00Z","2024-06-17T06:00:00Z"]`,
+		`["summary",{"language":"en"},"text","Review; part 1\nbring notes"]`,
+		`["categories",{},"text","WORK","MEETING, WEEKLY"]`,
+		`["geo",{},"float",[37.386013,-122.082932]]`,
+		`["sequence",{},"integer",2]`,
+		`["attendee",{"member":["mailto:dev@example.com"],"partstat":"ACCEPTED","cn":"Smith, John"},"cal-address","mailto:j.smith@example.com"]`,
+		`["request-status",{},"text",["2.0","Success"]]`,
+		`["x-custom",{"x-param":"yes"},"unknown","raw\\,value"]`,
+		`["trigger",{},"date-time","2024-06-10T05:45:00Z"]`,
+	} {
+		if !strings.Contains(string(data), fragment) {
+			t.Errorf("Expected jCal to contain %s, found:\n%s", fragment, data)
+		}
+	}
+
+	back, err := JCalToICal(data)
+	if err != nil {
+		t.Fatalf("Failed to convert to iCalendar ( %s )", err)
+	}
+	if unfold(back) != ical {
+		t.Errorf("Expected lossless round trip:\n%s\nfound:\n%s", ical, back)
+	}
+}
+
+func TestJCalCalendarRoundTrip(t *testing.T) {
+	rrule := NewRRule(FreqWeekly)
+	rrule.Count = 3
+	event, err := NewEventBuilder().
+		UID("sync@example.com").
+		Summary("Weekly sync, all hands").
+		Description("Agenda:\nstatus").
+		Location("Room 1").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), nil).
+		Duration(30*time.Minute).
+		Organizer("Boss", "boss@example.com").
+		Attendee(NewAttendee().SetName("John Smith").SetEmail("j.smith@example.com")).
+		Recur(rrule).
+		Alarm(NewAlarm().SetTrigger(-15 * time.Minute)).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	cal, err := NewCalendarBuilder().Name("Team").Description("Team events").Event(event).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+
+	data, err := cal.MarshalJCal()
+	if err != nil {
+		t.Fatalf("Failed to marshal jCal ( %s )", err)
+	}
+	parser := New()
+	if err := parser.LoadJCal(data); err != nil {
+		t.Fatalf("Failed to load jCal ( %s )", err)
+	}
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 {
+		t.Fatalf("Expected 1 calendar, found %d calendars", len(calendars))
+	}
+	loaded := calendars[0]
+	if loaded.GetName() != "Team" {
+		t.Errorf("Expected calendar name %s, found %s", "Team", loaded.GetName())
+	}
+	if len(loaded.GetEvents()) != 3 {
+		t.Errorf("Expected 3 occurrences, found %d", len(loaded.GetEvents()))
+	}
+	if loaded.Serialize() != cal.Serialize() {
+		t.Errorf("Expected the same calendar after the round trip:\n%s\nfound:\n%s", cal.Serialize(), loaded.Serialize())
+	}
+
+	if err := parser.LoadJCal([]byte(`["vcalendar", []]`)); err == nil {
+		t.Errorf("Expected error for invalid jCal")
+	}
+}
@@ -0,0 +1,290 @@
+package ics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The JSON shape of the events, separate from jCal, is
+//
+//	{
+//	  "id": "5d41402abc4b2a76b9719d911017c592",
+//	  "uid": "review@example.com",
+//	  "summary": "Design review",
+//	  "description": "Bring the slides",
+//	  "location": "Room 1",
+//	  "start": "2024-06-10T09:00:00+03:00",
+//	  "end": "2024-06-10T10:00:00+03:00",
+//	  "startTzid": "Europe/Sofia",
+//	  "endTzid": "Europe/Sofia",
+//	  "allDay": false,
+//	  "status": "CONFIRMED",
+//	  "class": "PUBLIC",
+//	  "sequence": 1,
+//	  "rrule": "FREQ=WEEKLY;COUNT=4",
+//	  "recurrenceId": "2024-06-17T09:00:00+03:00",
+//	  "created": "2024-06-01T08:00:00Z",
+//	  "lastModified": "2024-06-01T08:00:00Z",
+//	  "organizer": {"name": "Boss", "email": "boss@example.com"},
+//	  "attendees": [{"name": "John Smith", "email": "j.smith@example.com",
+//	    "status": "ACCEPTED", "role": "REQ-PARTICIPANT", "type": "INDIVIDUAL", "rsvp": true}],
+//	  "geo": {"lat": 37.386013, "long": -122.082932},
+//	  "conference": "https://meet.example.com/abc-defg-hij",
+//	  "calendar": "Team"
+//	}
+//
+// Times are in RFC 3339, empty values are left out, a GEO that is not
+// numbers is null. The calendar name is
+// only written, the calendar of an event is set when the calendar is read.
+// Calendars are written as
+//
+//	{"name": "Team", "description": "", "url": "", "method": "", "version": 2,
+//	 "timezone": "Europe/Sofia", "events": [...]}
+
+type eventJSON struct {
+	ID           string      `json:"id,omitempty"`
+	UID          string      `json:"uid,omitempty"`
+	Summary      string      `json:"summary,omitempty"`
+	Description  string      `json:"description,omitempty"`
+	Location     string      `json:"location,omitempty"`
+	Start        time.Time   `json:"start"`
+	End          time.Time   `json:"end"`
+	StartTZID    string      `json:"startTzid,omitempty"`
+	EndTZID      string      `json:"endTzid,omitempty"`
+	AllDay       bool        `json:"allDay"`
+	Status       string      `json:"status,omitempty"`
+	BusyStatus   string      `json:"busyStatus,omitempty"`
+	Class        string      `json:"class,omitempty"`
+	Sequence     int         `json:"sequence,omitempty"`
+	RRule        string      `json:"rrule,omitempty"`
+	RecurrenceID *time.Time  `json:"recurrenceId,omitempty"`
+	Created      *time.Time  `json:"created,omitempty"`
+	LastModified *time.Time  `json:"lastModified,omitempty"`
+	DTStamp      *time.Time  `json:"dtstamp,omitempty"`
+	Organizer    *Attendee   `json:"organizer,omitempty"`
+	Attendees    []*Attendee `json:"attendees,omitempty"`
+	Geo          *Geo        `json:"geo,omitempty"`
+	Conference   string      `json:"conference,omitempty"`
+	Calendar     string      `json:"calendar,omitempty"`
+}
+
+type calendarJSON struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	URL         string  `json:"url"`
+	Method      string  `json:"method,omitempty"`
+	Version     float64 `json:"version"`
+	Timezone    string  `json:"timezone"`
+	Events      []Event `json:"events"`
+}
+
+type attendeeJSON struct {
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email"`
+	Status string `json:"status,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Type   string `json:"type,omitempty"`
+	RSVP   bool   `json:"rsvp,omitempty"`
+}
+
+type geoJSON struct {
+	Lat  json.Number `json:"lat"`
+	Long json.Number `json:"long"`
+}
+
+// returns nil for zero times so they are left out
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// ======================== EVENT ===================
+
+// MarshalJSON writes the event in the JSON shape described above
+func (e Event) MarshalJSON() ([]byte, error) {
+	v := eventJSON{
+		ID:           e.GetID(),
+		UID:          e.GetImportedID(),
+		Summary:      e.plainText(e.GetSummary()),
+		Description:  e.plainText(e.GetDescription()),
+		Location:     e.plainText(e.GetLocation()),
+		Start:        e.GetStart(),
+		End:          e.GetEnd(),
+		StartTZID:    e.GetStartTZID(),
+		EndTZID:      e.GetEndTZID(),
+		AllDay:       e.IsWholeDay(),
+		Status:       e.GetStatus(),
+		BusyStatus:   e.GetBusyStatus(),
+		Class:        e.GetClass(),
+		Sequence:     e.GetSequence(),
+		RRule:        e.GetRRule(),
+		RecurrenceID: optionalTime(e.GetRecurrenceID()),
+		Created:      optionalTime(e.GetCreated()),
+		LastModified: optionalTime(e.GetLastModified()),
+		DTStamp:      optionalTime(e.GetDTStamp()),
+		Organizer:    e.GetOrganizer(),
+		Attendees:    e.GetAttendees(),
+		Geo:          e.GetGeo(),
+		Conference:   e.GetConference(),
+	}
+	if cal := e.GetCalendar(); cal != nil {
+		v.Calendar = cal.GetName()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads the event from the JSON shape described above
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var v eventJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*e = *NewEvent()
+	e.SetImportedID(v.UID)
+	e.SetSummary(v.Summary)
+	e.SetDescription(v.Description)
+	e.SetLocation(v.Location)
+	e.SetStart(v.Start)
+	e.SetEnd(v.End)
+	e.SetStartTZID(v.StartTZID)
+	e.SetEndTZID(v.EndTZID)
+	e.SetWholeDayEvent(v.AllDay)
+	e.SetStatus(v.Status)
+	e.SetBusyStatus(v.BusyStatus)
+	e.SetClass(v.Class)
+	e.SetSequence(v.Sequence)
+	e.SetRRule(v.RRule)
+	e.SetRecurrenceID(timeValue(v.RecurrenceID))
+	e.SetCreated(timeValue(v.Created))
+	e.SetLastModified(timeValue(v.LastModified))
+	e.SetDTStamp(timeValue(v.DTStamp))
+	e.SetOrganizer(v.Organizer)
+	if v.Attendees != nil {
+		e.SetAttendees(v.Attendees)
+	}
+	e.SetGeo(v.Geo)
+	e.SetConference(v.Conference)
+
+	if v.ID == "" {
+		v.ID = e.GenerateEventId()
+	}
+	e.SetID(v.ID)
+	return nil
+}
+
+// ======================== CALENDAR ===================
+
+// MarshalJSON writes the calendar with all its events
+func (c *Calendar) MarshalJSON() ([]byte, error) {
+	tz := c.GetTimezone()
+	events := c.GetEvents()
+	if events == nil {
+		events = []Event{}
+	}
+	return json.Marshal(calendarJSON{
+		Name:        c.GetName(),
+		Description: c.GetDesc(),
+		URL:         c.GetUrl(),
+		Method:      c.GetMethod(),
+		Version:     c.GetVersion(),
+		Timezone:    tz.String(),
+		Events:      events,
+	})
+}
+
+// UnmarshalJSON reads the calendar and adds its events
+func (c *Calendar) UnmarshalJSON(data []byte) error {
+	var v calendarJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(v.Timezone)
+	if err != nil {
+		return fmt.Errorf("Invalid calendar timezone %s ( %s )", v.Timezone, err)
+	}
+
+	*c = *NewCalendar()
+	c.SetName(v.Name)
+	c.SetDesc(v.Description)
+	c.SetUrl(v.URL)
+	c.SetMethod(v.Method)
+	c.SetVersion(v.Version)
+	c.SetTimezone(*loc)
+	for _, event := range v.Events {
+		event.SetCalendar(c)
+		if _, err := c.SetEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ======================== ATTENDEE ===================
+
+// MarshalJSON writes the attendee as name, email, status, role, type and rsvp
+func (a *Attendee) MarshalJSON() ([]byte, error) {
+	return json.Marshal(attendeeJSON{
+		Name:   a.GetName(),
+		Email:  a.GetEmail(),
+		Status: a.GetStatus(),
+		Role:   a.GetRole(),
+		Type:   a.GetType(),
+		RSVP:   a.GetRSVP(),
+	})
+}
+
+// UnmarshalJSON reads the attendee written by MarshalJSON
+func (a *Attendee) UnmarshalJSON(data []byte) error {
+	var v attendeeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = *NewAttendee()
+	a.SetName(v.Name).SetEmail(v.Email).SetStatus(v.Status).SetRole(v.Role).SetType(v.Type).SetRSVP(v.RSVP)
+	return nil
+}
+
+// ======================== GEO ===================
+
+// MarshalJSON writes the position as lat and long numbers, null when they
+// are not numbers
+func (g *Geo) MarshalJSON() ([]byte, error) {
+	_, latErr := g.Latitude()
+	_, longErr := g.Longitude()
+	if latErr != nil || longErr != nil {
+		// a broken GEO of a feed does not fail its calendar
+		return []byte("null"), nil
+	}
+	// JSON numbers have no plus sign
+	lat := strings.TrimPrefix(g.latStr, "+")
+	long := strings.TrimPrefix(g.longStr, "+")
+	return json.Marshal(geoJSON{Lat: json.Number(lat), Long: json.Number(long)})
+}
+
+// UnmarshalJSON reads the position written by MarshalJSON
+func (g *Geo) UnmarshalJSON(data []byte) error {
+	var v geoJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	for _, n := range []json.Number{v.Lat, v.Long} {
+		if _, err := strconv.ParseFloat(n.String(), 64); err != nil {
+			return fmt.Errorf("Invalid coordinate %q", n)
+		}
+	}
+	*g = *NewGeo(v.Lat.String(), v.Long.String())
+	return nil
+}
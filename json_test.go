@@ -0,0 +1,163 @@
+package ics
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventJSON(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Sofia")
+	if err != nil {
+		t.Fatalf("Failed to load location ( %s )", err)
+	}
+	event, err := NewEventBuilder().
+		UID("review@example.com").
+		Summary("Design review").
+		Description("Bring the slides; and coffee").
+		Location("Room 1").
+		Geo(37.386013, -122.082932).
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, loc), loc).
+		Duration(time.Hour).
+		Organizer("Boss", "boss@example.com").
+		Attendee(NewAttendee().SetName("John Smith").SetEmail("j.smith@example.com").SetRSVP(true)).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	event.SetRecurrenceID(time.Date(2024, 6, 10, 9, 0, 0, 0, loc))
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to marshal event ( %s )", err)
+	}
+	for _, fragment := range []string{
+		`"uid":"review@example.com"`,
+		`"summary":"Design review"`,
+		`"description":"Bring the slides; and coffee"`,
+		`"start":"2024-06-10T09:00:00+03:00"`,
+		`"end":"2024-06-10T10:00:00+03:00"`,
+		`"startTzid":"Europe/Sofia"`,
+		`"allDay":false`,
+		`"recurrenceId":"2024-06-10T09:00:00+03:00"`,
+		`"organizer":{"name":"Boss","email":"boss@example.com"`,
+		`"attendees":[{"name":"John Smith","email":"j.smith@example.com","status":"NEEDS-ACTION","role":"REQ-PARTICIPANT","type":"INDIVIDUAL","rsvp":true}]`,
+		`"geo":{"lat":37.386013,"long":-122.082932}`,
+	} {
+		if !strings.Contains(string(data), fragment) {
+			t.Errorf("Expected JSON to contain %s, found:\n%s", fragment, data)
+		}
+	}
+
+	var decoded Event
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal event ( %s )", err)
+	}
+	if decoded.GetID() != event.GetID() || decoded.GetImportedID() != event.GetImportedID() {
+		t.Errorf("Expected ids %s/%s, found %s/%s", event.GetID(), event.GetImportedID(), decoded.GetID(), decoded.GetImportedID())
+	}
+	if !decoded.GetStart().Equal(event.GetStart()) || !decoded.GetEnd().Equal(event.GetEnd()) {
+		t.Errorf("Expected %s - %s, found %s - %s", event.GetStart(), event.GetEnd(), decoded.GetStart(), decoded.GetEnd())
+	}
+	if decoded.GetOrganizer().GetEmail() != "boss@example.com" || len(decoded.GetAttendees()) != 1 || !decoded.GetAttendees()[0].GetRSVP() {
+		t.Errorf("Expected organizer and attendee, found %v and %v", decoded.GetOrganizer(), decoded.GetAttendees())
+	}
+	if lat, _ := decoded.GetGeo().Latitude(); lat != 37.386013 {
+		t.Errorf("Expected latitude %f, found %f", 37.386013, lat)
+	}
+	if decoded.Serialize() != event.Serialize() {
+		t.Errorf("Expected the same event after the round trip:\n%s\nfound:\n%s", event.Serialize(), decoded.Serialize())
+	}
+}
+
+func TestCalendarJSON(t *testing.T) {
+	parser := New()
+	input := parser.GetInputChan()
+	input <- "testCalendars/2eventsCal.ics"
+	parser.Wait()
+	calendars, err := parser.GetCalendars()
+	if err != nil || len(calendars) != 1 {
+		t.Fatalf("Expected 1 calendar, found %d ( %v )", len(calendars), err)
+	}
+	cal := calendars[0]
+
+	data, err := json.Marshal(cal)
+	if err != nil {
+		t.Fatalf("Failed to marshal calendar ( %s )", err)
+	}
+	if !strings.Contains(string(data), `"calendar":"`+cal.GetName()+`"`) {
+		t.Errorf("Expected the events to carry the calendar name %s, found:\n%s", cal.GetName(), data)
+	}
+
+	decoded := NewCalendar()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Failed to unmarshal calendar ( %s )", err)
+	}
+	if decoded.GetName() != cal.GetName() || decoded.GetDesc() != cal.GetDesc() {
+		t.Errorf("Expected calendar %s, found %s", cal.GetName(), decoded.GetName())
+	}
+	if len(decoded.GetEvents()) != len(cal.GetEvents()) {
+		t.Fatalf("Expected %d events, found %d", len(cal.GetEvents()), len(decoded.GetEvents()))
+	}
+	for _, event := range cal.GetEvents() {
+		found, err := decoded.GetEventByID(event.GetID())
+		if err != nil {
+			t.Errorf("Expected event %s after the round trip", event.GetID())
+			continue
+		}
+		if found.GetCalendar() != decoded {
+			t.Errorf("Expected event %s to belong to the decoded calendar", event.GetID())
+		}
+		if unescapeText(event.GetSummary()) != found.GetSummary() {
+			t.Errorf("Expected summary %s, found %s", event.GetSummary(), found.GetSummary())
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"lat":"north","long":1}`), NewGeo("", "")); err == nil {
+		t.Errorf("Expected error for invalid coordinate")
+	}
+}
+
+func TestMarshalInvalidGeo(t *testing.T) {
+	cal := NewCalendar()
+	event := NewEvent()
+	event.SetSummary("Lunch")
+	event.SetGeo(NewGeo("north", "1"))
+	cal.SetEvent(*event)
+
+	data, err := json.Marshal(cal)
+	if err != nil {
+		t.Fatalf("Expected the calendar with a broken GEO to marshal, found %s", err)
+	}
+	if !strings.Contains(string(data), `"geo":null`) || !strings.Contains(string(data), `"summary":"Lunch"`) {
+		t.Errorf("Expected the event with a null geo, found %s", data)
+	}
+	single, _ := json.Marshal(&cal.GetEvents()[0])
+	if !strings.Contains(string(data), string(single)) {
+		t.Errorf("Expected the event %s in the calendar %s", single, data)
+	}
+}
+
+func TestMarshalEventValues(t *testing.T) {
+	event := NewEvent()
+	event.SetImportedID("lunch@example.com")
+	event.SetSummary("Lunch")
+	pointer, _ := json.Marshal(event)
+
+	// the events that are not pointers used to be written as empty objects
+	value, err := json.Marshal(*event)
+	if err != nil {
+		t.Fatalf("Failed to marshal the event value ( %s )", err)
+	}
+	if string(value) != string(pointer) {
+		t.Errorf("Expected the event value as %s, found %s", pointer, value)
+	}
+	byUID, err := json.Marshal(map[string]Event{"lunch": *event})
+	if err != nil {
+		t.Fatalf("Failed to marshal the map of events ( %s )", err)
+	}
+	if expected := `{"lunch":` + string(pointer) + `}`; string(byUID) != expected {
+		t.Errorf("Expected the map as %s, found %s", expected, byUID)
+	}
+}
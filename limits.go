@@ -0,0 +1,179 @@
+package ics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Limits bound the calendars read by the parser so untrusted urls can be
+// parsed safely, a zero field is no limit. A calendar over a limit is left
+// out and a *LimitError is added to the parser errors.
+type Limits struct {
+	// the largest content of a calendar, the downloads stop there
+	MaxBytes int64
+	// the most content lines of a calendar, the folded lines count once
+	MaxLines int
+	// the longest unfolded content line
+	MaxLineLength int
+	// the most events of a calendar, before the repeat rules
+	MaxEvents int
+	// the most attendees of an event
+	MaxAttendees int
+	// the most events created by the repeat rules of a calendar
+	MaxInstances int
+	// the longest time to parse a calendar
+	ParseTimeout time.Duration
+}
+
+// LimitError is the error of a calendar over one of the Limits
+type LimitError struct {
+	// the name of the field of Limits, like "MaxEvents"
+	Limit string
+	Max   int64
+	// the url of the calendar with its secrets hidden
+	URL string
+}
+
+// returns the error of the limit of the calendar of the url
+func newLimitError(limit string, max int64, url string) *LimitError {
+	return &LimitError{Limit: limit, Max: max, URL: Redact(url)}
+}
+
+func (e *LimitError) Error() string {
+	what := "calendar"
+	if e.URL != "" {
+		what += " " + e.URL
+	}
+	if e.Limit == "ParseTimeout" {
+		return fmt.Sprintf("The %s is over the %s of %s", what, e.Limit, time.Duration(e.Max))
+	}
+	return fmt.Sprintf("The %s is over the %s of %d", what, e.Limit, e.Max)
+}
+
+// SetLimits sets the limits of the calendars, the MaxBytes also stops the
+// downloads of the http, https, webcal and webcals urls
+func (p *Parser) SetLimits(limits Limits) *Parser {
+	p.fetchMutex.Lock()
+	p.limits = limits
+	p.fetchMutex.Unlock()
+
+	web := p.httpFetcher()
+	web.MaxBytes = limits.MaxBytes
+	p.setHTTPFetcher(web)
+	return p
+}
+
+// GetLimits returns the limits of the calendars
+func (p *Parser) GetLimits() Limits {
+	p.fetchMutex.Lock()
+	defer p.fetchMutex.Unlock()
+	return p.limits
+}
+
+// limitedBody fails with a LimitError when it has more than max bytes
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	err       error
+}
+
+// returns the body failing after max bytes, the body itself if max is 0
+func limitBody(body io.ReadCloser, max int64, url string) io.ReadCloser {
+	if max <= 0 {
+		return body
+	}
+	return &limitedBody{body, max, newLimitError("MaxBytes", max, url)}
+}
+
+func (b *limitedBody) Read(data []byte) (int, error) {
+	// reads a byte more than allowed to know if there is more
+	if int64(len(data)) > b.remaining+1 {
+		data = data[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(data)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// limits the body of the response to the MaxBytes of the fetcher
+func (f *HTTPFetcher) limitResponse(response *http.Response, url string) (*http.Response, error) {
+	if f.MaxBytes <= 0 {
+		return response, nil
+	}
+	if response.ContentLength > f.MaxBytes {
+		response.Body.Close()
+		return nil, newLimitError("MaxBytes", f.MaxBytes, url)
+	}
+	response.Body = limitBody(response.Body, f.MaxBytes, url)
+	return response, nil
+}
+
+// checks the lines, events and attendees of the content before it is parsed
+func (l Limits) checkContent(content, url string) error {
+	lines, lineLength, events, attendees := 0, 0, 0, 0
+	inEvent, inAlarm := false, false
+	for len(content) > 0 {
+		line := content
+		if i := strings.IndexByte(content, '\n'); i >= 0 {
+			line, content = content[:i], content[i+1:]
+		} else {
+			content = ""
+		}
+		line = strings.TrimSuffix(line, "\r")
+
+		// the folded lines continue the previous line
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			lineLength += len(line) - 1
+			if l.MaxLineLength > 0 && lineLength > l.MaxLineLength {
+				return newLimitError("MaxLineLength", int64(l.MaxLineLength), url)
+			}
+			continue
+		}
+		lines++
+		lineLength = len(line)
+		if l.MaxLines > 0 && lines > l.MaxLines {
+			return newLimitError("MaxLines", int64(l.MaxLines), url)
+		}
+		if l.MaxLineLength > 0 && lineLength > l.MaxLineLength {
+			return newLimitError("MaxLineLength", int64(l.MaxLineLength), url)
+		}
+
+		switch {
+		case strings.HasPrefix(line, "BEGIN:VEVENT"):
+			inEvent = true
+			attendees = 0
+			events++
+			if l.MaxEvents > 0 && events > l.MaxEvents {
+				return newLimitError("MaxEvents", int64(l.MaxEvents), url)
+			}
+		case strings.HasPrefix(line, "END:VEVENT"):
+			inEvent = false
+		case strings.HasPrefix(line, "BEGIN:VALARM"):
+			inAlarm = true
+		case strings.HasPrefix(line, "END:VALARM"):
+			inAlarm = false
+		case inEvent && !inAlarm && (strings.HasPrefix(line, "ATTENDEE:") || strings.HasPrefix(line, "ATTENDEE;")):
+			attendees++
+			if l.MaxAttendees > 0 && attendees > l.MaxAttendees {
+				return newLimitError("MaxAttendees", int64(l.MaxAttendees), url)
+			}
+		}
+	}
+	return nil
+}
+
+// returns the error if the parse of the calendar is past the deadline
+func (l Limits) checkDeadline(deadline time.Time, url string) error {
+	if l.ParseTimeout > 0 && time.Now().After(deadline) {
+		return newLimitError("ParseTimeout", int64(l.ParseTimeout), url)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package ics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const limitsTestCal = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20200102T100000Z\r\nDTEND:20200102T110000Z\r\nSUMMARY:Daily\r\nRRULE:FREQ=DAILY;COUNT=5\r\n" +
+	"ATTENDEE;CN=John:mailto:john@example.com\r\nATTENDEE:mailto:sue@exa\r\n mple.com\r\n" +
+	"BEGIN:VALARM\r\nACTION:EMAIL\r\nATTENDEE:mailto:alarm@example.com\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:b\r\nDTSTART:20200103T100000Z\r\nDTEND:20200103T110000Z\r\nSUMMARY:Once\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		limits Limits
+		limit  string
+	}{
+		{Limits{MaxLines: 10}, "MaxLines"},
+		{Limits{MaxLineLength: 30}, "MaxLineLength"},
+		{Limits{MaxEvents: 1}, "MaxEvents"},
+		{Limits{MaxAttendees: 1}, "MaxAttendees"},
+		{Limits{MaxInstances: 3}, "MaxInstances"},
+		{Limits{MaxBytes: 100}, "MaxBytes"},
+		{Limits{ParseTimeout: time.Nanosecond}, "ParseTimeout"},
+		{Limits{MaxLines: 30, MaxLineLength: 45, MaxEvents: 2, MaxAttendees: 2, MaxInstances: 4, MaxBytes: 1000, ParseTimeout: time.Minute}, ""},
+	}
+	url := "data:text/calendar," + strings.Replace(limitsTestCal, "\r\n", "%0D%0A", -1)
+	for _, c := range cases {
+		parser := New().SetLimits(c.limits)
+		calendars, errs := fetchAll(parser, url)
+		if c.limit == "" {
+			if len(calendars) != 1 || len(errs) != 0 {
+				t.Errorf("Expected 1 calendar within the limits, found %d and %v", len(calendars), errs)
+			} else if events := calendars[0].GetEvents(); len(events) != 6 {
+				t.Errorf("Expected 6 events, found %d", len(events))
+			}
+			continue
+		}
+		if len(calendars) != 0 || len(errs) != 1 {
+			t.Errorf("Expected 1 error of %s, found %d calendars and %v", c.limit, len(calendars), errs)
+			continue
+		}
+		limitErr, ok := errs[0].(*LimitError)
+		if !ok || limitErr.Limit != c.limit {
+			t.Errorf("Expected a LimitError of %s, found %#v", c.limit, errs[0])
+		}
+	}
+}
+
+func TestLimitsStopDownloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	big := strings.Repeat("X-FILLER:0123456789\r\n", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the chunked responses have no Content-Length
+		if r.URL.Path == "/chunked" {
+			w.Write([]byte(big[:100]))
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(big))
+	}))
+	defer server.Close()
+
+	parser := New().SetLimits(Limits{MaxBytes: 1024})
+	calendars, errs := fetchAll(parser, server.URL+"/length", server.URL+"/chunked")
+	if len(calendars) != 0 || len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, found %d calendars and %v", len(calendars), errs)
+	}
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "MaxBytes of 1024") {
+			t.Errorf("Expected a MaxBytes error, found %s", err)
+		}
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("Expected the partial downloads to be deleted, found %d files", len(files))
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	for _, size := range []int{0, 9, 10} {
+		body := limitBody(ioutil.NopCloser(strings.NewReader(strings.Repeat("a", size))), 10, "")
+		data, err := ioutil.ReadAll(body)
+		if err != nil || len(data) != size {
+			t.Errorf("Expected %d bytes within the limit, found %d and %v", size, len(data), err)
+		}
+	}
+	body := limitBody(ioutil.NopCloser(strings.NewReader(strings.Repeat("a", 11))), 10, "")
+	if _, err := ioutil.ReadAll(body); err == nil {
+		t.Errorf("Expected an error over the limit")
+	}
+}
+
+func TestLimitsDeadlineOfTheSplit(t *testing.T) {
+	checks := 0
+	check := func() error {
+		checks++
+		if checks == 1 {
+			return newLimitError("ParseTimeout", int64(time.Second), "https://example.com/cal.ics?token=s3cret")
+		}
+		return nil
+	}
+	events, _, err := explodeICal(unfoldICal(limitsTestCal), check)
+	if checks != 1 || events != nil {
+		t.Errorf("Expected the split to stop after the first event, found %d checks and %d events", checks, len(events))
+	}
+	limitErr, ok := err.(*LimitError)
+	if !ok || strings.Contains(limitErr.URL, "s3cret") || strings.Contains(limitErr.Error(), "s3cret") {
+		t.Errorf("Expected a LimitError without the secret of the url, found %#v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package ics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// max depth of nested multipart and forwarded messages
+const maxMailDepth = 10
+
+// LoadMailPath parses the calendar invites of an .eml file, a mbox file or
+// a Maildir directory. Each calendar gets the Message-ID of its email and
+// the path of the message as url. Broken messages are added to the parser
+// errors and skipped.
+func (p *Parser) LoadMailPath(path string) error {
+	// the parser is not done until the messages are read
+	p.startParsing()
+	defer p.doneParsing()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return p.loadMaildir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	head, _ := r.Peek(5)
+	if string(head) == "From " {
+		return p.loadMbox(r, path)
+	}
+	if err := p.loadMail(r, path); err != nil {
+		p.addError(err)
+	}
+	return nil
+}
+
+// LoadMail parses the calendar invites of a single RFC 5322 message
+func (p *Parser) LoadMail(r io.Reader) error {
+	p.startParsing()
+	defer p.doneParsing()
+	return p.loadMail(r, "")
+}
+
+// walks the cur and new folders of a Maildir, or every file of a plain folder
+func (p *Parser) loadMaildir(dir string) error {
+	folders := []string{}
+	for _, sub := range []string{"cur", "new"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err == nil && info.IsDir() {
+			folders = append(folders, filepath.Join(dir, sub))
+		}
+	}
+	if len(folders) == 0 {
+		folders = append(folders, dir)
+	}
+
+	for _, folder := range folders {
+		files, err := ioutil.ReadDir(folder)
+		if err != nil {
+			return err
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+		for _, file := range files {
+			if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(folder, file.Name())
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				p.addError(err)
+				continue
+			}
+			if err := p.loadMail(bytes.NewReader(content), path); err != nil {
+				p.addError(err)
+			}
+		}
+	}
+	return nil
+}
+
+// splits a mbox file on the "From " separator lines
+func (p *Parser) loadMbox(r io.Reader, path string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var message bytes.Buffer
+	index := 0
+	flush := func() {
+		if message.Len() == 0 {
+			return
+		}
+		index++
+		if err := p.loadMail(bytes.NewReader(message.Bytes()), fmt.Sprintf("%s#%d", path, index)); err != nil {
+			p.addError(err)
+		}
+		message.Reset()
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") {
+			flush()
+			continue
+		}
+		// mboxrd quoting of From lines in the body
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") && strings.HasPrefix(line, ">") {
+			line = line[1:]
+		}
+		message.WriteString(line)
+		message.WriteString("\r\n")
+	}
+	flush()
+	return scanner.Err()
+}
+
+// parses every calendar part of the message
+func (p *Parser) loadMail(r io.Reader, url string) error {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return fmt.Errorf("Failed to read mail %s ( %s )", url, err)
+	}
+	messageID := strings.Trim(msg.Header.Get("Message-Id"), "<> ")
+
+	contents := []string{}
+	err = walkMailPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Header.Get("Content-Disposition"), msg.Body, 0, &contents)
+	if err != nil {
+		return fmt.Errorf("Failed to read mail %s ( %s )", url, err)
+	}
+
+	// iMIP mails carry the same calendar inline and as attachment
+	seen := map[string]bool{}
+	for _, content := range contents {
+		key := strings.TrimSpace(content)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if cal := p.parseICalContent(content, url); cal != nil {
+			cal.SetMessageID(messageID)
+		}
+	}
+	return nil
+}
+
+// collects the decoded calendar parts of a MIME entity
+func walkMailPart(contentType, encoding, disposition string, body io.Reader, depth int, contents *[]string) error {
+	if depth > maxMailDepth {
+		return errors.New("too deeply nested message")
+	}
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// broken parts are skipped, the rest of the message is still read
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			// the multipart reader already decodes quoted-printable
+			err = walkMailPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part, depth+1, contents)
+			if err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		forwarded, err := mail.ReadMessage(decodeTransfer(encoding, body))
+		if err != nil {
+			return nil
+		}
+		return walkMailPart(forwarded.Header.Get("Content-Type"), forwarded.Header.Get("Content-Transfer-Encoding"), forwarded.Header.Get("Content-Disposition"), forwarded.Body, depth+1, contents)
+	case isCalendarPart(mediaType, params, disposition):
+		content, err := ioutil.ReadAll(decodeTransfer(encoding, body))
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(content), "BEGIN:VCALENDAR") {
+			*contents = append(*contents, string(content))
+		}
+	}
+	return nil
+}
+
+// text/calendar, application/ics or an attachment named *.ics
+func isCalendarPart(mediaType string, params map[string]string, disposition string) bool {
+	switch mediaType {
+	case "text/calendar", "application/ics", "text/x-vcalendar":
+		return true
+	case "application/octet-stream":
+		name := params["name"]
+		if _, dparams, err := mime.ParseMediaType(disposition); err == nil && dparams["filename"] != "" {
+			name = dparams["filename"]
+		}
+		return strings.HasSuffix(strings.ToLower(name), ".ics")
+	}
+	return false
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
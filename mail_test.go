@@ -0,0 +1,132 @@
+package ics
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMailPathEml(t *testing.T) {
+	parser := New()
+	if err := parser.LoadMailPath("testCalendars/invite.eml"); err != nil {
+		t.Fatalf("Failed to load mail ( %s )", err)
+	}
+	parseErrors, _ := parser.GetErrors()
+	for i, pErr := range parseErrors {
+		t.Errorf("Parsing Error №%d: %s", i, pErr)
+	}
+
+	calendars, _ := parser.GetCalendars()
+	// the inline part and the attachment carry the same calendar
+	if len(calendars) != 1 {
+		t.Fatalf("Expected 1 calendar, found %d calendars", len(calendars))
+	}
+	cal := calendars[0]
+	if cal.GetMessageID() != "planning-invite@example.com" {
+		t.Errorf("Expected Message-ID %s, found %s", "planning-invite@example.com", cal.GetMessageID())
+	}
+	if cal.GetMethod() != MethodRequest {
+		t.Errorf("Expected method %s, found %s", MethodRequest, cal.GetMethod())
+	}
+	event, err := cal.GetEventByImportedID("planning-2024@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get event ( %s )", err)
+	}
+	if event.GetSummary() != "Quarter planning" {
+		t.Errorf("Expected summary %s, found %s", "Quarter planning", event.GetSummary())
+	}
+	if len(event.GetAttendees()) != 1 || !event.GetAttendees()[0].GetRSVP() {
+		t.Errorf("Expected 1 attendee asked to reply, found %v", event.GetAttendees())
+	}
+}
+
+func TestLoadMailPathMbox(t *testing.T) {
+	parser := New()
+	if err := parser.LoadMailPath("testCalendars/invites.mbox"); err != nil {
+		t.Fatalf("Failed to load mbox ( %s )", err)
+	}
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 {
+		t.Fatalf("Expected 1 calendar, found %d calendars", len(calendars))
+	}
+	cal := calendars[0]
+	if cal.GetMessageID() != "retro-cancel@example.com" {
+		t.Errorf("Expected Message-ID %s, found %s", "retro-cancel@example.com", cal.GetMessageID())
+	}
+	if cal.GetUrl() != "testCalendars/invites.mbox#2" {
+		t.Errorf("Expected url %s, found %s", "testCalendars/invites.mbox#2", cal.GetUrl())
+	}
+	event := cal.GetEvents()[0]
+	if event.GetSummary() != "Retrospective – sprint 12" {
+		t.Errorf("Expected quoted-printable summary to be decoded, found %s", event.GetSummary())
+	}
+	if event.GetDescription() != "A long description that is wrapped by the quoted-printable encoding of the mailer" {
+		t.Errorf("Expected soft line breaks to be joined, found %s", event.GetDescription())
+	}
+}
+
+func TestLoadMailPathMaildir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maildir")
+	if err != nil {
+		t.Fatalf("Failed to create maildir ( %s )", err)
+	}
+	defer os.RemoveAll(dir)
+
+	eml, _ := ioutil.ReadFile("testCalendars/invite.eml")
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		os.Mkdir(filepath.Join(dir, sub), 0700)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "new", "1717228800.M1P1.host"), eml, 0600)
+	ioutil.WriteFile(filepath.Join(dir, "cur", "1717228801.M2P1.host:2,S"), []byte("Subject: no invite\r\n\r\nhello\r\n"), 0600)
+	// messages still being delivered are ignored
+	ioutil.WriteFile(filepath.Join(dir, "tmp", "1717228802.M3P1.host"), eml, 0600)
+
+	parser := New()
+	if err := parser.LoadMailPath(dir); err != nil {
+		t.Fatalf("Failed to load maildir ( %s )", err)
+	}
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 {
+		t.Fatalf("Expected 1 calendar, found %d calendars", len(calendars))
+	}
+	if calendars[0].GetMessageID() != "planning-invite@example.com" {
+		t.Errorf("Expected Message-ID %s, found %s", "planning-invite@example.com", calendars[0].GetMessageID())
+	}
+}
+
+// a reader signaling its first read
+type signalingReader struct {
+	io.Reader
+	read chan struct{}
+}
+
+func (r *signalingReader) Read(b []byte) (int, error) {
+	if r.read != nil {
+		close(r.read)
+		r.read = nil
+	}
+	return r.Reader.Read(b)
+}
+
+func TestLoadMailWaited(t *testing.T) {
+	eml, _ := ioutil.ReadFile("testCalendars/invite.eml")
+	pr, pw := io.Pipe()
+	read := make(chan struct{})
+	parser := New()
+	go parser.LoadMail(&signalingReader{Reader: pr, read: read})
+
+	<-read
+	if parser.Done() {
+		t.Errorf("Expected the parser not done while a mail is read")
+	}
+	pw.Write(eml)
+	pw.Close()
+	parser.Wait()
+
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 || calendars[0].GetMessageID() != "planning-invite@example.com" {
+		t.Errorf("Expected the calendar of the mail after Wait, found %d calendars", len(calendars))
+	}
+}
@@ -0,0 +1,669 @@
+// Package msgraph converts between the events of github.com/PuloV/ics-golang
+// and the event resource of Microsoft Graph (Outlook and Exchange). It works
+// on JSON dumps of the API and makes no API calls.
+package msgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+	wtz "github.com/yaegashi/wtz.go"
+)
+
+// Event is the Microsoft Graph event resource
+type Event struct {
+	ID                    string            `json:"id,omitempty"`
+	ICalUID               string            `json:"iCalUId,omitempty"`
+	Subject               string            `json:"subject,omitempty"`
+	Body                  *ItemBody         `json:"body,omitempty"`
+	BodyPreview           string            `json:"bodyPreview,omitempty"`
+	Start                 *DateTimeTimeZone `json:"start,omitempty"`
+	End                   *DateTimeTimeZone `json:"end,omitempty"`
+	OriginalStart         *time.Time        `json:"originalStart,omitempty"`
+	Location              *Location         `json:"location,omitempty"`
+	IsAllDay              bool              `json:"isAllDay"`
+	IsCancelled           bool              `json:"isCancelled,omitempty"`
+	Sensitivity           string            `json:"sensitivity,omitempty"`
+	ShowAs                string            `json:"showAs,omitempty"`
+	Type                  string            `json:"type,omitempty"`
+	SeriesMasterID        string            `json:"seriesMasterId,omitempty"`
+	Organizer             *Recipient        `json:"organizer,omitempty"`
+	Attendees             []*Attendee       `json:"attendees,omitempty"`
+	Recurrence            *Recurrence       `json:"recurrence,omitempty"`
+	IsOnlineMeeting       bool              `json:"isOnlineMeeting,omitempty"`
+	OnlineMeetingProvider string            `json:"onlineMeetingProvider,omitempty"`
+	OnlineMeeting         *OnlineMeeting    `json:"onlineMeeting,omitempty"`
+	CreatedDateTime       *time.Time        `json:"createdDateTime,omitempty"`
+	LastModifiedDateTime  *time.Time        `json:"lastModifiedDateTime,omitempty"`
+}
+
+// ItemBody is the text or html body of an event
+type ItemBody struct {
+	ContentType string `json:"contentType,omitempty"`
+	Content     string `json:"content,omitempty"`
+}
+
+// DateTimeTimeZone is a local time with its Windows or IANA time zone
+type DateTimeTimeZone struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Location is the place of an event
+type Location struct {
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// EmailAddress is the name and address of a person
+type EmailAddress struct {
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// Recipient is the organizer of an event
+type Recipient struct {
+	EmailAddress *EmailAddress `json:"emailAddress,omitempty"`
+}
+
+// Attendee is a guest of an event with its response
+type Attendee struct {
+	EmailAddress *EmailAddress   `json:"emailAddress,omitempty"`
+	Type         string          `json:"type,omitempty"`
+	Status       *ResponseStatus `json:"status,omitempty"`
+}
+
+// ResponseStatus is the response of an attendee
+type ResponseStatus struct {
+	Response string     `json:"response,omitempty"`
+	Time     *time.Time `json:"time,omitempty"`
+}
+
+// Recurrence is the pattern and range of a series
+type Recurrence struct {
+	Pattern *RecurrencePattern `json:"pattern,omitempty"`
+	Range   *RecurrenceRange   `json:"range,omitempty"`
+}
+
+// RecurrencePattern is how often a series repeats
+type RecurrencePattern struct {
+	Type           string   `json:"type"`
+	Interval       int      `json:"interval"`
+	DaysOfWeek     []string `json:"daysOfWeek,omitempty"`
+	DayOfMonth     int      `json:"dayOfMonth,omitempty"`
+	Month          int      `json:"month,omitempty"`
+	Index          string   `json:"index,omitempty"`
+	FirstDayOfWeek string   `json:"firstDayOfWeek,omitempty"`
+}
+
+// RecurrenceRange is how long a series repeats
+type RecurrenceRange struct {
+	Type                string `json:"type"`
+	StartDate           string `json:"startDate,omitempty"`
+	EndDate             string `json:"endDate,omitempty"`
+	NumberOfOccurrences int    `json:"numberOfOccurrences,omitempty"`
+	RecurrenceTimeZone  string `json:"recurrenceTimeZone,omitempty"`
+}
+
+// OnlineMeeting is the Teams or Skype meeting of an event
+type OnlineMeeting struct {
+	JoinURL string `json:"joinUrl,omitempty"`
+}
+
+// Events is the collection returned by the events endpoints
+type Events struct {
+	Value []*Event `json:"value"`
+}
+
+// the layouts of Graph times and dates
+const (
+	dateTimeLayout = "2006-01-02T15:04:05.0000000"
+	parseLayout    = "2006-01-02T15:04:05.9999999"
+	dateLayout     = "2006-01-02"
+)
+
+// ======================== DUMPS ===================
+
+// Decode reads an events collection, an array of events or a single event
+func Decode(r io.Reader) (*Events, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	events := &Events{}
+	switch {
+	case len(data) == 0:
+		return nil, errors.New("msgraph: empty dump")
+	case data[0] == '[':
+		err = json.Unmarshal(data, &events.Value)
+	default:
+		var probe struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if err = json.Unmarshal(data, &probe); err != nil {
+			return nil, err
+		}
+		if probe.Value != nil {
+			err = json.Unmarshal(data, events)
+		} else {
+			event := &Event{}
+			err = json.Unmarshal(data, event)
+			events.Value = []*Event{event}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReadFile reads a dump and converts it to a calendar
+func ReadFile(path string) (*ics.Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("msgraph: failed to read %s ( %s )", path, err)
+	}
+	cal, err := events.ToCalendar()
+	if err != nil {
+		return nil, err
+	}
+	cal.SetUrl(path)
+	return cal, nil
+}
+
+// WriteFile writes the events of the calendar as an events collection
+func WriteFile(path string, cal *ics.Calendar) error {
+	events, err := FromCalendar(cal)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ToCalendar converts the events of the collection to a calendar
+func (l *Events) ToCalendar() (*ics.Calendar, error) {
+	cal := ics.NewCalendar()
+	cal.SetVersion(2.0)
+	for i, item := range l.Value {
+		event, err := item.ToEvent()
+		if err != nil {
+			return nil, fmt.Errorf("msgraph: event %d ( %s )", i, err)
+		}
+		event.SetCalendar(cal)
+		if _, err := cal.SetEvent(*event); err != nil {
+			return nil, err
+		}
+	}
+	return cal, nil
+}
+
+// FromCalendar converts the events of the calendar to an events collection.
+// Repeating events are written once with their recurrence.
+func FromCalendar(cal *ics.Calendar) (*Events, error) {
+	l := &Events{Value: []*Event{}}
+	repeated := map[string]bool{}
+	events := cal.GetEvents()
+	for i := range events {
+		event := &events[i]
+		if event.GetRRule() != "" {
+			if repeated[event.GetImportedID()] {
+				continue
+			}
+			repeated[event.GetImportedID()] = true
+		}
+		item, err := FromEvent(event)
+		if err != nil {
+			return nil, err
+		}
+		l.Value = append(l.Value, item)
+	}
+	return l, nil
+}
+
+// ======================== EVENTS ===================
+
+var showAs = map[string]string{
+	"free":             "FREE",
+	"tentative":        "TENTATIVE",
+	"busy":             "BUSY",
+	"oof":              "OOF",
+	"workingElsewhere": "WORKINGELSEWHERE",
+}
+
+var sensitivities = map[string]string{
+	"normal":       "",
+	"personal":     "PRIVATE",
+	"private":      "PRIVATE",
+	"confidential": "CONFIDENTIAL",
+}
+
+var responses = map[string]string{
+	"none":                "NEEDS-ACTION",
+	"notResponded":        "NEEDS-ACTION",
+	"organizer":           "ACCEPTED",
+	"accepted":            "ACCEPTED",
+	"declined":            "DECLINED",
+	"tentativelyAccepted": "TENTATIVE",
+}
+
+// returns the first key of the value in the map, in the order of keys
+func reverse(m map[string]string, keys []string, value string) string {
+	for _, k := range keys {
+		if m[k] == strings.ToUpper(value) {
+			return k
+		}
+	}
+	return ""
+}
+
+var reTags = regexp.MustCompile(`(?s)<(head|style|script)[^>]*>.*?</(head|style|script)>|<[^>]*>`)
+var reBlankLines = regexp.MustCompile(`\n\s*\n+`)
+
+// returns the text of a html body
+func htmlToText(content string) string {
+	content = strings.NewReplacer("\r\n", "\n", "<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "</p>\n").Replace(content)
+	text := html.UnescapeString(reTags.ReplaceAllString(content, ""))
+	return strings.TrimSpace(reBlankLines.ReplaceAllString(text, "\n"))
+}
+
+// ToEvent converts the Graph event to an event
+func (g *Event) ToEvent() (*ics.Event, error) {
+	e := ics.NewEvent()
+	uid := g.ICalUID
+	if uid == "" {
+		uid = g.ID
+	}
+	e.SetImportedID(uid)
+	e.SetSummary(g.Subject)
+	if g.Body != nil {
+		if strings.EqualFold(g.Body.ContentType, "html") {
+			e.SetDescription(htmlToText(g.Body.Content))
+		} else {
+			e.SetDescription(g.Body.Content)
+		}
+	} else {
+		e.SetDescription(g.BodyPreview)
+	}
+	if g.Location != nil {
+		e.SetLocation(g.Location.DisplayName)
+	}
+	e.SetBusyStatus(showAs[g.ShowAs])
+	e.SetClass(sensitivities[g.Sensitivity])
+	if g.IsCancelled {
+		e.SetStatus("CANCELLED")
+	}
+	if g.CreatedDateTime != nil {
+		e.SetCreated(*g.CreatedDateTime)
+	}
+	if g.LastModifiedDateTime != nil {
+		e.SetLastModified(*g.LastModifiedDateTime)
+		e.SetDTStamp(*g.LastModifiedDateTime)
+	}
+
+	if g.Start == nil {
+		return nil, errors.New("event has no start")
+	}
+	start, tzid, err := g.Start.time(g.IsAllDay)
+	if err != nil {
+		return nil, err
+	}
+	end, endTZID := start, tzid
+	if g.End != nil {
+		if end, endTZID, err = g.End.time(g.IsAllDay); err != nil {
+			return nil, err
+		}
+	}
+	e.SetStart(start)
+	e.SetEnd(end)
+	e.SetStartTZID(tzid)
+	e.SetEndTZID(endTZID)
+	e.SetWholeDayEvent(g.IsAllDay)
+	if g.OriginalStart != nil {
+		e.SetRecurrenceID(*g.OriginalStart)
+	}
+	if g.Recurrence != nil {
+		rule, err := g.Recurrence.rrule(start)
+		if err != nil {
+			return nil, err
+		}
+		e.SetRRule(rule.String())
+	}
+
+	if g.Organizer != nil && g.Organizer.EmailAddress != nil {
+		e.SetOrganizer(ics.NewAttendee().SetName(g.Organizer.EmailAddress.Name).SetEmail(g.Organizer.EmailAddress.Address))
+	}
+	for _, a := range g.Attendees {
+		if a.EmailAddress == nil {
+			continue
+		}
+		attendee := ics.NewAttendee().
+			SetName(a.EmailAddress.Name).
+			SetEmail(a.EmailAddress.Address).
+			SetStatus("NEEDS-ACTION").
+			SetType("INDIVIDUAL").
+			SetRole("REQ-PARTICIPANT")
+		if a.Status != nil && responses[a.Status.Response] != "" {
+			attendee.SetStatus(responses[a.Status.Response])
+		}
+		if a.Type == "optional" {
+			attendee.SetRole("OPT-PARTICIPANT")
+		}
+		if a.Type == "resource" {
+			attendee.SetType("RESOURCE").SetRole("NON-PARTICIPANT")
+		}
+		e.SetAttendee(attendee)
+	}
+
+	if g.OnlineMeeting != nil && g.OnlineMeeting.JoinURL != "" {
+		e.SetConference(g.OnlineMeeting.JoinURL)
+	}
+
+	e.SetID(e.GenerateEventId())
+	return e, nil
+}
+
+// FromEvent converts the event to a Graph event
+func FromEvent(e *ics.Event) (*Event, error) {
+	g := &Event{
+		ICalUID:     e.GetImportedID(),
+		Subject:     e.GetPlainSummary(),
+		Body:        &ItemBody{ContentType: "text", Content: e.GetPlainDescription()},
+		Start:       newDateTimeTimeZone(e.GetStart(), e.GetStartTZID(), e.IsWholeDay()),
+		End:         newDateTimeTimeZone(e.GetEnd(), e.GetEndTZID(), e.IsWholeDay()),
+		IsAllDay:    e.IsWholeDay(),
+		IsCancelled: strings.EqualFold(e.GetStatus(), "CANCELLED"),
+		Sensitivity: "normal",
+		ShowAs:      reverse(showAs, []string{"free", "tentative", "busy", "oof", "workingElsewhere"}, e.GetBusyStatus()),
+		Type:        "singleInstance",
+	}
+	if g.ICalUID == "" {
+		g.ICalUID = e.GetID()
+	}
+	if e.GetLocation() != "" {
+		g.Location = &Location{DisplayName: e.GetPlainLocation()}
+	}
+	if class := reverse(sensitivities, []string{"private", "confidential"}, e.GetClass()); class != "" {
+		g.Sensitivity = class
+	}
+	if g.ShowAs == "" {
+		g.ShowAs = "busy"
+	}
+	if created := e.GetCreated(); !created.IsZero() {
+		g.CreatedDateTime = &created
+	}
+	if modified := e.GetLastModified(); !modified.IsZero() {
+		g.LastModifiedDateTime = &modified
+	}
+	if recurrenceID := e.GetRecurrenceID(); !recurrenceID.IsZero() {
+		g.OriginalStart = &recurrenceID
+		g.Type = "exception"
+	}
+	if e.GetRRule() != "" {
+		rule, err := ics.ParseRRule(e.GetRRule())
+		if err != nil {
+			return nil, err
+		}
+		if g.Recurrence, err = newRecurrence(rule, e.GetStart(), e.GetStartTZID()); err != nil {
+			return nil, err
+		}
+		g.Type = "seriesMaster"
+	}
+
+	if o := e.GetOrganizer(); o != nil {
+		g.Organizer = &Recipient{EmailAddress: &EmailAddress{Name: o.GetName(), Address: o.GetEmail()}}
+	}
+	for _, a := range e.GetAttendees() {
+		attendeeType := "required"
+		if a.GetRole() == "OPT-PARTICIPANT" {
+			attendeeType = "optional"
+		}
+		if a.GetType() == "RESOURCE" || a.GetType() == "ROOM" {
+			attendeeType = "resource"
+		}
+		response := reverse(responses, []string{"accepted", "declined", "tentativelyAccepted"}, a.GetStatus())
+		if response == "" {
+			response = "none"
+		}
+		g.Attendees = append(g.Attendees, &Attendee{
+			EmailAddress: &EmailAddress{Name: a.GetName(), Address: a.GetEmail()},
+			Type:         attendeeType,
+			Status:       &ResponseStatus{Response: response},
+		})
+	}
+
+	if e.GetConference() != "" {
+		g.IsOnlineMeeting = true
+		g.OnlineMeeting = &OnlineMeeting{JoinURL: e.GetConference()}
+	}
+	return g, nil
+}
+
+// ======================== TIMES ===================
+
+// loads IANA and Windows time zones
+func loadLocation(name string) (*time.Location, error) {
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, nil
+	}
+	loc, err := wtz.LoadLocation(name)
+	if err != nil || loc == nil {
+		return nil, fmt.Errorf("unknown time zone %s", name)
+	}
+	return loc, nil
+}
+
+// returns the time and its TZID, whole days are kept at midnight UTC
+func (d *DateTimeTimeZone) time(wholeDay bool) (time.Time, string, error) {
+	if wholeDay {
+		t, err := time.Parse(parseLayout, d.DateTime)
+		if err != nil {
+			return t, "", err
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), "", nil
+	}
+	loc := time.UTC
+	tzid := ""
+	if d.TimeZone != "" && !strings.EqualFold(d.TimeZone, "UTC") {
+		var err error
+		if loc, err = loadLocation(d.TimeZone); err != nil {
+			return time.Time{}, "", err
+		}
+		tzid = d.TimeZone
+	}
+	t, err := time.ParseInLocation(parseLayout, d.DateTime, loc)
+	return t, tzid, err
+}
+
+func newDateTimeTimeZone(t time.Time, tzid string, wholeDay bool) *DateTimeTimeZone {
+	if wholeDay {
+		return &DateTimeTimeZone{DateTime: t.Format(dateLayout) + "T00:00:00.0000000", TimeZone: "UTC"}
+	}
+	if tzid != "" {
+		if loc, err := loadLocation(tzid); err == nil {
+			return &DateTimeTimeZone{DateTime: t.In(loc).Format(dateTimeLayout), TimeZone: tzid}
+		}
+	}
+	return &DateTimeTimeZone{DateTime: t.UTC().Format(dateTimeLayout), TimeZone: "UTC"}
+}
+
+// ======================== RECURRENCE ===================
+
+var weekdays = map[string]string{
+	"sunday":    "SU",
+	"monday":    "MO",
+	"tuesday":   "TU",
+	"wednesday": "WE",
+	"thursday":  "TH",
+	"friday":    "FR",
+	"saturday":  "SA",
+}
+
+var weekdayNames = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+var indexes = map[string]string{
+	"first":  "1",
+	"second": "2",
+	"third":  "3",
+	"fourth": "4",
+	"last":   "-1",
+}
+
+var indexNames = []string{"first", "second", "third", "fourth", "last"}
+
+// converts the pattern and range to a rule, start is the first occurrence
+func (r *Recurrence) rrule(start time.Time) (*ics.RRule, error) {
+	if r.Pattern == nil {
+		return nil, errors.New("recurrence has no pattern")
+	}
+	p := r.Pattern
+	rule := &ics.RRule{Interval: p.Interval}
+	days := []string{}
+	for _, day := range p.DaysOfWeek {
+		abbr, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day of week %s", day)
+		}
+		days = append(days, abbr)
+	}
+	switch p.Type {
+	case "daily":
+		rule.Freq = ics.FreqDaily
+	case "weekly":
+		rule.Freq = ics.FreqWeekly
+		rule.ByDay = days
+		if p.FirstDayOfWeek != "" {
+			rule.WeekStart = weekdays[strings.ToLower(p.FirstDayOfWeek)]
+		}
+	case "absoluteMonthly", "absoluteYearly":
+		rule.Freq = ics.FreqMonthly
+		rule.ByMonthDay = []int{p.DayOfMonth}
+	case "relativeMonthly", "relativeYearly":
+		rule.Freq = ics.FreqMonthly
+		index := indexes[p.Index]
+		if p.Index == "" {
+			index = "1"
+		}
+		for _, day := range days {
+			rule.ByDay = append(rule.ByDay, index+day)
+		}
+	default:
+		return nil, fmt.Errorf("unknown recurrence pattern %s", p.Type)
+	}
+	if strings.HasSuffix(p.Type, "Yearly") {
+		rule.Freq = ics.FreqYearly
+		rule.ByMonth = []int{p.Month}
+	}
+
+	if r.Range != nil {
+		switch r.Range.Type {
+		case "endDate":
+			end, err := time.Parse(dateLayout, r.Range.EndDate)
+			if err != nil {
+				return nil, err
+			}
+			// the series ends with the last second of the end date
+			rule.Until = time.Date(end.Year(), end.Month(), end.Day(), 23, 59, 59, 0, start.Location())
+		case "numbered":
+			rule.Count = r.Range.NumberOfOccurrences
+		}
+	}
+	return rule, rule.Validate()
+}
+
+// converts a rule to the pattern and range of a series starting at start
+func newRecurrence(rule *ics.RRule, start time.Time, tzid string) (*Recurrence, error) {
+	interval := rule.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	p := &RecurrencePattern{Interval: interval}
+	index := ""
+	for _, day := range rule.ByDay {
+		abbr := day[len(day)-2:]
+		for _, name := range weekdayNames {
+			if weekdays[name] == abbr {
+				p.DaysOfWeek = append(p.DaysOfWeek, name)
+			}
+		}
+		for _, name := range indexNames {
+			if indexes[name] == strings.TrimPrefix(day[:len(day)-2], "+") {
+				index = name
+			}
+		}
+	}
+	switch rule.Freq {
+	case ics.FreqDaily:
+		p.Type = "daily"
+	case ics.FreqWeekly:
+		p.Type = "weekly"
+		if len(p.DaysOfWeek) == 0 {
+			p.DaysOfWeek = []string{weekdayNames[start.Weekday()]}
+		}
+		if rule.WeekStart != "" {
+			p.FirstDayOfWeek = reverse(weekdays, weekdayNames, rule.WeekStart)
+		}
+	case ics.FreqMonthly, ics.FreqYearly:
+		kind := "Monthly"
+		if rule.Freq == ics.FreqYearly {
+			kind = "Yearly"
+			p.Month = int(start.Month())
+			if len(rule.ByMonth) > 0 {
+				p.Month = rule.ByMonth[0]
+			}
+		}
+		if index != "" {
+			p.Type = "relative" + kind
+			p.Index = index
+		} else {
+			p.Type = "absolute" + kind
+			p.DaysOfWeek = nil
+			p.DayOfMonth = start.Day()
+			if len(rule.ByMonthDay) > 0 {
+				p.DayOfMonth = rule.ByMonthDay[0]
+			}
+		}
+	default:
+		return nil, fmt.Errorf("recurrence %s is not supported by Microsoft Graph", rule.Freq)
+	}
+
+	r := &RecurrenceRange{Type: "noEnd", StartDate: start.Format(dateLayout), RecurrenceTimeZone: tzid}
+	if tzid != "" {
+		if loc, err := loadLocation(tzid); err == nil {
+			r.StartDate = start.In(loc).Format(dateLayout)
+		}
+	}
+	switch {
+	case rule.Count > 0:
+		r.Type = "numbered"
+		r.NumberOfOccurrences = rule.Count
+	case !rule.Until.IsZero():
+		r.Type = "endDate"
+		until := rule.Until
+		if tzid != "" {
+			if loc, err := loadLocation(tzid); err == nil {
+				until = until.In(loc)
+			}
+		}
+		r.EndDate = until.Format(dateLayout)
+	}
+	return &Recurrence{Pattern: p, Range: r}, nil
+}
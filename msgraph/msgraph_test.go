@@ -0,0 +1,180 @@
+package msgraph
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func TestReadFile(t *testing.T) {
+	cal, err := ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatalf("Failed to read the dump ( %s )", err)
+	}
+	events := cal.GetEvents()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, found %d", len(events))
+	}
+
+	review := events[0]
+	if review.GetImportedID() != "040000008200E00074C5B7101A82E00800000000" {
+		t.Errorf("Expected the iCalUId as UID, found %s", review.GetImportedID())
+	}
+	start := time.Date(2024, 6, 10, 7, 0, 0, 0, time.UTC)
+	if !review.GetStart().Equal(start) || review.GetStartTZID() != "Romance Standard Time" {
+		t.Errorf("Expected start %s in Romance Standard Time, found %s in %s", start, review.GetStart(), review.GetStartTZID())
+	}
+	if review.GetDescription() != "Bring the slides & notes\nSee you" {
+		t.Errorf("Expected the text of the html body, found %q", review.GetDescription())
+	}
+	if review.GetBusyStatus() != "BUSY" || review.GetClass() != "" {
+		t.Errorf("Expected BUSY without class, found %s %s", review.GetBusyStatus(), review.GetClass())
+	}
+	if review.GetRRule() != "FREQ=MONTHLY;UNTIL=20241231T225959Z;BYDAY=2MO" {
+		t.Errorf("Expected the monthly rrule, found %s", review.GetRRule())
+	}
+	if review.GetConference() != "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc" {
+		t.Errorf("Expected the Teams link as conference, found %s", review.GetConference())
+	}
+	attendees := review.GetAttendees()
+	if len(attendees) != 3 {
+		t.Fatalf("Expected 3 attendees, found %d", len(attendees))
+	}
+	for i, expected := range [][3]string{
+		{"ACCEPTED", "REQ-PARTICIPANT", "INDIVIDUAL"},
+		{"TENTATIVE", "OPT-PARTICIPANT", "INDIVIDUAL"},
+		{"NEEDS-ACTION", "NON-PARTICIPANT", "RESOURCE"},
+	} {
+		a := attendees[i]
+		if a.GetStatus() != expected[0] || a.GetRole() != expected[1] || a.GetType() != expected[2] {
+			t.Errorf("Expected attendee %d to be %v, found %s %s %s", i, expected, a.GetStatus(), a.GetRole(), a.GetType())
+		}
+	}
+
+	offsite := events[1]
+	if !offsite.IsWholeDay() || !offsite.GetStart().Equal(time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected whole day event on 2024-06-20, found %s", offsite.GetStart())
+	}
+	if offsite.GetStatus() != "CANCELLED" || offsite.GetClass() != "PRIVATE" || offsite.GetBusyStatus() != "OOF" {
+		t.Errorf("Expected CANCELLED PRIVATE OOF, found %s %s %s", offsite.GetStatus(), offsite.GetClass(), offsite.GetBusyStatus())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cal, err := ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatalf("Failed to read the dump ( %s )", err)
+	}
+	dir, err := ioutil.TempDir("", "msgraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.json")
+	if err := WriteFile(path, cal); err != nil {
+		t.Fatalf("Failed to write the dump ( %s )", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	for _, fragment := range []string{
+		`"dateTime": "2024-06-10T09:00:00.0000000"`,
+		`"timeZone": "Romance Standard Time"`,
+		`"type": "relativeMonthly"`,
+		`"index": "second"`,
+		`"endDate": "2024-12-31"`,
+		`"response": "tentativelyAccepted"`,
+		`"joinUrl": "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc"`,
+		`"isAllDay": true`,
+	} {
+		if !strings.Contains(string(data), fragment) {
+			t.Errorf("Expected the dump to contain %s, found:\n%s", fragment, data)
+		}
+	}
+
+	back, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the written dump ( %s )", err)
+	}
+	if back.Serialize() != cal.Serialize() {
+		t.Errorf("Expected the same calendar after the round trip:\n%s\nfound:\n%s", cal.Serialize(), back.Serialize())
+	}
+}
+
+func TestRecurrencePatterns(t *testing.T) {
+	start := time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		rule     string
+		pattern  RecurrencePattern
+		expected string
+	}{
+		{"FREQ=DAILY;INTERVAL=2", RecurrencePattern{Type: "daily", Interval: 2}, "FREQ=DAILY;INTERVAL=2"},
+		{"FREQ=WEEKLY;BYDAY=MO,WE", RecurrencePattern{Type: "weekly", Interval: 1, DaysOfWeek: []string{"monday", "wednesday"}}, "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"FREQ=MONTHLY", RecurrencePattern{Type: "absoluteMonthly", Interval: 1, DayOfMonth: 15}, "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", RecurrencePattern{Type: "relativeMonthly", Interval: 1, DaysOfWeek: []string{"friday"}, Index: "last"}, "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=YEARLY;COUNT=3", RecurrencePattern{Type: "absoluteYearly", Interval: 1, DayOfMonth: 15, Month: 3}, "FREQ=YEARLY;COUNT=3;BYMONTHDAY=15;BYMONTH=3"},
+		{"FREQ=YEARLY;BYDAY=1SU;BYMONTH=11", RecurrencePattern{Type: "relativeYearly", Interval: 1, DaysOfWeek: []string{"sunday"}, Index: "first", Month: 11}, "FREQ=YEARLY;BYDAY=1SU;BYMONTH=11"},
+	} {
+		event := ics.NewEvent().SetStart(start).SetEnd(start.Add(time.Hour)).SetRRule(test.rule)
+		g, err := FromEvent(event)
+		if err != nil {
+			t.Errorf("Failed to convert %s ( %s )", test.rule, err)
+			continue
+		}
+		p, expected := g.Recurrence.Pattern, test.pattern
+		if p.Type != expected.Type || p.Interval != expected.Interval || p.DayOfMonth != expected.DayOfMonth ||
+			p.Month != expected.Month || p.Index != expected.Index || strings.Join(p.DaysOfWeek, ",") != strings.Join(expected.DaysOfWeek, ",") {
+			t.Errorf("Expected %s to be %+v, found %+v", test.rule, expected, *p)
+		}
+
+		back, err := g.ToEvent()
+		if err != nil {
+			t.Errorf("Failed to convert %s back ( %s )", test.rule, err)
+			continue
+		}
+		if back.GetRRule() != test.expected {
+			t.Errorf("Expected %s after the round trip, found %s", test.expected, back.GetRRule())
+		}
+	}
+
+	if _, err := FromEvent(ics.NewEvent().SetRRule("FREQ=HOURLY")); err == nil {
+		t.Errorf("Expected error for hourly rrule")
+	}
+	events, _ := Decode(bytes.NewBufferString(`{"start": {"dateTime": "2024-06-10T09:00:00", "timeZone": "Nowhere"}}`))
+	if _, err := events.ToCalendar(); err == nil {
+		t.Errorf("Expected error for unknown time zone")
+	}
+}
+
+func TestFromEventTexts(t *testing.T) {
+	// the texts of the events that were never escaped used to lose their backslashes
+	built := ics.NewEvent().SetSummary(`regex \d+\n`).SetDescription(`a\b`).SetLocation(`C:\rooms`)
+	parser := ics.New()
+	parser.Load("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:texts@example.com\r\nDTSTART:20240610T090000Z\r\n" +
+		"SUMMARY:regex \\\\d+\\\\n\r\nDESCRIPTION:Bring the slides\\, please\r\nLOCATION:a\\\\b\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 || len(calendars[0].GetEvents()) != 1 {
+		t.Fatalf("Expected 1 parsed event")
+	}
+	parsed := calendars[0].GetEvents()[0]
+	for _, test := range []struct {
+		event                          *ics.Event
+		summary, description, location string
+	}{
+		{built, `regex \d+\n`, `a\b`, `C:\rooms`},
+		{&parsed, `regex \d+\n`, "Bring the slides, please", `a\b`},
+	} {
+		g, err := FromEvent(test.event)
+		if err != nil {
+			t.Fatalf("Failed to convert the event ( %s )", err)
+		}
+		if g.Subject != test.summary || g.Body.Content != test.description || g.Location.DisplayName != test.location {
+			t.Errorf("Expected %q, %q and %q, found %q, %q and %q", test.summary, test.description, test.location, g.Subject, g.Body.Content, g.Location.DisplayName)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -39,6 +38,7 @@ func init() {
 
 type Parser struct {
 	inputChan       chan string
+	syncChan        chan struct{}
 	outputChan      chan *Event
 	bufferedChan    chan *Event
 	errorsOccured   []error
@@ -46,18 +46,25 @@ type Parser struct {
 	parsedEvents    []*Event
 	statusCalendars int
 	wg              *sync.WaitGroup
+	fetchers        map[string]Fetcher
+	health          map[string]*Health
+	limits          Limits
+	policy          *FetchPolicy
+	fetchMutex      sync.Mutex
 }
 
 // creates new parser
 func New() *Parser {
 	p := new(Parser)
 	p.inputChan = make(chan string)
+	p.syncChan = make(chan struct{})
 	p.outputChan = make(chan *Event)
 	p.bufferedChan = make(chan *Event)
 	p.errorsOccured = []error{}
 	p.wg = new(sync.WaitGroup)
 	p.parsedCalendars = []*Calendar{}
 	p.parsedEvents = []*Event{}
+	p.fetchers = defaultFetchers(nil)
 
 	// buffers the events output chan
 	go func() {
@@ -79,39 +86,18 @@ func New() *Parser {
 	go func(input chan string) {
 		// endless loop for getting the ics urls
 		for {
-			link := <-input
-
-			// mark calendar in the wait group as not parsed
-			p.wg.Add(1)
-
-			// marks that we have statusCalendars +1 calendars to be parsed
-			mutex.Lock()
-			p.statusCalendars++
-			mutex.Unlock()
+			var link string
+			select {
+			case link = <-input:
+			case <-p.syncChan:
+				// every url sent before is in the wait group
+				continue
+			}
 
+			p.startParsing()
 			go func(link string) {
-				// mark calendar in the wait group as  parsed
-				defer p.wg.Done()
-
-				iCalContent, err := p.getICal(link)
-				if err != nil {
-					p.errorsOccured = append(p.errorsOccured, err)
-
-					mutex.Lock()
-					// marks that we have parsed 1 calendar and we have statusCalendars -1 left to be parsed
-					p.statusCalendars--
-					mutex.Unlock()
-					return
-				}
-
-				// parse the ICal calendar
-				p.parseICalContent(iCalContent, link)
-
-				mutex.Lock()
-				// marks that we have parsed 1 calendar and we have statusCalendars -1 left to be parsed
-				p.statusCalendars--
-				mutex.Unlock()
-
+				defer p.doneParsing()
+				p.parseLink(link, p.getICal)
 			}(link)
 		}
 	}(p.inputChan)
@@ -158,78 +144,248 @@ func (p *Parser) Done() bool {
 
 // wait until everything is parsed
 func (p *Parser) Wait() {
+	// the urls received are added to the wait group after the send returns
+	p.syncChan <- struct{}{}
 	p.wg.Wait()
 }
 
-//  get the data from the calendar
-func (p *Parser) getICal(url string) (string, error) {
-	re, _ := regexp.Compile(`http(s){0,1}:\/\/`)
+//  get the calendars of the url with the fetcher of its scheme, and if they
+//  are not modified since the last fetch
+func (p *Parser) getICal(url string) ([]icalFile, bool, error) {
+	fetcher, err := p.fetcherOf(url)
+	if err != nil {
+		return nil, false, err
+	}
+	return p.readICal(fetcher, url)
+}
 
-	var fileName string
-	var errDownload error
+// reads the calendars of the url with the fetcher, and whether they are not
+// modified since they were read before
+func (p *Parser) readICal(fetcher Fetcher, url string) ([]icalFile, bool, error) {
+	body, err := fetcher.Fetch(url)
+	if err != nil {
+		return nil, false, err
+	}
+	defer body.Close()
 
-	if re.FindString(url) != "" {
-		// download the file and store it local
-		fileName, errDownload = downloadFromUrl(url)
+	//  read the ical data
+	maxBytes := p.GetLimits().MaxBytes
+	fileContent, errRead := ioutil.ReadAll(limitBody(body, maxBytes, p.redact(url)))
 
-		if errDownload != nil {
-			return "", errDownload
-		}
+	if errRead != nil {
+		return nil, false, errRead
+	}
 
-	} else { //  use a file from local storage
+	notModified := false
+	if n, ok := body.(interface{ NotModified() bool }); ok {
+		notModified = n.NotModified()
+	}
 
-		//  check if file exists
-		if fileExists(url) {
-			fileName = url
-		} else {
-			err := fmt.Sprintf("File %s does not exists", url)
-			return "", errors.New(err)
-		}
+	//  unpack the gzip and zip files
+	files, err := unpackICal(fileContent, maxBytes, p.redact(url))
+	if s, ok := body.(interface{ Stale() error }); ok && err == nil && s.Stale() != nil {
+		return files, false, &staleError{s.Stale()}
 	}
+	return files, notModified, err
+}
 
-	//  read the file with the ical data
-	fileContent, errReadFile := ioutil.ReadFile(fileName)
+// staleError is the failure of the origin of an url read from a stale copy,
+// the copy is parsed and the failure is recorded in the health of the url
+type staleError struct {
+	err error
+}
 
-	if errReadFile != nil {
-		return "", errReadFile
+func (e *staleError) Error() string {
+	return e.err.Error()
+}
+
+// parses the calendars of the link read with getICal, the parsed calendars
+// and the errors are kept in the parser
+func (p *Parser) parseLink(link string, getICal func(string) ([]icalFile, bool, error)) {
+	files, notModified, err := getICal(link)
+	p.recordHealth(link, err)
+	_, stale := err.(*staleError)
+	if err != nil && !stale {
+		p.addError(err)
+		return
 	}
 
-	if DeleteTempFiles && re.FindString(url) != "" {
-		os.Remove(fileName)
+	// parse the ICal calendars unless they are the ones parsed before, a
+	// stale copy is the last one read
+	if !notModified && !stale || !p.hasCalendar(link) {
+		for _, file := range files {
+			if cal := p.parseICalContent(file.content, link); cal != nil {
+				cal.SetArchivePath(file.path)
+			}
+		}
 	}
+}
+
+// marks a calendar in the wait group as not parsed, and that we have
+// statusCalendars +1 calendars to be parsed
+func (p *Parser) startParsing() {
+	p.wg.Add(1)
+	mutex.Lock()
+	p.statusCalendars++
+	mutex.Unlock()
+}
 
-	return fmt.Sprintf("%s", fileContent), nil
+// marks a calendar of startParsing as parsed, and that we have
+// statusCalendars -1 left to be parsed
+func (p *Parser) doneParsing() {
+	mutex.Lock()
+	p.statusCalendars--
+	mutex.Unlock()
+	p.wg.Done()
+}
+
+// is a calendar parsed from the url
+func (p *Parser) hasCalendar(url string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, cal := range p.parsedCalendars {
+		if cal.GetUrl() == url {
+			return true
+		}
+	}
+	return false
 }
 
 // ======================== CALENDAR PARSING ===================
 
-// parses the iCal formated string to a calendar object
-func (p *Parser) parseICalContent(iCalContent, url string) {
-	ical := NewCalendar()
+// parses the iCal formated string to a calendar object, a calendar over
+// the limits of the parser is left out and nil is returned
+func (p *Parser) parseICalContent(iCalContent, url string) *Calendar {
+	ical, events, err := p.parseCalendar(iCalContent, url)
+	if err != nil {
+		p.addError(err)
+		return nil
+	}
+
+	mutex.Lock()
 	p.parsedCalendars = append(p.parsedCalendars, ical)
+	mutex.Unlock()
+	for _, event := range events {
+		p.bufferedChan <- event
+	}
+	return ical
+}
+
+// parses the calendar and its events without adding them to the parser
+func (p *Parser) parseCalendar(iCalContent, url string) (*Calendar, []*Event, error) {
+	limits := p.GetLimits()
+	deadline := time.Now().Add(limits.ParseTimeout)
+	// the url of the limit errors
+	redactedURL := p.redact(url)
+	if err := limits.checkContent(iCalContent, redactedURL); err != nil {
+		return nil, nil, err
+	}
+	ical := NewCalendar()
+	ical.escaped = true
+
+	checkDeadline := func() error {
+		return limits.checkDeadline(deadline, redactedURL)
+	}
 
 	// split the data into calendar info and events data
-	eventsData, calInfo := explodeICal(iCalContent)
+	unfolded := unfoldICal(iCalContent)
+	if err := checkDeadline(); err != nil {
+		return nil, nil, err
+	}
+	eventsData, calInfo, err := explodeICal(unfolded, checkDeadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	mutex.Lock()
 	idCounter++
+	mutex.Unlock()
 
 	// fill the calendar fields
 	ical.SetName(p.parseICalName(calInfo))
 	ical.SetDesc(p.parseICalDesc(calInfo))
 	ical.SetVersion(p.parseICalVersion(calInfo))
 	ical.SetTimezone(p.parseICalTimezone(calInfo))
+	ical.SetMethod(p.parseICalMethod(calInfo))
+	ical.SetRefreshInterval(p.parseICalRefreshInterval(calInfo))
 	ical.SetUrl(url)
+	if err := checkDeadline(); err != nil {
+		return nil, nil, err
+	}
 
 	// parse the events and add them to ical
-	p.parseEvents(ical, eventsData)
+	events, err := p.parseEvents(ical, eventsData, limits, deadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ical, events, nil
+}
 
+// adds the error to the errors of the parser
+func (p *Parser) addError(err error) {
+	mutex.Lock()
+	p.errorsOccured = append(p.errorsOccured, err)
+	mutex.Unlock()
 }
 
-// explodes the ICal content to array of events and calendar info
-func explodeICal(iCalContent string) ([]string, string) {
-	reEvents, _ := regexp.Compile(`(BEGIN:VEVENT(.*\n)*?END:VEVENT\r?\n)`)
-	allEvents := reEvents.FindAllString(iCalContent, len(iCalContent))
-	calInfo := reEvents.ReplaceAllString(iCalContent, "")
-	return allEvents, calInfo
+// joins the folded content lines
+func unfoldICal(iCalContent string) string {
+	re, _ := regexp.Compile(`\r?\n[ \t]`)
+	return re.ReplaceAllString(iCalContent, "")
+}
+
+// explodes the ICal content to array of events and calendar info, the
+// error of check stops it after an event
+func explodeICal(iCalContent string, check func() error) ([]string, string, error) {
+	events := []string{}
+	calInfo := []string{}
+	rest := iCalContent
+	// without an END:VEVENT line after a BEGIN:VEVENT only the events ending
+	// on their BEGIN:VEVENT line are left
+	ended := true
+	for {
+		start := strings.Index(rest, "BEGIN:VEVENT")
+		if start < 0 {
+			break
+		}
+		end := eventEnd(rest[start:], ended)
+		if end < 0 {
+			ended = false
+			calInfo = append(calInfo, rest[:start+len("BEGIN:VEVENT")])
+			rest = rest[start+len("BEGIN:VEVENT"):]
+			continue
+		}
+		calInfo = append(calInfo, rest[:start])
+		events = append(events, rest[start:start+end])
+		rest = rest[start+end:]
+		if err := check(); err != nil {
+			return nil, "", err
+		}
+	}
+	calInfo = append(calInfo, rest)
+	return events, strings.Join(calInfo, ""), nil
+}
+
+// returns the length of the event starting the content up to the newline
+// of its END:VEVENT line, -1 if it does not end. The END:VEVENT is looked
+// for on the next lines when lines is true, else only after BEGIN:VEVENT.
+func eventEnd(content string, lines bool) int {
+	from := len("BEGIN:VEVENT")
+	for {
+		if strings.HasPrefix(content[from:], "END:VEVENT") {
+			end := from + len("END:VEVENT")
+			if strings.HasPrefix(content[end:], "\r\n") {
+				return end + 2
+			}
+			if strings.HasPrefix(content[end:], "\n") {
+				return end + 1
+			}
+		}
+		i := strings.IndexByte(content[from:], '\n')
+		if i < 0 || !lines {
+			return -1
+		}
+		from += i + 1
+	}
 }
 
 // parses the iCal Name
@@ -255,6 +411,30 @@ func (p *Parser) parseICalVersion(iCalContent string) float64 {
 	return ver
 }
 
+// parses the iCal method
+func (p *Parser) parseICalMethod(iCalContent string) string {
+	re, _ := regexp.Compile(`METHOD:.*?\n`)
+	result := re.FindString(iCalContent)
+	return trimField(result, "METHOD:")
+}
+
+// parses the REFRESH-INTERVAL (RFC 7986) or the X-PUBLISHED-TTL of the calendar
+func (p *Parser) parseICalRefreshInterval(iCalContent string) time.Duration {
+	re, _ := regexp.Compile(`(REFRESH-INTERVAL[^:\n]*|X-PUBLISHED-TTL):(.*?)\r?\n`)
+	found := map[string]string{}
+	for _, match := range re.FindAllStringSubmatch(iCalContent, -1) {
+		found[strings.SplitN(match[1], ";", 2)[0]] = match[2]
+	}
+	for _, name := range []string{"REFRESH-INTERVAL", "X-PUBLISHED-TTL"} {
+		if value, ok := found[name]; ok {
+			if parsed, err := duration.FromString(value); err == nil {
+				return parsed.ToDuration()
+			}
+		}
+	}
+	return 0
+}
+
 // parses the iCal timezone
 func (p *Parser) parseICalTimezone(iCalContent string) time.Location {
 	re, _ := regexp.Compile(`X-WR-TIMEZONE:.*?\n`)
@@ -267,7 +447,9 @@ func (p *Parser) parseICalTimezone(iCalContent string) time.Location {
 
 	// if fails with the timezone => go Local
 	if err != nil {
+		mutex.Lock()
 		p.errorsOccured = append(p.errorsOccured, err)
+		mutex.Unlock()
 		loc, _ = time.LoadLocation("UTC")
 	}
 	return *loc
@@ -275,11 +457,31 @@ func (p *Parser) parseICalTimezone(iCalContent string) time.Location {
 
 // ======================== EVENTS PARSING ===================
 
-// parses the iCal events Data
-func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
+// parses the events, adds them to the calendar and returns them for the
+// output chan
+func (p *Parser) parseEvents(cal *Calendar, eventsData []string, limits Limits, deadline time.Time) ([]*Event, error) {
+	events := []*Event{}
+	// the url of the limit errors
+	url := p.redact(cal.GetUrl())
+	// the events created by the repeat rules
+	instances := 0
+	addInstance := func(e Event) error {
+		instances++
+		if limits.MaxInstances > 0 && instances > limits.MaxInstances {
+			return newLimitError("MaxInstances", int64(limits.MaxInstances), url)
+		}
+		cal.SetEvent(e)
+		return nil
+	}
 
 	for _, eventData := range eventsData {
+		if err := limits.checkDeadline(deadline, url); err != nil {
+			return nil, err
+		}
 		event := NewEvent()
+		event.escaped = true
+		// the alarms are parsed apart so their properties don't mix with the event ones
+		alarmsData, eventData := explodeAlarms(eventData)
 		start, startTZID := p.parseEventStart(eventData)
 		end, endTZID := p.parseEventEnd(eventData)
 		duration := p.parseEventDuration(eventData)
@@ -302,9 +504,12 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 		event.SetSequence(p.parseEventSequence(eventData))
 		event.SetCreated(p.parseEventCreated(eventData))
 		event.SetLastModified(p.parseEventModified(eventData))
+		event.SetDTStamp(p.parseEventDTStamp(eventData))
+		event.SetRecurrenceID(p.parseEventRecurrenceID(eventData))
 		event.SetRRule(p.parseEventRRule(eventData))
 		event.SetLocation(p.parseEventLocation(eventData))
 		event.SetGeo(p.parseEventGeo(eventData))
+		event.SetConference(p.parseEventConference(eventData))
 		event.SetStart(start)
 		event.SetEnd(end)
 		event.SetWholeDayEvent(wholeDay)
@@ -312,9 +517,12 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 		event.SetOrganizer(p.parseEventOrganizer(eventData))
 		event.SetCalendar(cal)
 		event.SetID(event.GenerateEventId())
+		for _, alarmData := range alarmsData {
+			event.AddAlarm(p.parseAlarm(alarmData))
+		}
 
 		cal.SetEvent(*event)
-		p.bufferedChan <- event
+		events = append(events, event)
 
 		if RepeatRuleApply && event.GetRRule() != "" {
 
@@ -345,6 +553,9 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 			count, _ := strconv.Atoi(countString)
 			if count == 0 {
 				count = MaxRepeats
+			} else {
+				// the count includes the first event
+				count--
 			}
 
 			// freq field
@@ -395,6 +606,9 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 
 			// loops by freq
 			for {
+				if err := limits.checkDeadline(deadline, url); err != nil {
+					return nil, err
+				}
 				weekDaysStart := freqDateStart
 				weekDaysEnd := freqDateEnd
 
@@ -404,6 +618,10 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 					if byday != "" {
 						// loops the weekdays
 						for i := 0; i < 7; i++ {
+							// the count runs out inside the week
+							if count <= 0 {
+								break
+							}
 							day := parseDayNameToIcsName(weekDaysStart.Format("Mon"))
 							if strings.Contains(byday, day) && weekDaysStart != start {
 								current++
@@ -414,7 +632,9 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 								newE.SetID(newE.GenerateEventId())
 								newE.SetSequence(current)
 								if until == nil || (until != nil && until.Format(YmdHis) >= weekDaysStart.Format(YmdHis)) {
-									cal.SetEvent(newE)
+									if err := addInstance(newE); err != nil {
+										return nil, err
+									}
 								}
 
 							}
@@ -432,7 +652,9 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 							newE.SetID(newE.GenerateEventId())
 							newE.SetSequence(current)
 							if until == nil || (until != nil && until.Format(YmdHis) >= weekDaysStart.Format(YmdHis)) {
-								cal.SetEvent(newE)
+								if err := addInstance(newE); err != nil {
+									return nil, err
+								}
 							}
 
 						}
@@ -442,7 +664,7 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 
 				freqDateStart = freqDateStart.AddDate(years, months, days)
 				freqDateEnd = freqDateEnd.AddDate(years, months, days)
-				if current > MaxRepeats || count == 0 {
+				if current > MaxRepeats || count <= 0 {
 					break
 				}
 
@@ -453,6 +675,7 @@ func (p *Parser) parseEvents(cal *Calendar, eventsData []string) {
 
 		}
 	}
+	return events, nil
 }
 
 // parses the event summary
@@ -523,6 +746,15 @@ func (p *Parser) parseEventModified(eventData string) time.Time {
 	return t
 }
 
+// parses the event dtstamp
+func (p *Parser) parseEventDTStamp(eventData string) time.Time {
+	re, _ := regexp.Compile(`DTSTAMP:.*?\n`)
+	result := re.FindString(eventData)
+	stamp := trimField(result, "DTSTAMP:")
+	t, _ := time.Parse(IcsFormat, stamp)
+	return t
+}
+
 // parses the event start time
 func (p *Parser) parseTimeField(fieldName string, eventData string) (time.Time, string) {
 	reWholeDay, _ := regexp.Compile(fmt.Sprintf(`%s;VALUE=DATE:.*?\n`, fieldName))
@@ -536,6 +768,7 @@ func (p *Parser) parseTimeField(fieldName string, eventData string) (time.Time,
 		// whole day event
 		modified := trimField(resultWholeDay, fmt.Sprintf("%s;VALUE=DATE:", fieldName))
 		t, _ = time.Parse(IcsFormatWholeDay, modified)
+		ut = t
 	} else {
 		// event that has start hour and minute
 		result := re.FindStringSubmatch(eventData)
@@ -551,11 +784,16 @@ func (p *Parser) parseTimeField(fieldName string, eventData string) (time.Time,
 			newloc, newlocerr := wtz.LoadLocation(tzID)
 			if newlocerr != nil {
 				loc = time.UTC
+			} else {
+				loc = newloc
 			}
-			loc = newloc
 		}
 
-		localTime, _ := time.ParseInLocation(dateTimeLayoutLocalized, dt, loc)
+		// UTC times end with Z
+		if strings.HasSuffix(dt, "Z") {
+			loc = time.UTC
+		}
+		localTime, _ := time.ParseInLocation(dateTimeLayoutLocalized, strings.TrimSuffix(dt, "Z"), loc)
 		utcTime := localTime.UTC().Format(dateTimeLayoutLocalized)
 
 		if !strings.Contains(dt, "Z") {
@@ -583,6 +821,12 @@ func (p *Parser) parseEventEnd(eventData string) (time.Time, string) {
 	return p.parseTimeField("DTEND", eventData)
 }
 
+// parses the recurrence id of a single occurrence
+func (p *Parser) parseEventRecurrenceID(eventData string) time.Time {
+	t, _ := p.parseTimeField("RECURRENCE-ID", eventData)
+	return t
+}
+
 func (p *Parser) parseEventDuration(eventData string) time.Duration {
 	reDuration, _ := regexp.Compile(`DURATION:.*?\n`)
 	result := reDuration.FindString(eventData)
@@ -625,6 +869,58 @@ func (p *Parser) parseEventGeo(eventData string) *Geo {
 	return NewGeo(values[0], values[1])
 }
 
+// parses the event CONFERENCE
+func (p *Parser) parseEventConference(eventData string) string {
+	re, _ := regexp.Compile(`CONFERENCE[;:].*?\n`)
+	result := strings.TrimSpace(re.FindString(eventData))
+	if result == "" {
+		return ""
+	}
+	// the parameters can be quoted and the uri has colons
+	prop, err := parseContentLine(result)
+	if err != nil {
+		return ""
+	}
+	return prop.value
+}
+
+// ======================== ALARM PARSING ===================
+
+// explodes the event data to array of alarms and event properties
+func explodeAlarms(eventData string) ([]string, string) {
+	reAlarms, _ := regexp.Compile(`(BEGIN:VALARM(.*\n)*?END:VALARM\r?\n)`)
+	allAlarms := reAlarms.FindAllString(eventData, len(eventData))
+	eventInfo := reAlarms.ReplaceAllString(eventData, "")
+	return allAlarms, eventInfo
+}
+
+// parses the VALARM properties
+func (p *Parser) parseAlarm(alarmData string) *Alarm {
+	a := NewAlarm()
+	reAction, _ := regexp.Compile(`ACTION:.*?\n`)
+	if action := trimField(reAction.FindString(alarmData), "ACTION:"); action != "" {
+		a.SetAction(action)
+	}
+	reDesc, _ := regexp.Compile(`DESCRIPTION:.*?\n`)
+	a.SetDescription(trimField(reDesc.FindString(alarmData), "DESCRIPTION:"))
+	reSumm, _ := regexp.Compile(`SUMMARY:.*?\n`)
+	a.SetSummary(trimField(reSumm.FindString(alarmData), "SUMMARY:"))
+
+	// only triggers relative to the start are supported
+	reTrigger, _ := regexp.Compile(`TRIGGER(;RELATED=START)?:.*?\n`)
+	trigger := trimField(reTrigger.FindString(alarmData), `TRIGGER(;RELATED=START)?:`)
+	negative := strings.HasPrefix(trigger, "-")
+	parsedDuration, err := duration.FromString(strings.TrimLeft(trigger, "+-"))
+	if err == nil {
+		d := parsedDuration.ToDuration()
+		if negative {
+			d = -d
+		}
+		a.SetTrigger(d)
+	}
+	return a
+}
+
 // ======================== ATTENDEE PARSING ===================
 
 // parses the event attendees
@@ -672,6 +968,7 @@ func (p *Parser) parseAttendee(attendeeData string) *Attendee {
 	a.SetRole(p.parseAttendeeRole(attendeeData))
 	a.SetStatus(p.parseAttendeeStatus(attendeeData))
 	a.SetType(p.parseAttendeeType(attendeeData))
+	a.SetRSVP(p.parseAttendeeRSVP(attendeeData))
 	return a
 }
 
@@ -684,33 +981,39 @@ func (p *Parser) parseAttendeeMail(attendeeData string) string {
 
 // parses the attendee status
 func (p *Parser) parseAttendeeStatus(attendeeData string) string {
-	re, _ := regexp.Compile(`PARTSTAT=.*?;`)
+	re, _ := regexp.Compile(`PARTSTAT=[^;:]*[;:]`)
 	result := re.FindString(attendeeData)
 	if result == "" {
 		return ""
 	}
-	return trimField(result, `(PARTSTAT=|;)`)
+	return trimField(result, `(PARTSTAT=|;|:)`)
+}
+
+// parses the attendee rsvp
+func (p *Parser) parseAttendeeRSVP(attendeeData string) bool {
+	re, _ := regexp.Compile(`RSVP=TRUE[;:]`)
+	return re.FindString(attendeeData) != ""
 }
 
 // parses the attendee role
 func (p *Parser) parseAttendeeRole(attendeeData string) string {
-	re, _ := regexp.Compile(`ROLE=.*?;`)
+	re, _ := regexp.Compile(`ROLE=[^;:]*[;:]`)
 	result := re.FindString(attendeeData)
 
 	if result == "" {
 		return ""
 	}
-	return trimField(result, `(ROLE=|;)`)
+	return trimField(result, `(ROLE=|;|:)`)
 }
 
 // parses the attendee Name
 func (p *Parser) parseAttendeeName(attendeeData string) string {
-	re, _ := regexp.Compile(`CN=.*?;`)
+	re, _ := regexp.Compile(`CN=[^;:]*[;:]`)
 	result := re.FindString(attendeeData)
 	if result == "" {
 		return ""
 	}
-	return trimField(result, `(CN=|;)`)
+	return trimField(result, `(CN=|;|:|")`)
 }
 
 // parses the organizer Name
@@ -725,10 +1028,10 @@ func (p *Parser) parseOrganizerName(orgData string) string {
 
 // parses the attendee type
 func (p *Parser) parseAttendeeType(attendeeData string) string {
-	re, _ := regexp.Compile(`CUTYPE=.*?;`)
+	re, _ := regexp.Compile(`CUTYPE=[^;:]*[;:]`)
 	result := re.FindString(attendeeData)
 	if result == "" {
 		return ""
 	}
-	return trimField(result, `(CUTYPE=|;)`)
+	return trimField(result, `(CUTYPE=|;|:)`)
 }
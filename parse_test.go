@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -723,3 +724,268 @@ func TestPagerDutyCalendarEventTimes(t *testing.T) {
 		t.Fatalf("End should be %s, but was %s", expectedEnd, end)
 	}
 }
+
+func crlf(lines ...string) string {
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestEventTimeZones(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:sofia@example.com",
+		"DTSTART;TZID=Europe/Sofia:20240610T090000",
+		"DTEND;TZID=Europe/Sofia:20240610T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:unknown@example.com",
+		"DTSTART;TZID=Unknown/Zone:20240611T090000",
+		"DTEND;TZID=Unknown/Zone:20240611T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	events := calendars[0].GetEvents()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, found %d", len(events))
+	}
+	if expected := time.Date(2024, 6, 10, 6, 0, 0, 0, time.UTC); !events[0].GetStart().Equal(expected) {
+		t.Errorf("Expected start %s in Europe/Sofia, found %s", expected, events[0].GetStart())
+	}
+	// an unknown TZID is read as UTC, it used to panic on the nil location
+	if expected := time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC); !events[1].GetStart().Equal(expected) || events[1].GetStartTZID() != "Unknown/Zone" {
+		t.Errorf("Expected start %s of the unknown TZID, found %s", expected, events[1].GetStart())
+	}
+}
+
+func TestEventUTCTimes(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:utc@example.com",
+		"DTSTART;TZID=Europe/Sofia:20240610T090000Z",
+		"DTEND:20240610T100000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	event := calendars[0].GetEvents()[0]
+	// the times ending with Z are UTC even with a TZID, they used to be read in the TZID
+	if expected := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC); !event.GetStart().Equal(expected) {
+		t.Errorf("Expected start %s, found %s", expected, event.GetStart())
+	}
+	if expected := time.Date(2024, 6, 10, 10, 0, 0, 0, time.UTC); !event.GetEnd().Equal(expected) {
+		t.Errorf("Expected end %s, found %s", expected, event.GetEnd())
+	}
+}
+
+func TestWholeDayEventDates(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:holiday@example.com",
+		"DTSTART;VALUE=DATE:20240610",
+		"DTEND;VALUE=DATE:20240611",
+		"SUMMARY:Holiday",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	event := calendars[0].GetEvents()[0]
+	// the dates of the whole day events used to be left zero
+	if expected := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC); !event.GetStart().Equal(expected) {
+		t.Errorf("Expected start %s, found %s", expected, event.GetStart())
+	}
+	if expected := time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC); !event.GetEnd().Equal(expected) {
+		t.Errorf("Expected end %s, found %s", expected, event.GetEnd())
+	}
+}
+
+func TestRepeatCount(t *testing.T) {
+	// the COUNT includes the first occurrence, it used to repeat COUNT more times
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"DTSTART:20240610T090000Z",
+		"DTEND:20240610T091500Z",
+		"SUMMARY:Standup",
+		"RRULE:FREQ=DAILY;COUNT=3",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	events := calendars[0].GetEvents()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events of COUNT=3, found %d", len(events))
+	}
+	if expected := time.Date(2024, 6, 12, 9, 0, 0, 0, time.UTC); !events[2].GetStart().Equal(expected) {
+		t.Errorf("Expected the last event at %s, found %s", expected, events[2].GetStart())
+	}
+
+	// the count runs out inside the week of a BYDAY, it used to repeat up to MaxRepeats
+	cases := []struct {
+		rrule    string
+		expected int
+		last     time.Time
+	}{
+		{"FREQ=WEEKLY;COUNT=2;BYDAY=MO,WE,FR", 2, time.Date(2024, 6, 12, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;COUNT=1;BYDAY=MO,WE", 1, time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;COUNT=4;BYDAY=MO,WE,FR", 4, time.Date(2024, 6, 17, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		parser := New()
+		parser.Load(crlf(
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"BEGIN:VEVENT",
+			"UID:standup@example.com",
+			"DTSTART:20240610T090000Z",
+			"DTEND:20240610T091500Z",
+			"SUMMARY:Standup",
+			"RRULE:"+c.rrule,
+			"END:VEVENT",
+			"END:VCALENDAR",
+		))
+		calendars, _ := parser.GetCalendars()
+		events := calendars[0].GetEvents()
+		if len(events) != c.expected {
+			t.Errorf("Expected %d events of %s, found %d", c.expected, c.rrule, len(events))
+			continue
+		}
+		if !events[len(events)-1].GetStart().Equal(c.last) {
+			t.Errorf("Expected the last event of %s at %s, found %s", c.rrule, c.last, events[len(events)-1].GetStart())
+		}
+	}
+}
+
+func TestAttendeeLastParams(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:params@example.com",
+		"DTSTART:20240610T090000Z",
+		"DTEND:20240610T100000Z",
+		`ATTENDEE;ROLE=CHAIR;CN="Smith, John";PARTSTAT=ACCEPTED:mailto:john@example.com`,
+		"ATTENDEE;PARTSTAT=DECLINED;CUTYPE=ROOM;ROLE=NON-PARTICIPANT;CN=Room:mailto:room@example.com",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	attendees := calendars[0].GetEvents()[0].GetAttendees()
+	if len(attendees) != 2 {
+		t.Fatalf("Expected 2 attendees, found %d", len(attendees))
+	}
+	// the parameters before the colon were left out, the regexps expected a semicolon after them
+	cases := []struct{ found, expected string }{
+		{attendees[0].GetName(), "Smith, John"},
+		{attendees[0].GetRole(), "CHAIR"},
+		{attendees[0].GetStatus(), "ACCEPTED"},
+		{attendees[1].GetName(), "Room"},
+		{attendees[1].GetRole(), "NON-PARTICIPANT"},
+		{attendees[1].GetType(), "ROOM"},
+		{attendees[1].GetStatus(), "DECLINED"},
+	}
+	for _, c := range cases {
+		if c.found != c.expected {
+			t.Errorf("Expected attendee parameter %s, found %s", c.expected, c.found)
+		}
+	}
+}
+
+func TestFoldedLines(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:folded@example.com",
+		"DTSTART:20240610T090000Z",
+		"DTEND:20240610T100000Z",
+		"SUMMARY:Design",
+		"  review",
+		"ATTENDEE;CN=John Smith;PARTSTAT=ACCEPTED:mailto:j.smith@exa",
+		"\tmple.com",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	event := calendars[0].GetEvents()[0]
+	// the folded lines used to be cut at the fold
+	if event.GetSummary() != "Design review" {
+		t.Errorf("Expected the unfolded summary Design review, found %q", event.GetSummary())
+	}
+	if attendees := event.GetAttendees(); len(attendees) != 1 || attendees[0].GetEmail() != "j.smith@example.com" {
+		t.Errorf("Expected the unfolded attendee j.smith@example.com, found %v", attendees)
+	}
+}
+
+func TestEventAlarms(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:alarm@example.com",
+		"DTSTART:20240610T090000Z",
+		"DTEND:20240610T100000Z",
+		"SUMMARY:Review",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Reminder",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	event := calendars[0].GetEvents()[0]
+	// the alarm properties used to be read as the event ones
+	if event.GetDescription() != "" {
+		t.Errorf("Expected no event description, found %q", event.GetDescription())
+	}
+	alarms := event.GetAlarms()
+	if len(alarms) != 1 {
+		t.Fatalf("Expected 1 alarm, found %d", len(alarms))
+	}
+	if alarms[0].GetDescription() != "Reminder" || alarms[0].GetTrigger() != -15*time.Minute {
+		t.Errorf("Expected the alarm Reminder at -15m, found %q at %s", alarms[0].GetDescription(), alarms[0].GetTrigger())
+	}
+}
+
+func TestEventConference(t *testing.T) {
+	parser := New()
+	parser.Load(crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:review@example.com",
+		"DTSTART:20240610T090000Z",
+		"DTEND:20240610T100000Z",
+		"SUMMARY:Design review",
+		"CONFERENCE;VALUE=URI;FEATURE=VIDEO;LABEL=Join:https://meet.example.com/abc",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	))
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 || len(calendars[0].GetEvents()) != 1 {
+		t.Fatalf("Expected 1 calendar with 1 event, found %d calendars", len(calendars))
+	}
+	event := calendars[0].GetEvents()[0]
+	if event.GetConference() != "https://meet.example.com/abc" {
+		t.Errorf("Expected conference https://meet.example.com/abc, found %s", event.GetConference())
+	}
+	if !strings.Contains(event.Serialize(), "CONFERENCE;VALUE=URI:https://meet.example.com/abc\r\n") {
+		t.Errorf("Expected the conference in the serialized event, found:\n%s", event.Serialize())
+	}
+}
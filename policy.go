@@ -0,0 +1,227 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FetchPolicy restricts what the parser reads, for urls given by users. The
+// addresses are checked after the DNS resolution, on every redirect too.
+type FetchPolicy struct {
+	// blocks the loopback, private, link-local, multicast and reserved addresses
+	BlockPrivate bool
+	// blocks the file urls and the paths without a scheme
+	DenyLocalFiles bool
+	// the only hosts allowed when not empty, "*.example.com" allows the subdomains
+	AllowHosts []string
+	// the hosts blocked, "*.example.com" blocks the subdomains
+	DenyHosts []string
+
+	mutex sync.Mutex
+	// the copies of the transports of the clients checking the addresses
+	transports map[*http.Transport]*http.Transport
+}
+
+// NewSafeFetchPolicy creates the policy blocking the private addresses and
+// the local files
+func NewSafeFetchPolicy() *FetchPolicy {
+	return &FetchPolicy{BlockPrivate: true, DenyLocalFiles: true}
+}
+
+// BlockedError is the error of an url blocked by the FetchPolicy
+type BlockedError struct {
+	// the host, address or file blocked
+	Target string
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("Fetching %s is blocked: %s", e.Target, e.Reason)
+}
+
+// the networks blocked with BlockPrivate
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8",      // this network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, cloud metadata
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved and broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // NAT64 of IPv4 addresses
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// reports whether the address is blocked with BlockPrivate
+func isPrivateIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// reports whether the host matches one of the patterns
+func matchHost(host string, patterns []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// checks the host of an url against the allow and deny lists
+func (f *FetchPolicy) checkHost(host string) error {
+	if matchHost(host, f.DenyHosts) {
+		return &BlockedError{Target: host, Reason: "the host is denied"}
+	}
+	if len(f.AllowHosts) > 0 && !matchHost(host, f.AllowHosts) {
+		return &BlockedError{Target: host, Reason: "the host is not allowed"}
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil && f.BlockPrivate && isPrivateIP(ip) {
+		return &BlockedError{Target: host, Reason: "the address is private"}
+	}
+	return nil
+}
+
+// checks the resolved address before the connection is made
+func (f *FetchPolicy) control(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return &BlockedError{Target: address, Reason: "the address is not an IP"}
+	}
+	if f.BlockPrivate && isPrivateIP(ip) {
+		return &BlockedError{Target: host, Reason: "the address is private"}
+	}
+	return nil
+}
+
+// returns the copy of the transport dialing the checked addresses without a
+// proxy, the dial functions of the transport are not used
+func (f *FetchPolicy) transportOf(base *http.Transport) *http.Transport {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if transport, ok := f.transports[base]; ok {
+		return transport
+	}
+	// the fields of Transport.Clone, which is Go 1.13
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   f.control,
+		}).DialContext,
+		TLSClientConfig:        base.TLSClientConfig,
+		TLSHandshakeTimeout:    base.TLSHandshakeTimeout,
+		DisableKeepAlives:      base.DisableKeepAlives,
+		DisableCompression:     base.DisableCompression,
+		MaxIdleConns:           base.MaxIdleConns,
+		MaxIdleConnsPerHost:    base.MaxIdleConnsPerHost,
+		MaxConnsPerHost:        base.MaxConnsPerHost,
+		IdleConnTimeout:        base.IdleConnTimeout,
+		ResponseHeaderTimeout:  base.ResponseHeaderTimeout,
+		ExpectContinueTimeout:  base.ExpectContinueTimeout,
+		TLSNextProto:           base.TLSNextProto,
+		MaxResponseHeaderBytes: base.MaxResponseHeaderBytes,
+	}
+	if f.transports == nil {
+		f.transports = map[*http.Transport]*http.Transport{}
+	}
+	f.transports[base] = transport
+	return transport
+}
+
+// returns the client of the requests under the policy. It has the Timeout
+// and Jar of the base client, a copy of its transport checking the addresses
+// without a proxy, and it checks the hosts of the redirects. A transport that
+// is not a *http.Transport can't be checked and is an error.
+func (f *FetchPolicy) client(base *http.Client) (*http.Client, error) {
+	var roundTripper http.RoundTripper = http.DefaultTransport
+	if base != nil && base.Transport != nil {
+		roundTripper = base.Transport
+	}
+	baseTransport, ok := roundTripper.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("The transport %T of the http client can't be used with a fetch policy", roundTripper)
+	}
+	client := &http.Client{Transport: f.transportOf(baseTransport)}
+	var checkRedirect func(*http.Request, []*http.Request) error
+	if base != nil {
+		client.Timeout = base.Timeout
+		client.Jar = base.Jar
+		checkRedirect = base.CheckRedirect
+	}
+	client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if err := f.checkHost(request.URL.Hostname()); err != nil {
+			return err
+		}
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return client, nil
+}
+
+// SetFetchPolicy sets the policy of the urls of the parser, nil removes it
+func (p *Parser) SetFetchPolicy(policy *FetchPolicy) *Parser {
+	p.fetchMutex.Lock()
+	p.policy = policy
+	p.fetchMutex.Unlock()
+
+	web := p.httpFetcher()
+	web.Policy = policy
+	p.setHTTPFetcher(web)
+	return p
+}
+
+// GetFetchPolicy returns the policy of the urls of the parser
+func (p *Parser) GetFetchPolicy() *FetchPolicy {
+	p.fetchMutex.Lock()
+	defer p.fetchMutex.Unlock()
+	return p.policy
+}
@@ -0,0 +1,135 @@
+package ics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.20.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:127.0.0.1": true,
+		"8.8.8.8":          false,
+		"172.32.0.1":       false,
+		"2001:4860::8888":  false,
+	}
+	for address, expected := range cases {
+		if found := isPrivateIP(net.ParseIP(address)); found != expected {
+			t.Errorf("Expected %s to be private %t, found %t", address, expected, found)
+		}
+	}
+}
+
+func TestFetchPolicyHosts(t *testing.T) {
+	policy := &FetchPolicy{AllowHosts: []string{"*.example.com", "cal.test"}, DenyHosts: []string{"private.example.com"}}
+	cases := map[string]bool{
+		"www.example.com":     true,
+		"WWW.Example.COM.":    true,
+		"example.com":         false,
+		"cal.test":            true,
+		"private.example.com": false,
+		"evil.com":            false,
+	}
+	for host, allowed := range cases {
+		if err := policy.checkHost(host); (err == nil) != allowed {
+			t.Errorf("Expected %s to be allowed %t, found %v", host, allowed, err)
+		}
+	}
+}
+
+func TestFetchPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			// the same server by a denied name
+			http.Redirect(w, r, strings.Replace(serverURL(r), "127.0.0.1", "localhost", 1)+"/cal.ics", http.StatusFound)
+			return
+		}
+		w.Write([]byte(fetchTestCal))
+	}))
+	defer server.Close()
+
+	// the loopback server is blocked after the resolution of localhost
+	parser := New().SetFetchPolicy(NewSafeFetchPolicy())
+	localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	calendars, errs := fetchAll(parser, server.URL+"/cal.ics", localhost+"/cal.ics", "testCalendars/2eventsCal.ics", "file:///etc/passwd")
+	if len(calendars) != 0 || len(errs) != 4 {
+		t.Fatalf("Expected 4 blocked urls, found %d calendars and %v", len(calendars), errs)
+	}
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "is blocked") {
+			t.Errorf("Expected a blocked error, found %s", err)
+		}
+	}
+
+	// the redirects are checked too
+	parser = New().SetFetchPolicy(&FetchPolicy{DenyHosts: []string{"localhost"}})
+	calendars, errs = fetchAll(parser, server.URL+"/cal.ics", server.URL+"/redirect", "testCalendars/2eventsCal.ics")
+	if len(calendars) != 2 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "the host is denied") {
+		t.Errorf("Expected the redirect to be denied, found %d calendars and %v", len(calendars), errs)
+	}
+}
+
+// returns the url of the server of the request
+func serverURL(r *http.Request) string {
+	return "http://" + r.Host
+}
+
+// a transport that is not a *http.Transport
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestFetchPolicyKeepsTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fetchTestCal))
+	}))
+	defer server.Close()
+
+	// the client of the test server trusts its certificate
+	calendars, errs := fetchAll(New().SetHTTPClient(server.Client()).SetFetchPolicy(&FetchPolicy{}), server.URL+"/cal.ics")
+	if len(calendars) != 1 || len(errs) != 0 {
+		t.Errorf("Expected the calendar with the TLS config of the transport, found %d calendars and %v", len(calendars), errs)
+	}
+
+	// the addresses are still checked
+	calendars, errs = fetchAll(New().SetHTTPClient(server.Client()).SetFetchPolicy(NewSafeFetchPolicy()), server.URL+"/cal.ics")
+	if len(calendars) != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), "is blocked") {
+		t.Errorf("Expected the loopback server to be blocked, found %d calendars and %v", len(calendars), errs)
+	}
+
+	parser := New().SetHTTPClient(&http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}).SetFetchPolicy(&FetchPolicy{})
+	if calendars, errs := fetchAll(parser, server.URL+"/cal.ics"); len(calendars) != 0 || len(errs) != 1 {
+		t.Errorf("Expected an error of the transport, found %d calendars and %v", len(calendars), errs)
+	}
+}
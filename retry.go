@@ -0,0 +1,192 @@
+package ics
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy is how the downloads failed with a 5xx status, a 429 Too Many
+// Requests or a timeout are tried again. The waits grow exponentially with
+// a random jitter, a Retry-After of the server is honoured.
+type RetryPolicy struct {
+	// the retries after the first attempt
+	Retries int
+	// the wait before the first retry, doubled before each next one
+	Backoff time.Duration
+	// the longest wait, a longer Retry-After ends the retries
+	MaxBackoff time.Duration
+}
+
+// NewRetryPolicy creates a policy of the retries, waiting from a second up
+// to a minute
+func NewRetryPolicy(retries int) *RetryPolicy {
+	return &RetryPolicy{
+		Retries:    retries,
+		Backoff:    time.Second,
+		MaxBackoff: time.Minute,
+	}
+}
+
+// returns the wait before the retry, between the half and the whole of the
+// exponential backoff
+func (r *RetryPolicy) wait(retry int) time.Duration {
+	backoff := r.Backoff
+	for i := 0; i < retry && (r.MaxBackoff <= 0 || backoff < r.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	if backoff <= 1 {
+		return backoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// reports whether the failed request should be tried again
+func retryable(response *http.Response, err error) bool {
+	if err != nil {
+		netErr, ok := err.(net.Error)
+		return ok && netErr.Timeout()
+	}
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+}
+
+// returns the wait of the Retry-After header, in seconds or a date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// sends the request with the client of the fetcher and its retries
+func (f *HTTPFetcher) send(request *http.Request) (*http.Response, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if f.Policy != nil {
+		if err := f.Policy.checkHost(request.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		var err error
+		if client, err = f.Policy.client(f.Client); err != nil {
+			return nil, err
+		}
+	}
+	client = withoutCredentialsOnRedirect(client)
+	for retry := 0; ; retry++ {
+		response, err := client.Do(request)
+		if f.Retry == nil || retry >= f.Retry.Retries || !retryable(response, err) {
+			if err != nil {
+				return nil, err
+			}
+			return f.limitResponse(response, f.redact(request.URL.String()))
+		}
+		wait := f.Retry.wait(retry)
+		if response != nil {
+			if after, ok := retryAfter(response.Header.Get("Retry-After")); ok {
+				if f.Retry.MaxBackoff > 0 && after > f.Retry.MaxBackoff {
+					return f.limitResponse(response, f.redact(request.URL.String()))
+				}
+				wait = after
+			}
+			// drain the body so the connection is reused
+			io.Copy(ioutil.Discard, io.LimitReader(response.Body, 1<<16))
+			response.Body.Close()
+		}
+		time.Sleep(wait)
+	}
+}
+
+// SetRetryPolicy sets the retries of the http, https, webcal and webcals
+// urls, it replaces the fetchers of these schemes
+func (p *Parser) SetRetryPolicy(policy *RetryPolicy) *Parser {
+	web := p.httpFetcher()
+	web.Retry = policy
+	p.setHTTPFetcher(web)
+	return p
+}
+
+// Health is the state of the fetches of a source
+type Health struct {
+	URL         string
+	LastSuccess time.Time
+	LastFailure time.Time
+	LastError   error
+	// the failures since the last success
+	ConsecutiveFailures int
+}
+
+// Healthy reports whether the last fetch of the source succeeded
+func (h Health) Healthy() bool {
+	return h.ConsecutiveFailures == 0
+}
+
+// records the result of a fetch of the url, a stale copy is a failure
+func (p *Parser) recordHealth(url string, err error) {
+	if stale, ok := err.(*staleError); ok {
+		err = stale.err
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if p.health == nil {
+		p.health = map[string]*Health{}
+	}
+	h, ok := p.health[url]
+	if !ok {
+		h = &Health{URL: p.redact(url)}
+		p.health[url] = h
+	}
+	if err != nil {
+		h.LastFailure = time.Now()
+		h.LastError = err
+		h.ConsecutiveFailures++
+		return
+	}
+	h.LastSuccess = time.Now()
+	h.ConsecutiveFailures = 0
+}
+
+// GetHealth returns the state of the fetches of the url, false if it was not
+// fetched yet
+func (p *Parser) GetHealth(url string) (Health, bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	h, ok := p.health[url]
+	if !ok {
+		return Health{}, false
+	}
+	return *h, true
+}
+
+// GetHealths returns the state of the fetches of every url, ordered by url
+func (p *Parser) GetHealths() []Health {
+	mutex.Lock()
+	defer mutex.Unlock()
+	healths := []Health{}
+	for _, h := range p.health {
+		healths = append(healths, *h)
+	}
+	sort.Slice(healths, func(i, j int) bool {
+		return healths[i].URL < healths[j].URL
+	})
+	return healths
+}
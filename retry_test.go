@@ -0,0 +1,149 @@
+package ics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// a server failing with the status before it serves fetchTestCal
+func flakyServer(failures int32, status int, retryAfter string) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(fetchTestCal))
+	}))
+	return server, &requests
+}
+
+func TestRetryPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+	policy := &RetryPolicy{Retries: 3, Backoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+	server, requests := flakyServer(2, http.StatusBadGateway, "")
+	calendars, errs := fetchAll(New().SetRetryPolicy(policy), server.URL)
+	server.Close()
+	if len(calendars) != 1 || len(errs) != 0 || *requests != 3 {
+		t.Errorf("Expected 1 calendar after 3 requests, found %d calendars, %d requests and %v", len(calendars), *requests, errs)
+	}
+
+	server, requests = flakyServer(5, http.StatusServiceUnavailable, "0")
+	calendars, errs = fetchAll(New().SetRetryPolicy(policy), server.URL)
+	server.Close()
+	if len(calendars) != 0 || len(errs) != 1 || *requests != 4 {
+		t.Errorf("Expected 1 error after 4 requests, found %d calendars, %d requests and %v", len(calendars), *requests, errs)
+	}
+
+	// a Retry-After longer than MaxBackoff ends the retries
+	server, requests = flakyServer(1, http.StatusTooManyRequests, "120")
+	_, errs = fetchAll(New().SetRetryPolicy(policy), server.URL)
+	server.Close()
+	if len(errs) != 1 || *requests != 1 {
+		t.Errorf("Expected 1 error after 1 request, found %d requests and %v", *requests, errs)
+	}
+
+	// the client errors are not retried
+	server, requests = flakyServer(1, http.StatusNotFound, "")
+	_, errs = fetchAll(New().SetRetryPolicy(policy), server.URL)
+	server.Close()
+	if len(errs) != 1 || *requests != 1 {
+		t.Errorf("Expected 1 error after 1 request, found %d requests and %v", *requests, errs)
+	}
+}
+
+func TestRetryOnTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(fetchTestCal))
+	}))
+	defer server.Close()
+
+	parser := New().
+		SetHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}).
+		SetRetryPolicy(&RetryPolicy{Retries: 1, Backoff: time.Millisecond})
+	calendars, errs := fetchAll(parser, server.URL)
+	if n := atomic.LoadInt32(&requests); len(calendars) != 1 || len(errs) != 0 || n != 2 {
+		t.Errorf("Expected 1 calendar after 2 requests, found %d calendars, %d requests and %v", len(calendars), n, errs)
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	policy := &RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		wait := policy.wait(retry)
+		if wait < max/2 || wait > max {
+			t.Errorf("Expected the wait of the retry %d between %s and %s, found %s", retry, max/2, max, wait)
+		}
+	}
+
+	if wait, ok := retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"); !ok || wait != 0 {
+		t.Errorf("Expected no wait for a past date, found %s", wait)
+	}
+	if wait, ok := retryAfter("30"); !ok || wait != 30*time.Second {
+		t.Errorf("Expected a wait of 30s, found %s", wait)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Errorf("Expected an invalid Retry-After to be ignored")
+	}
+}
+
+func TestHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	FilePath = dir + "/"
+	defer func() { FilePath = "tmp/" }()
+
+	server, _ := flakyServer(2, http.StatusInternalServerError, "")
+	defer server.Close()
+	parser := New()
+	if _, ok := parser.GetHealth(server.URL); ok {
+		t.Errorf("Expected no health of a source not fetched")
+	}
+
+	fetchAll(parser, server.URL)
+	fetchAll(parser, server.URL)
+	health, _ := parser.GetHealth(server.URL)
+	if health.Healthy() || health.ConsecutiveFailures != 2 || health.LastError == nil || !health.LastSuccess.IsZero() {
+		t.Errorf("Expected 2 consecutive failures, found %+v", health)
+	}
+
+	fetchAll(parser, server.URL, "testCalendars/2eventsCal.ics")
+	health, _ = parser.GetHealth(server.URL)
+	if !health.Healthy() || health.LastSuccess.IsZero() || health.LastFailure.IsZero() {
+		t.Errorf("Expected a healthy source with the last failure, found %+v", health)
+	}
+	if healths := parser.GetHealths(); len(healths) != 2 {
+		t.Errorf("Expected the health of 2 sources, found %d", len(healths))
+	}
+}
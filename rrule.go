@@ -0,0 +1,184 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence frequencies of the RRULE FREQ part
+const (
+	FreqSecondly = "SECONDLY"
+	FreqMinutely = "MINUTELY"
+	FreqHourly   = "HOURLY"
+	FreqDaily    = "DAILY"
+	FreqWeekly   = "WEEKLY"
+	FreqMonthly  = "MONTHLY"
+	FreqYearly   = "YEARLY"
+)
+
+// RRule is the structured form of the RRULE property of an event
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []string
+	ByMonthDay []int
+	ByMonth    []int
+	WeekStart  string
+}
+
+// NewRRule creates a new RRule repeating with the given frequency
+func NewRRule(freq string) *RRule {
+	return &RRule{Freq: freq}
+}
+
+// Validate checks that the rule can be written as a valid RRULE value
+func (r *RRule) Validate() error {
+	switch r.Freq {
+	case FreqSecondly, FreqMinutely, FreqHourly, FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	case "":
+		return errors.New("rrule: FREQ is required")
+	default:
+		return fmt.Errorf("rrule: unknown FREQ %s", r.Freq)
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("rrule: negative INTERVAL %d", r.Interval)
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("rrule: negative COUNT %d", r.Count)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("rrule: COUNT and UNTIL must not occur together")
+	}
+	for _, day := range r.ByDay {
+		if !validByDay(day) {
+			return fmt.Errorf("rrule: invalid BYDAY %s", day)
+		}
+	}
+	for _, day := range r.ByMonthDay {
+		if day == 0 || day < -31 || day > 31 {
+			return fmt.Errorf("rrule: invalid BYMONTHDAY %d", day)
+		}
+	}
+	for _, month := range r.ByMonth {
+		if month < 1 || month > 12 {
+			return fmt.Errorf("rrule: invalid BYMONTH %d", month)
+		}
+	}
+	if r.WeekStart != "" && !validByDay(r.WeekStart) {
+		return fmt.Errorf("rrule: invalid WKST %s", r.WeekStart)
+	}
+	return nil
+}
+
+// String formats the rule as the value of a RRULE property
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(IcsFormat))
+	}
+	if len(r.ByDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(r.ByDay, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if r.WeekStart != "" {
+		parts = append(parts, "WKST="+r.WeekStart)
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseRRule parses the value of a RRULE property
+func ParseRRule(value string) (*RRule, error) {
+	r := new(RRule)
+	for _, part := range strings.Split(strings.TrimSpace(value), ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("rrule: malformed part %s", part)
+		}
+		var err error
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(kv[1])
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(kv[1])
+		case "COUNT":
+			r.Count, err = strconv.Atoi(kv[1])
+		case "UNTIL":
+			r.Until, err = time.Parse(IcsFormat, kv[1])
+			if err != nil {
+				r.Until, err = time.Parse(IcsFormatWholeDay, kv[1])
+			}
+		case "BYDAY":
+			r.ByDay = strings.Split(strings.ToUpper(kv[1]), ",")
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = splitInts(kv[1])
+		case "BYMONTH":
+			r.ByMonth, err = splitInts(kv[1])
+		case "WKST":
+			r.WeekStart = strings.ToUpper(kv[1])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: invalid %s ( %s )", kv[0], err)
+		}
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checks a BYDAY entry like MO, -1FR or 2TU
+func validByDay(day string) bool {
+	if len(day) < 2 {
+		return false
+	}
+	switch day[len(day)-2:] {
+	case "MO", "TU", "WE", "TH", "FR", "SA", "SU":
+	default:
+		return false
+	}
+	if ord := day[:len(day)-2]; ord != "" {
+		n, err := strconv.Atoi(ord)
+		return err == nil && n != 0 && n >= -53 && n <= 53
+	}
+	return true
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitInts(value string) ([]int, error) {
+	parts := strings.Split(value, ",")
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
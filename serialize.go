@@ -0,0 +1,677 @@
+package ics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	wtz "github.com/yaegashi/wtz.go"
+)
+
+// product identifier written in the PRODID of serialized calendars
+var ProdID = "-//PuloV//ics-golang//EN"
+
+// max octets of a content line before it is folded
+const maxLineOctets = 75
+
+// ======================== CONTENT MODEL ===================
+
+// param is a single property parameter like CN=John
+type param struct {
+	name  string
+	value string
+}
+
+// property is a single content line of a component
+type property struct {
+	name   string
+	params []param
+	value  string
+}
+
+// component is a BEGIN/END block with its properties and sub components
+type component struct {
+	name  string
+	props []*property
+	comps []*component
+}
+
+func newComponent(name string) *component {
+	return &component{name: name}
+}
+
+// adds a property to the component
+func (c *component) addProp(name, value string, params ...param) *property {
+	prop := &property{name: name, value: value, params: params}
+	c.props = append(c.props, prop)
+	return prop
+}
+
+// adds a TEXT property only when the value is not empty. The parsed
+// values keep their escapes and are written as they are.
+func (c *component) addText(name, value string, escaped bool) {
+	if value == "" {
+		return
+	}
+	if !escaped {
+		value = escapeText(value)
+	}
+	c.addProp(name, value)
+}
+
+// returns the first property with the given name
+func (c *component) prop(name string) *property {
+	for _, prop := range c.props {
+		if prop.name == name {
+			return prop
+		}
+	}
+	return nil
+}
+
+// returns the value of the named parameter
+func (p *property) param(name string) string {
+	for _, prm := range p.params {
+		if prm.name == name {
+			return prm.value
+		}
+	}
+	return ""
+}
+
+// writes the component as folded content lines
+func (c *component) write(b *strings.Builder) {
+	writeLine(b, "BEGIN:"+c.name)
+	for _, prop := range c.props {
+		writeLine(b, prop.String())
+	}
+	for _, sub := range c.comps {
+		sub.write(b)
+	}
+	writeLine(b, "END:"+c.name)
+}
+
+func (c *component) String() string {
+	var b strings.Builder
+	c.write(&b)
+	return b.String()
+}
+
+// formats the property as an unfolded content line
+func (p *property) String() string {
+	var b strings.Builder
+	b.WriteString(p.name)
+	for _, prm := range p.params {
+		b.WriteString(";")
+		b.WriteString(prm.name)
+		b.WriteString("=")
+		b.WriteString(quoteParam(prm.value))
+	}
+	b.WriteString(":")
+	b.WriteString(p.value)
+	return b.String()
+}
+
+// writes a content line folded after 75 octets without splitting characters
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of the continuation line counts as an octet
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
+
+// ======================== CONTENT PARSING ===================
+
+// reads the first component of an iCalendar stream with all its
+// properties, parameters and sub components
+func readComponent(content string) (*component, error) {
+	var root *component
+	stack := []*component{}
+	for _, line := range strings.Split(unfoldICal(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseContentLine(line)
+		if err != nil {
+			return nil, err
+		}
+		switch prop.name {
+		case "BEGIN":
+			c := newComponent(strings.ToUpper(prop.value))
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.comps = append(parent.comps, c)
+			} else {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("Unexpected END:%s", prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return root, nil
+			}
+		default:
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				top.props = append(top.props, prop)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("No component found")
+	}
+	return nil, fmt.Errorf("Missing END:%s", stack[len(stack)-1].name)
+}
+
+// parses an unfolded content line like NAME;PARAM="value":value
+func parseContentLine(line string) (*property, error) {
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return nil, fmt.Errorf("Invalid content line %q", line)
+	}
+	prop := &property{name: strings.ToUpper(line[:i])}
+	rest := line[i:]
+	for rest[0] == ';' {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("Invalid parameter in content line %q", line)
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		// the value can be quoted and can be a list
+		values := []string{}
+		for {
+			if len(rest) > 0 && rest[0] == '"' {
+				end := strings.IndexByte(rest[1:], '"')
+				if end < 0 {
+					return nil, fmt.Errorf("Unterminated quote in content line %q", line)
+				}
+				values = append(values, rest[1:end+1])
+				rest = rest[end+2:]
+			} else {
+				end := strings.IndexAny(rest, ",;:")
+				if end < 0 {
+					return nil, fmt.Errorf("Invalid content line %q", line)
+				}
+				values = append(values, rest[:end])
+				rest = rest[end:]
+			}
+			if len(rest) == 0 || rest[0] != ',' {
+				break
+			}
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("Missing value in content line %q", line)
+		}
+		prop.params = append(prop.params, param{name, strings.Join(values, ",")})
+	}
+	prop.value = rest[1:]
+	return prop, nil
+}
+
+// splits a value on the separators that are not escaped
+func splitEscaped(value string, sep byte) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// escapes a TEXT value
+func escapeText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// returns the text of the event without the escapes of the parsed texts
+func (e *Event) plainText(value string) string {
+	if e.escaped {
+		return unescapeText(value)
+	}
+	return value
+}
+
+// GetPlainSummary returns the summary without the escapes of the parsed texts
+func (e *Event) GetPlainSummary() string {
+	return e.plainText(e.GetSummary())
+}
+
+// GetPlainDescription returns the description without the escapes of the parsed texts
+func (e *Event) GetPlainDescription() string {
+	return e.plainText(e.GetDescription())
+}
+
+// GetPlainLocation returns the location without the escapes of the parsed texts
+func (e *Event) GetPlainLocation() string {
+	return e.plainText(e.GetLocation())
+}
+
+// reverts escapeText
+func unescapeText(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '\\' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// quotes a parameter value when it contains separators
+func quoteParam(value string) string {
+	value = strings.Replace(value, `"`, "'", -1)
+	if strings.ContainsAny(value, ":;,") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// formats a duration as an iCalendar DURATION value like -PT15M or P1DT2H
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString("-")
+		d = -d
+	}
+	b.WriteString("P")
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		if days%7 == 0 && d == 0 {
+			return fmt.Sprintf("%s%dW", b.String(), days/7)
+		}
+		b.WriteString(strconv.Itoa(int(days)) + "D")
+	}
+	if d > 0 || days == 0 {
+		b.WriteString("T")
+		hours := d / time.Hour
+		d -= hours * time.Hour
+		minutes := d / time.Minute
+		d -= minutes * time.Minute
+		seconds := d / time.Second
+		if hours > 0 {
+			b.WriteString(strconv.Itoa(int(hours)) + "H")
+		}
+		if minutes > 0 {
+			b.WriteString(strconv.Itoa(int(minutes)) + "M")
+		}
+		if seconds > 0 || (hours == 0 && minutes == 0) {
+			b.WriteString(strconv.Itoa(int(seconds)) + "S")
+		}
+	}
+	return b.String()
+}
+
+// LoadLocation loads a location by its IANA or Windows name, like the
+// TZIDs read by the parser
+func LoadLocation(tzid string) (*time.Location, error) {
+	loc, err := time.LoadLocation(tzid)
+	if err == nil {
+		return loc, nil
+	}
+	return wtz.LoadLocation(tzid)
+}
+
+// formats a DATE-TIME or DATE property
+func dateTimeProp(c *component, name string, t time.Time, tzid string, wholeDay bool) {
+	if wholeDay {
+		c.addProp(name, t.Format(IcsFormatWholeDay), param{"VALUE", "DATE"})
+		return
+	}
+	if loc := tzidLocation(tzid); loc != nil {
+		c.addProp(name, t.In(loc).Format(dateTimeLayoutLocalized), param{"TZID", tzid})
+		return
+	}
+	c.addProp(name, t.UTC().Format(IcsFormat))
+}
+
+// returns the location of the TZID of a DATE-TIME, nil when it is written in UTC
+func tzidLocation(tzid string) *time.Location {
+	if tzid == "" || tzid == "UTC" {
+		return nil
+	}
+	if loc, err := LoadLocation(tzid); err == nil {
+		return loc
+	}
+	return nil
+}
+
+// ======================== TIME ZONE SERIALIZATION ===================
+
+// tzTransition is a change of the UTC offset of a time zone
+type tzTransition struct {
+	at       int64
+	from, to int
+	name     string
+}
+
+// returns the transitions of the location between the unix times, the
+// first one is the offset at the start
+func tzTransitions(loc *time.Location, start, end int64) []tzTransition {
+	offsetAt := func(at int64) (string, int) {
+		return time.Unix(at, 0).In(loc).Zone()
+	}
+	name, offset := offsetAt(start)
+	transitions := []tzTransition{{at: start, from: offset, to: offset, name: name}}
+	const day = 24 * 60 * 60
+	for t := start; t < end; {
+		next := t + day
+		if _, o := offsetAt(next); o == offset {
+			t = next
+			continue
+		}
+		// the first second of the new offset
+		lo, hi := t, next
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if _, o := offsetAt(mid); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		name, o := offsetAt(hi)
+		transitions = append(transitions, tzTransition{at: hi, from: offset, to: o, name: name})
+		offset = o
+		t = hi
+	}
+	return transitions
+}
+
+// formats an UTC offset in seconds like +0130
+func formatUTCOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	value := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		value += fmt.Sprintf("%02d", offset%60)
+	}
+	return value
+}
+
+// builds the VTIMEZONE of the location with its transitions in the years
+// from the first to the last time. Each offset change is a STANDARD or a
+// DAYLIGHT observance with the dates of its transitions.
+func timezoneComponent(tzid string, loc *time.Location, first, last time.Time) *component {
+	start := time.Date(first.In(loc).Year(), 1, 1, 0, 0, 0, 0, loc).Unix()
+	end := time.Date(last.In(loc).Year()+1, 1, 1, 0, 0, 0, 0, loc).Unix()
+	transitions := tzTransitions(loc, start, end)
+
+	// the smallest offset is the standard time
+	standard := transitions[0].to
+	for _, t := range transitions {
+		if t.to < standard {
+			standard = t.to
+		}
+	}
+
+	tz := newComponent("VTIMEZONE")
+	tz.addProp("TZID", tzid)
+	observances := map[tzTransition]*component{}
+	for _, t := range transitions {
+		// the transitions are written in the local time before them
+		local := time.Unix(t.at, 0).In(time.FixedZone("", t.from)).Format(dateTimeLayoutLocalized)
+		key := tzTransition{from: t.from, to: t.to, name: t.name}
+		if observance, ok := observances[key]; ok {
+			observance.addProp("RDATE", local)
+			continue
+		}
+		kind := "STANDARD"
+		if t.to > standard {
+			kind = "DAYLIGHT"
+		}
+		observance := newComponent(kind)
+		observance.addProp("DTSTART", local)
+		observance.addProp("TZOFFSETFROM", formatUTCOffset(t.from))
+		observance.addProp("TZOFFSETTO", formatUTCOffset(t.to))
+		observance.addProp("TZNAME", t.name)
+		observances[key] = observance
+		tz.comps = append(tz.comps, observance)
+	}
+	return tz
+}
+
+// returns the VTIMEZONE components of the TZIDs of the events, in the
+// order of their first use
+func (c *Calendar) timezoneComponents() []*component {
+	type zone struct {
+		loc         *time.Location
+		first, last time.Time
+	}
+	zones := map[string]*zone{}
+	tzids := []string{}
+	use := func(tzid string, t time.Time) {
+		if t.IsZero() {
+			return
+		}
+		z, ok := zones[tzid]
+		if !ok {
+			loc := tzidLocation(tzid)
+			if loc == nil {
+				return
+			}
+			z = &zone{loc: loc, first: t, last: t}
+			zones[tzid] = z
+			tzids = append(tzids, tzid)
+		}
+		if t.Before(z.first) {
+			z.first = t
+		}
+		if t.After(z.last) {
+			z.last = t
+		}
+	}
+	// the expanded occurrences give the years of the repeating events
+	for i := range c.events {
+		event := &c.events[i]
+		if event.IsWholeDay() {
+			continue
+		}
+		use(event.GetStartTZID(), event.GetStart())
+		use(event.GetStartTZID(), event.GetRecurrenceID())
+		use(event.GetEndTZID(), event.GetEnd())
+	}
+	comps := []*component{}
+	for _, tzid := range tzids {
+		z := zones[tzid]
+		comps = append(comps, timezoneComponent(tzid, z.loc, z.first, z.last))
+	}
+	return comps
+}
+
+// formats an attendee as a calendar user address property
+func attendeeProp(c *component, name string, a *Attendee) {
+	var params []param
+	if a.GetType() != "" {
+		params = append(params, param{"CUTYPE", a.GetType()})
+	}
+	if a.GetRole() != "" {
+		params = append(params, param{"ROLE", a.GetRole()})
+	}
+	if a.GetStatus() != "" {
+		params = append(params, param{"PARTSTAT", a.GetStatus()})
+	}
+	if a.GetRSVP() {
+		params = append(params, param{"RSVP", "TRUE"})
+	}
+	if a.GetName() != "" {
+		params = append(params, param{"CN", a.GetName()})
+	}
+	c.addProp(name, "mailto:"+a.GetEmail(), params...)
+}
+
+// ======================== EVENT SERIALIZATION ===================
+
+// builds the VEVENT component of the event
+func (e *Event) component() *component {
+	c := newComponent("VEVENT")
+
+	uid := e.GetImportedID()
+	if uid == "" {
+		uid = e.GetID()
+	}
+	c.addProp("UID", uid)
+
+	stamp := e.GetDTStamp()
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	c.addProp("DTSTAMP", stamp.UTC().Format(IcsFormat))
+
+	dateTimeProp(c, "DTSTART", e.GetStart(), e.GetStartTZID(), e.IsWholeDay())
+	if !e.GetEnd().IsZero() {
+		dateTimeProp(c, "DTEND", e.GetEnd(), e.GetEndTZID(), e.IsWholeDay())
+	}
+	if !e.GetRecurrenceID().IsZero() {
+		dateTimeProp(c, "RECURRENCE-ID", e.GetRecurrenceID(), e.GetStartTZID(), e.IsWholeDay())
+	}
+	if !e.GetCreated().IsZero() {
+		c.addProp("CREATED", e.GetCreated().UTC().Format(IcsFormat))
+	}
+	if !e.GetLastModified().IsZero() {
+		c.addProp("LAST-MODIFIED", e.GetLastModified().UTC().Format(IcsFormat))
+	}
+	if e.GetSequence() > 0 {
+		c.addProp("SEQUENCE", strconv.Itoa(e.GetSequence()))
+	}
+	if e.GetOrganizer() != nil {
+		attendeeProp(c, "ORGANIZER", e.GetOrganizer())
+	}
+	for _, a := range e.GetAttendees() {
+		attendeeProp(c, "ATTENDEE", a)
+	}
+	c.addText("SUMMARY", e.GetSummary(), e.escaped)
+	c.addText("DESCRIPTION", e.GetDescription(), e.escaped)
+	c.addText("LOCATION", e.GetLocation(), e.escaped)
+	if geo := e.GetGeo(); geo != nil {
+		c.addProp("GEO", geo.latStr+";"+geo.longStr)
+	}
+	if e.GetConference() != "" {
+		c.addProp("CONFERENCE", e.GetConference(), param{"VALUE", "URI"})
+	}
+	c.addText("STATUS", e.GetStatus(), e.escaped)
+	c.addText("CLASS", e.GetClass(), e.escaped)
+	if e.GetRRule() != "" {
+		c.addProp("RRULE", e.GetRRule())
+	}
+	c.addText("X-MICROSOFT-CDO-BUSYSTATUS", e.GetBusyStatus(), e.escaped)
+
+	for _, a := range e.GetAlarms() {
+		c.comps = append(c.comps, a.component(e))
+	}
+	return c
+}
+
+// builds the VALARM component of the alarm
+func (a *Alarm) component(e *Event) *component {
+	c := newComponent("VALARM")
+	c.addProp("ACTION", a.GetAction())
+	c.addProp("TRIGGER", formatDuration(a.GetTrigger()))
+
+	description := a.GetDescription()
+	if description == "" && a.GetAction() != AlarmAudio {
+		// DISPLAY and EMAIL alarms require a description
+		description = e.GetSummary()
+	}
+	c.addText("DESCRIPTION", description, e.escaped)
+	if a.GetAction() == AlarmEmail {
+		summary := a.GetSummary()
+		if summary == "" {
+			summary = e.GetSummary()
+		}
+		c.addText("SUMMARY", summary, e.escaped)
+		for _, attendee := range e.GetAttendees() {
+			c.addProp("ATTENDEE", "mailto:"+attendee.GetEmail())
+		}
+	}
+	return c
+}
+
+// Serialize returns the event as a VEVENT block
+func (e *Event) Serialize() string {
+	return e.component().String()
+}
+
+// ======================== CALENDAR SERIALIZATION ===================
+
+// builds the VCALENDAR component of the calendar
+func (c *Calendar) component() *component {
+	cal := newComponent("VCALENDAR")
+	cal.addProp("VERSION", "2.0")
+	cal.addProp("PRODID", ProdID)
+	cal.addProp("CALSCALE", "GREGORIAN")
+	if c.GetMethod() != "" {
+		cal.addProp("METHOD", c.GetMethod())
+	}
+	cal.addText("X-WR-CALNAME", c.GetName(), c.escaped)
+	cal.addText("X-WR-CALDESC", c.GetDesc(), c.escaped)
+	tz := c.GetTimezone()
+	if name := tz.String(); name != "" && name != "UTC" {
+		cal.addProp("X-WR-TIMEZONE", name)
+	}
+
+	// every TZID of the events has its VTIMEZONE
+	cal.comps = append(cal.comps, c.timezoneComponents()...)
+
+	// the parser expands repeating events, only the first occurrence
+	// that carries the RRULE is written back
+	repeated := map[string]bool{}
+	for i := range c.events {
+		event := &c.events[i]
+		if event.GetRRule() != "" && event.GetImportedID() != "" {
+			if repeated[event.GetImportedID()] {
+				continue
+			}
+			repeated[event.GetImportedID()] = true
+		}
+		cal.comps = append(cal.comps, itipComponent(c.GetMethod(), event))
+	}
+	return cal
+}
+
+// Serialize returns the calendar with its events in the iCalendar format
+func (c *Calendar) Serialize() string {
+	return c.component().String()
+}
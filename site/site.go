@@ -0,0 +1,420 @@
+// Package site generates a static HTML site from calendars of
+// github.com/PuloV/ics-golang with a month grid, a week view, a page per
+// event and an ICS download of each event.
+//
+// The generated directory looks like
+//
+//	index.html             redirects to the month of Now
+//	style.css
+//	months/2024-06.html    month grid
+//	weeks/2024-06-10.html  week view, named by the first day of the week
+//	events/<id>.html       event page, <id> is Event.GetID()
+//	events/<id>.ics        ICS download, repeating events share the file of the series
+package site
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// the number of calendar colors in the style sheet
+const colors = 8
+
+// Site is the configuration of a generated site
+type Site struct {
+	// the title of the pages, the names of the calendars by default
+	Title string
+	// the location of the times and days, UTC by default
+	Location *time.Location
+	// the first day of the weeks, Monday by default
+	WeekStart time.Weekday
+	// the highlighted day and the month of the index, time.Now() by default
+	Now time.Time
+
+	calendars []*ics.Calendar
+}
+
+// New creates a site of the calendars
+func New(calendars ...*ics.Calendar) *Site {
+	names := []string{}
+	for _, cal := range calendars {
+		if cal.GetName() != "" {
+			names = append(names, cal.GetName())
+		}
+	}
+	return &Site{
+		Title:     strings.Join(names, ", "),
+		Location:  time.UTC,
+		WeekStart: time.Monday,
+		Now:       time.Now(),
+		calendars: calendars,
+	}
+}
+
+// occurrence is an event placed in the site
+type occurrence struct {
+	Event    *ics.Event
+	Calendar *ics.Calendar
+	Color    int
+	Start    time.Time
+	End      time.Time
+	Days     []time.Time
+	// the page and the ICS download relative to the site root
+	Page string
+	ICS  string
+}
+
+func (o *occurrence) Summary() string     { return o.Event.GetPlainSummary() }
+func (o *occurrence) Description() string { return o.Event.GetPlainDescription() }
+func (o *occurrence) Location() string    { return o.Event.GetPlainLocation() }
+
+// Clock returns the time of a single day occurrence like "09:00-10:00"
+func (o *occurrence) Clock() string {
+	if o.Start.Equal(o.End) {
+		return o.Start.Format("15:04")
+	}
+	return o.Start.Format("15:04") + "-" + o.End.Format("15:04")
+}
+
+// When returns the dates and times of the occurrence
+func (o *occurrence) When() string {
+	const date = "Monday, 2 January 2006"
+	first, last := o.Days[0], o.Days[len(o.Days)-1]
+	switch {
+	case o.Event.IsWholeDay() && first.Equal(last):
+		return first.Format(date)
+	case o.Event.IsWholeDay():
+		return first.Format(date) + " - " + last.Format(date)
+	case first.Equal(last):
+		return first.Format(date) + " " + o.Clock()
+	}
+	return o.Start.Format(date+" 15:04") + " - " + o.End.Format(date+" 15:04")
+}
+
+// Banner reports whether the occurrence is drawn as a bar over its days
+func (o *occurrence) Banner() bool {
+	return o.Event.IsWholeDay() || len(o.Days) > 1
+}
+
+// segment is the part of a banner in a week or a gap between banners
+type segment struct {
+	Occ       *occurrence
+	Span      int
+	Continued bool
+	Continues bool
+}
+
+// week is a row of the month grid or a week page
+type week struct {
+	Start time.Time
+	Page  string
+	Days  []*day
+	// rows of banners spanning their days
+	Lanes [][]*segment
+}
+
+// day is a cell of a week
+type day struct {
+	Date    time.Time
+	InMonth bool
+	Today   bool
+	Items   []*occurrence
+}
+
+type monthPage struct {
+	Title    string
+	Month    time.Time
+	Prev     string
+	Next     string
+	Weekdays []string
+	Weeks    []*week
+}
+
+type weekPage struct {
+	Title    string
+	Week     *week
+	Prev     string
+	Next     string
+	Month    string
+	Weekdays []string
+}
+
+type eventPage struct {
+	Title string
+	Occ   *occurrence
+	Month string
+	Week  string
+}
+
+// Generate writes the site to the directory, existing files are overwritten
+func (s *Site) Generate(dir string) error {
+	occurrences := s.collect()
+
+	byDay := map[string][]*occurrence{}
+	first := s.day(s.Now)
+	last := first
+	for _, o := range occurrences {
+		for _, d := range o.Days {
+			byDay[d.Format(ics.YmdHis)] = append(byDay[d.Format(ics.YmdHis)], o)
+			if d.Before(first) {
+				first = d
+			}
+			if d.After(last) {
+				last = d
+			}
+		}
+	}
+
+	for _, sub := range []string{"months", "weeks", "events"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "style.css"), []byte(style), 0644); err != nil {
+		return err
+	}
+	index := fmt.Sprintf(`<!DOCTYPE html><meta http-equiv="refresh" content="0; url=%s"><a href="%s">%s</a>`,
+		s.monthPage(s.Now), s.monthPage(s.Now), template.HTMLEscapeString(s.Title))
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte(index), 0644); err != nil {
+		return err
+	}
+
+	weekdays := []string{}
+	for i := 0; i < 7; i++ {
+		weekdays = append(weekdays, time.Weekday((int(s.WeekStart) + i) % 7).String()[:3])
+	}
+
+	// months
+	for month := s.month(first); !month.After(last); month = month.AddDate(0, 1, 0) {
+		page := &monthPage{
+			Title:    s.Title,
+			Month:    month,
+			Prev:     s.monthPage(month.AddDate(0, -1, 0)),
+			Next:     s.monthPage(month.AddDate(0, 1, 0)),
+			Weekdays: weekdays,
+		}
+		for start := s.weekStart(month); start.Before(month.AddDate(0, 1, 0)); start = start.AddDate(0, 0, 7) {
+			page.Weeks = append(page.Weeks, s.week(start, month, byDay))
+		}
+		if err := s.write(dir, s.monthPage(month), "month", page); err != nil {
+			return err
+		}
+	}
+
+	// weeks
+	for start := s.weekStart(first); !start.After(last); start = start.AddDate(0, 0, 7) {
+		page := &weekPage{
+			Title:    s.Title,
+			Week:     s.week(start, time.Time{}, byDay),
+			Prev:     s.weekPage(start.AddDate(0, 0, -7)),
+			Next:     s.weekPage(start.AddDate(0, 0, 7)),
+			Month:    s.monthPage(start),
+			Weekdays: weekdays,
+		}
+		if err := s.write(dir, page.Week.Page, "week", page); err != nil {
+			return err
+		}
+	}
+
+	// events
+	for _, o := range occurrences {
+		page := &eventPage{
+			Title: s.Title,
+			Occ:   o,
+			Month: s.monthPage(o.Start),
+			Week:  s.weekPage(s.weekStart(o.Days[0])),
+		}
+		if err := s.write(dir, o.Page, "event", page); err != nil {
+			return err
+		}
+		if o.ICS != "events/"+o.Event.GetID()+".ics" {
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, o.ICS), []byte(download(o)), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collects the events of the calendars once, in the order of their start
+func (s *Site) collect() []*occurrence {
+	occurrences := []*occurrence{}
+	seen := map[*ics.Event]bool{}
+	for ci, cal := range s.calendars {
+		// the index by date holds every event, multi day events under each day
+		for _, events := range cal.GetEventsByDates() {
+			for _, event := range events {
+				if seen[event] {
+					continue
+				}
+				seen[event] = true
+				start, end := event.Span(s.Location)
+				occurrences = append(occurrences, &occurrence{
+					Event:    event,
+					Calendar: cal,
+					Color:    ci % colors,
+					Start:    start,
+					End:      end,
+					Days:     event.Days(s.Location),
+					Page:     "events/" + event.GetID() + ".html",
+				})
+			}
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		x, y := occurrences[i], occurrences[j]
+		if x.Banner() != y.Banner() {
+			return x.Banner()
+		}
+		if !x.Start.Equal(y.Start) {
+			return x.Start.Before(y.Start)
+		}
+		if !x.End.Equal(y.End) {
+			return x.End.After(y.End)
+		}
+		return x.Event.GetID() < y.Event.GetID()
+	})
+
+	// the occurrences of a series share the download of the series
+	series := map[string]string{}
+	for _, o := range occurrences {
+		o.ICS = "events/" + o.Event.GetID() + ".ics"
+		if o.Event.GetRRule() == "" || o.Event.GetImportedID() == "" {
+			continue
+		}
+		if first, ok := series[o.Event.GetImportedID()]; ok {
+			o.ICS = first
+		} else {
+			series[o.Event.GetImportedID()] = o.ICS
+		}
+	}
+	return occurrences
+}
+
+// builds the week starting at start, month is zero for week pages
+func (s *Site) week(start, month time.Time, byDay map[string][]*occurrence) *week {
+	w := &week{Start: start, Page: s.weekPage(start)}
+	today := s.day(s.Now)
+	end := start.AddDate(0, 0, 7)
+	banners := []*occurrence{}
+	seen := map[*occurrence]bool{}
+	for i := 0; i < 7; i++ {
+		date := start.AddDate(0, 0, i)
+		d := &day{
+			Date:    date,
+			InMonth: month.IsZero() || date.Month() == month.Month(),
+			Today:   date.Equal(today),
+		}
+		for _, o := range byDay[date.Format(ics.YmdHis)] {
+			if !o.Banner() {
+				d.Items = append(d.Items, o)
+			} else if !seen[o] {
+				seen[o] = true
+				banners = append(banners, o)
+			}
+		}
+		w.Days = append(w.Days, d)
+	}
+
+	// interval packing: each banner goes to the first lane where it fits
+	ends := []int{}
+	for _, o := range banners {
+		from, to := 7, -1
+		for _, d := range o.Days {
+			if !d.Before(start) && d.Before(end) {
+				col := s.column(start, d)
+				if col < from {
+					from = col
+				}
+				if col > to {
+					to = col
+				}
+			}
+		}
+		lane := 0
+		for lane < len(ends) && ends[lane] >= from {
+			lane++
+		}
+		if lane == len(ends) {
+			ends = append(ends, -1)
+			w.Lanes = append(w.Lanes, nil)
+		}
+		if gap := from - ends[lane] - 1; gap > 0 {
+			w.Lanes[lane] = append(w.Lanes[lane], &segment{Span: gap})
+		}
+		w.Lanes[lane] = append(w.Lanes[lane], &segment{
+			Occ:       o,
+			Span:      to - from + 1,
+			Continued: o.Days[0].Before(start),
+			Continues: !o.Days[len(o.Days)-1].Before(end),
+		})
+		ends[lane] = to
+	}
+	for lane := range w.Lanes {
+		if gap := 6 - ends[lane]; gap > 0 {
+			w.Lanes[lane] = append(w.Lanes[lane], &segment{Span: gap})
+		}
+	}
+	return w
+}
+
+// returns the column of the day in the week, days are counted by date so
+// the daylight saving changes don't matter
+func (s *Site) column(start, d time.Time) int {
+	col := 0
+	for day := start; day.Before(d); day = day.AddDate(0, 0, 1) {
+		col++
+	}
+	return col
+}
+
+func (s *Site) day(t time.Time) time.Time {
+	t = t.In(s.Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.Location)
+}
+
+func (s *Site) month(t time.Time) time.Time {
+	t = t.In(s.Location)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.Location)
+}
+
+func (s *Site) weekStart(t time.Time) time.Time {
+	d := s.day(t)
+	return d.AddDate(0, 0, -((int(d.Weekday()) - int(s.WeekStart) + 7) % 7))
+}
+
+func (s *Site) monthPage(t time.Time) string {
+	return "months/" + s.month(t).Format("2006-01") + ".html"
+}
+
+func (s *Site) weekPage(t time.Time) string {
+	return "weeks/" + s.day(t).Format("2006-01-02") + ".html"
+}
+
+// executes the template to the page
+func (s *Site) write(dir, page, name string, data interface{}) error {
+	var b bytes.Buffer
+	if err := pages.ExecuteTemplate(&b, name, data); err != nil {
+		return fmt.Errorf("site: failed to render %s ( %s )", page, err)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, page), b.Bytes(), 0644)
+}
+
+// returns the calendar downloaded for the occurrence
+func download(o *occurrence) string {
+	cal := ics.NewCalendar()
+	cal.SetName(o.Calendar.GetName())
+	cal.SetTimezone(o.Calendar.GetTimezone())
+	cal.SetEvent(*o.Event)
+	return cal.Serialize()
+}
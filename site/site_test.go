@@ -0,0 +1,127 @@
+package site
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func generate(t *testing.T) (string, *ics.Calendar) {
+	review, err := ics.NewEventBuilder().UID("review@example.com").Summary("Design review").Location("Room 1").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), time.UTC).Duration(time.Hour).
+		Organizer("Boss", "boss@example.com").Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	offsite, _ := ics.NewEventBuilder().UID("offsite@example.com").Summary("Offsite").
+		AllDay(time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), 3).Build()
+	trip, _ := ics.NewEventBuilder().UID("trip@example.com").Summary("Trip <Berlin>").
+		AllDay(time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), 1).Build()
+	cal, err := ics.NewCalendarBuilder().Name("Team").Event(review).Event(offsite).Event(trip).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+
+	dir, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(cal)
+	s.Now = time.Date(2024, 6, 11, 12, 0, 0, 0, time.UTC)
+	if err := s.Generate(dir); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Failed to generate the site ( %s )", err)
+	}
+	return dir, cal
+}
+
+func readPage(t *testing.T, dir, page string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, page))
+	if err != nil {
+		t.Fatalf("Expected page %s ( %s )", page, err)
+	}
+	return string(data)
+}
+
+func TestGenerate(t *testing.T) {
+	dir, cal := generate(t)
+	defer os.RemoveAll(dir)
+
+	for _, page := range []string{"index.html", "style.css", "months/2024-06.html", "weeks/2024-06-10.html", "weeks/2024-06-17.html"} {
+		readPage(t, dir, page)
+	}
+	if index := readPage(t, dir, "index.html"); !strings.Contains(index, "months/2024-06.html") {
+		t.Errorf("Expected the index to redirect to the month of now, found %s", index)
+	}
+
+	month := readPage(t, dir, "months/2024-06.html")
+	for _, fragment := range []string{
+		`<h1>June 2024</h1>`,
+		`<td class=" today"><a href="../weeks/2024-06-10.html">11</a></td>`,
+		`<td class="other"><a href="../weeks/2024-05-27.html">31</a></td>`,
+		`<span class="time">09:00</span> Design review`,
+		`Trip &lt;Berlin&gt;`,
+	} {
+		if !strings.Contains(month, fragment) {
+			t.Errorf("Expected the month to contain %s, found:\n%s", fragment, month)
+		}
+	}
+
+	// the offsite spans saturday and sunday of the first week and monday of
+	// the next, the trip goes to a second lane on sunday
+	week := readPage(t, dir, "weeks/2024-06-10.html")
+	lanes := regexp.MustCompile(`<tr class="lane">.*</tr>`).FindAllString(week, -1)
+	if len(lanes) != 2 {
+		t.Fatalf("Expected 2 lanes in the week, found %d:\n%s", len(lanes), week)
+	}
+	if !strings.HasPrefix(lanes[0], `<tr class="lane"><td colspan="5"></td><td colspan="2"><a class="banner cal-0 continues"`) {
+		t.Errorf("Expected the offsite over the weekend, found %s", lanes[0])
+	}
+	if !strings.HasPrefix(lanes[1], `<tr class="lane"><td colspan="6"></td><td colspan="1"><a class="banner cal-0"`) {
+		t.Errorf("Expected the trip on sunday, found %s", lanes[1])
+	}
+	next := readPage(t, dir, "weeks/2024-06-17.html")
+	if !strings.Contains(next, `<tr class="lane"><td colspan="1"><a class="banner cal-0 continued"`) {
+		t.Errorf("Expected the offsite continued on monday, found:\n%s", next)
+	}
+
+	event, _ := cal.GetEventByImportedID("review@example.com")
+	page := readPage(t, dir, "events/"+event.GetID()+".html")
+	for _, fragment := range []string{
+		`<dt>When</dt><dd>Monday, 10 June 2024 09:00-10:00</dd>`,
+		`<dt>Where</dt><dd>Room 1</dd>`,
+		`<dd>Boss &lt;boss@example.com&gt;</dd>`,
+		`href="../events/` + event.GetID() + `.ics"`,
+	} {
+		if !strings.Contains(page, fragment) {
+			t.Errorf("Expected the event page to contain %s, found:\n%s", fragment, page)
+		}
+	}
+	download := readPage(t, dir, "events/"+event.GetID()+".ics")
+	if !strings.Contains(download, "UID:review@example.com") || !strings.HasPrefix(download, "BEGIN:VCALENDAR") {
+		t.Errorf("Expected the calendar of the event, found:\n%s", download)
+	}
+}
+
+func TestOccurrenceTexts(t *testing.T) {
+	// the texts of the events that were never escaped used to lose their backslashes
+	o := &occurrence{Event: ics.NewEvent().SetSummary(`regex \d+\n`).SetDescription(`a\b`).SetLocation(`\\fileserver`)}
+	if o.Summary() != `regex \d+\n` || o.Description() != `a\b` || o.Location() != `\\fileserver` {
+		t.Errorf("Expected the texts unchanged, found %q, %q and %q", o.Summary(), o.Description(), o.Location())
+	}
+
+	parser := ics.New()
+	parser.Load("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:texts@example.com\r\nDTSTART:20240610T090000Z\r\n" +
+		"SUMMARY:Design\\, review\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	calendars, _ := parser.GetCalendars()
+	parsed := calendars[0].GetEvents()[0]
+	if summary := (&occurrence{Event: &parsed}).Summary(); summary != "Design, review" {
+		t.Errorf("Expected the parsed summary unescaped, found %q", summary)
+	}
+}
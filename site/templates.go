@@ -0,0 +1,129 @@
+package site
+
+import "html/template"
+
+// the pages are one directory deep, links go through the site root
+var pages = template.Must(template.New("pages").Parse(`
+{{- define "head" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<link rel="stylesheet" href="../style.css">
+</head>
+<body>
+{{- end}}
+
+{{- define "foot"}}
+</body>
+</html>
+{{end}}
+
+{{- define "banner" -}}
+{{- if .Occ}}<td colspan="{{.Span}}"><a class="banner cal-{{.Occ.Color}}{{if .Continued}} continued{{end}}{{if .Continues}} continues{{end}}" href="../{{.Occ.Page}}">{{.Occ.Summary}}</a></td>
+{{- else}}<td colspan="{{.Span}}"></td>
+{{- end}}
+{{- end}}
+
+{{- define "lanes"}}
+{{- range .Lanes}}
+<tr class="lane">{{range .}}{{template "banner" .}}{{end}}</tr>
+{{- end}}
+{{- end}}
+
+{{- define "class"}}{{if not .InMonth}}other{{end}}{{if .Today}} today{{end}}{{end}}
+
+{{- define "month" -}}
+{{template "head" .Title}}
+<nav><a href="../{{.Prev}}">&larr;</a> <h1>{{.Month.Format "January 2006"}}</h1> <a href="../{{.Next}}">&rarr;</a></nav>
+<table class="month">
+<thead><tr>{{range .Weekdays}}<th>{{.}}</th>{{end}}</tr></thead>
+{{- range .Weeks}}
+<tbody class="week">
+<tr class="dates">{{$page := .Page}}{{range .Days}}<td class="{{template "class" .}}"><a href="../{{$page}}">{{.Date.Day}}</a></td>{{end}}</tr>
+{{- template "lanes" .}}
+<tr class="items">{{range .Days}}<td class="{{template "class" .}}"><ul>
+{{- range .Items}}<li class="cal-{{.Color}}"><a href="../{{.Page}}"><span class="time">{{.Start.Format "15:04"}}</span> {{.Summary}}</a></li>{{end -}}
+</ul></td>{{end}}</tr>
+</tbody>
+{{- end}}
+</table>
+{{- template "foot"}}
+{{- end}}
+
+{{- define "week" -}}
+{{template "head" .Title}}
+<nav><a href="../{{.Prev}}">&larr;</a> <h1>Week of {{.Week.Start.Format "2 January 2006"}}</h1> <a href="../{{.Next}}">&rarr;</a> <a href="../{{.Month}}">Month</a></nav>
+<table class="week">
+<thead><tr>{{range .Week.Days}}<th class="{{template "class" .}}">{{.Date.Format "Mon 2 Jan"}}</th>{{end}}</tr></thead>
+<tbody>
+{{- template "lanes" .Week}}
+<tr class="items">{{range .Week.Days}}<td class="{{template "class" .}}"><ul>
+{{- range .Items}}<li class="cal-{{.Color}}"><a href="../{{.Page}}"><span class="time">{{.Clock}}</span> {{.Summary}}</a>{{with .Location}}<br><small>{{.}}</small>{{end}}</li>{{end -}}
+</ul></td>{{end}}</tr>
+</tbody>
+</table>
+{{- template "foot"}}
+{{- end}}
+
+{{- define "event" -}}
+{{template "head" .Occ.Summary}}
+<nav><a href="../{{.Month}}">Month</a> <a href="../{{.Week}}">Week</a></nav>
+<h1>{{.Occ.Summary}}</h1>
+<dl>
+<dt>When</dt><dd>{{.Occ.When}}</dd>
+{{- with .Occ.Location}}
+<dt>Where</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Occ.Event.GetConference}}
+<dt>Online</dt><dd><a href="{{.}}">{{.}}</a></dd>
+{{- end}}
+{{- with .Occ.Calendar.GetName}}
+<dt>Calendar</dt><dd>{{.}}</dd>
+{{- end}}
+{{- with .Occ.Event.GetOrganizer}}
+<dt>Organizer</dt><dd>{{.GetName}} &lt;{{.GetEmail}}&gt;</dd>
+{{- end}}
+{{- with .Occ.Event.GetAttendees}}
+<dt>Attendees</dt>
+{{- range .}}
+<dd>{{.GetName}} &lt;{{.GetEmail}}&gt;{{with .GetStatus}} ({{.}}){{end}}</dd>
+{{- end}}
+{{- end}}
+{{- with .Occ.Description}}
+<dt>Description</dt><dd class="description">{{.}}</dd>
+{{- end}}
+</dl>
+<p><a class="download" href="../{{.Occ.ICS}}" download>Add to calendar (.ics)</a></p>
+{{- template "foot"}}
+{{- end}}
+`))
+
+// the style sheet of the site
+const style = `body { font-family: sans-serif; margin: 1em; }
+nav { display: flex; align-items: center; gap: 1em; }
+nav h1 { margin: 0; }
+table { border-collapse: collapse; table-layout: fixed; width: 100%; }
+th, td { border: 1px solid #ddd; vertical-align: top; padding: 2px 4px; }
+tr.lane td, tr.items td { border-top: none; border-bottom: none; padding: 1px 2px; }
+td.other { color: #aaa; background: #fafafa; }
+td.today { background: #fff8d0; font-weight: bold; }
+ul { list-style: none; margin: 0; padding: 0; }
+li { font-size: 0.85em; overflow: hidden; white-space: nowrap; text-overflow: ellipsis; }
+a.banner { display: block; color: #fff; border-radius: 3px; padding: 0 4px; font-size: 0.85em;
+  overflow: hidden; white-space: nowrap; text-overflow: ellipsis; text-decoration: none; }
+a.continued { border-top-left-radius: 0; border-bottom-left-radius: 0; }
+a.continues { border-top-right-radius: 0; border-bottom-right-radius: 0; }
+.time { color: #666; }
+dt { font-weight: bold; margin-top: 0.5em; }
+dd.description { white-space: pre-line; }
+.cal-0 { background: #3a87ad; } .cal-1 { background: #d9534f; } .cal-2 { background: #5cb85c; }
+.cal-3 { background: #f0ad4e; } .cal-4 { background: #8e44ad; } .cal-5 { background: #16a085; }
+.cal-6 { background: #7f8c8d; } .cal-7 { background: #c0392b; }
+li.cal-0, li.cal-1, li.cal-2, li.cal-3, li.cal-4, li.cal-5, li.cal-6, li.cal-7 { background: none; }
+li::before { content: "\25CF "; }
+li.cal-0::before { color: #3a87ad; } li.cal-1::before { color: #d9534f; } li.cal-2::before { color: #5cb85c; }
+li.cal-3::before { color: #f0ad4e; } li.cal-4::before { color: #8e44ad; } li.cal-5::before { color: #16a085; }
+li.cal-6::before { color: #7f8c8d; } li.cal-7::before { color: #c0392b; }
+`
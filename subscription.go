@@ -0,0 +1,343 @@
+package ics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeType is the kind of a change of an event of a subscribed url
+type ChangeType string
+
+const (
+	EventAdded   ChangeType = "added"
+	EventUpdated ChangeType = "updated"
+	EventRemoved ChangeType = "removed"
+)
+
+// Change is a change of an event of a subscribed url
+type Change struct {
+	Type ChangeType
+	URL  string
+	// the event after the change, the removed one for EventRemoved
+	Event *Event
+	// the event before an update
+	Previous *Event
+}
+
+// Subscription refreshes calendar urls on their own intervals and sends the
+// changes of their events since the previous refresh on the Changes chan.
+// The events are matched by UID, and by start for the repeated ones.
+type Subscription struct {
+	// the interval of the urls without their own one and without a
+	// REFRESH-INTERVAL or X-PUBLISHED-TTL
+	DefaultInterval time.Duration
+	// the shortest interval, the published ones don't go below it
+	MinInterval time.Duration
+
+	parser  *Parser
+	changes chan Change
+	mutex   sync.Mutex
+	urls    map[string]*subscribedURL
+	wake    chan struct{}
+	stop    chan struct{}
+}
+
+// subscribedURL is the state of an url of a subscription
+type subscribedURL struct {
+	// the interval given for the url, 0 uses the published one
+	interval   time.Duration
+	next       time.Time
+	refreshing bool
+	calendars  []*Calendar
+	events     map[string]*Event
+	// one refresh of the url at a time
+	refresh sync.Mutex
+}
+
+// Subscribe creates a subscription fetching with the fetchers, limits and
+// policy of the parser. The refreshes are recorded in the health of the
+// parser, the calendars are not added to it.
+func (p *Parser) Subscribe() *Subscription {
+	return &Subscription{
+		DefaultInterval: time.Hour,
+		MinInterval:     time.Minute,
+		parser:          p,
+		changes:         make(chan Change, 100),
+		urls:            map[string]*subscribedURL{},
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// Changes returns the chan of the changes, it must be read for the
+// refreshes to go on
+func (s *Subscription) Changes() <-chan Change {
+	return s.changes
+}
+
+// Add subscribes to the url, refreshed on the interval or on the published
+// one of its calendar when the interval is 0. The first refresh is due now.
+func (s *Subscription) Add(url string, interval time.Duration) {
+	s.mutex.Lock()
+	if sub, ok := s.urls[url]; ok {
+		sub.interval = interval
+	} else {
+		s.urls[url] = &subscribedURL{interval: interval, events: map[string]*Event{}}
+	}
+	s.mutex.Unlock()
+	s.wakeUp()
+}
+
+// Remove unsubscribes from the url and sends the removal of its events
+func (s *Subscription) Remove(url string) {
+	s.mutex.Lock()
+	sub, ok := s.urls[url]
+	delete(s.urls, url)
+	s.mutex.Unlock()
+	if !ok {
+		return
+	}
+	sub.refresh.Lock()
+	defer sub.refresh.Unlock()
+	s.send(diffEvents(url, sub.events, nil))
+}
+
+// GetURLs returns the subscribed urls, ordered
+func (s *Subscription) GetURLs() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	urls := []string{}
+	for url := range s.urls {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// GetCalendars returns the last calendars of the subscribed urls
+func (s *Subscription) GetCalendars() []*Calendar {
+	calendars := []*Calendar{}
+	for _, url := range s.GetURLs() {
+		s.mutex.Lock()
+		if sub, ok := s.urls[url]; ok {
+			calendars = append(calendars, sub.calendars...)
+		}
+		s.mutex.Unlock()
+	}
+	return calendars
+}
+
+// GetNextRefresh returns the time of the next refresh of the url, false if
+// it is not subscribed
+func (s *Subscription) GetNextRefresh(url string) (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sub, ok := s.urls[url]
+	if !ok {
+		return time.Time{}, false
+	}
+	return sub.next, true
+}
+
+// Refresh fetches the url now, sends the changes of its events and returns
+// the error of the fetch. A failed url is tried again after its interval.
+func (s *Subscription) Refresh(url string) error {
+	s.mutex.Lock()
+	sub, ok := s.urls[url]
+	s.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("%s is not subscribed", s.parser.redact(url))
+	}
+	sub.refresh.Lock()
+	defer sub.refresh.Unlock()
+
+	files, notModified, err := s.parser.getICal(url)
+	s.parser.recordHealth(url, err)
+	stale, isStale := err.(*staleError)
+	if err != nil && !isStale || (notModified || isStale) && sub.calendars != nil {
+		s.schedule(sub, sub.calendars)
+		if isStale {
+			return stale.err
+		}
+		return err
+	}
+
+	calendars := []*Calendar{}
+	events := map[string]*Event{}
+	for _, file := range files {
+		cal, _, err := s.parser.parseCalendar(file.content, url)
+		if err != nil {
+			s.schedule(sub, sub.calendars)
+			return err
+		}
+		cal.SetArchivePath(file.path)
+		calendars = append(calendars, cal)
+		calEvents := cal.GetEvents()
+		for i := range calEvents {
+			events[eventKey(&calEvents[i])] = &calEvents[i]
+		}
+	}
+
+	s.mutex.Lock()
+	if s.urls[url] != sub {
+		// removed during the fetch, its removal is sent by Remove
+		s.mutex.Unlock()
+		return fmt.Errorf("%s is not subscribed", s.parser.redact(url))
+	}
+	changes := diffEvents(url, sub.events, events)
+	sub.calendars = calendars
+	sub.events = events
+	s.mutex.Unlock()
+	s.schedule(sub, calendars)
+	s.send(changes)
+	if isStale {
+		return stale.err
+	}
+	return nil
+}
+
+// sets the next refresh of the url from the interval of the calendars
+func (s *Subscription) schedule(sub *subscribedURL, calendars []*Calendar) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	interval := sub.interval
+	if interval == 0 {
+		for _, cal := range calendars {
+			if r := cal.GetRefreshInterval(); r > 0 && (interval == 0 || r < interval) {
+				interval = r
+			}
+		}
+	}
+	if interval == 0 {
+		interval = s.DefaultInterval
+	}
+	if interval < s.MinInterval {
+		interval = s.MinInterval
+	}
+	sub.next = time.Now().Add(interval)
+}
+
+func (s *Subscription) send(changes []Change) {
+	for _, change := range changes {
+		s.changes <- change
+	}
+}
+
+// Start refreshes the urls in the background when they are due
+func (s *Subscription) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	go s.run(s.stop)
+}
+
+// Stop ends the background refreshes, the running ones still finish
+func (s *Subscription) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+func (s *Subscription) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) run(stop chan struct{}) {
+	for {
+		now := time.Now()
+		next := now.Add(time.Hour)
+		s.mutex.Lock()
+		for url, sub := range s.urls {
+			if sub.refreshing {
+				continue
+			}
+			if !sub.next.After(now) {
+				sub.refreshing = true
+				go func(url string, sub *subscribedURL) {
+					s.Refresh(url)
+					s.mutex.Lock()
+					sub.refreshing = false
+					s.mutex.Unlock()
+					s.wakeUp()
+				}(url, sub)
+			} else if sub.next.Before(next) {
+				next = sub.next
+			}
+		}
+		s.mutex.Unlock()
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// returns the key matching the event between two refreshes
+func eventKey(e *Event) string {
+	uid := e.GetImportedID()
+	if uid == "" {
+		return e.GetID()
+	}
+	if !e.GetRecurrenceID().IsZero() {
+		return uid + "/" + e.GetRecurrenceID().UTC().Format(IcsFormat)
+	}
+	if e.GetRRule() != "" {
+		return uid + "/" + e.GetStart().UTC().Format(IcsFormat)
+	}
+	return uid
+}
+
+// returns the content of the event compared between two refreshes, without
+// the DTSTAMP that some servers set to the time of the export
+func eventContent(e *Event) string {
+	lines := []string{}
+	for _, line := range strings.Split(e.Serialize(), "\r\n") {
+		if !strings.HasPrefix(line, "DTSTAMP") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// returns the changes from the old to the new events, ordered by start
+func diffEvents(url string, old, new map[string]*Event) []Change {
+	changes := []Change{}
+	for key, event := range new {
+		previous, ok := old[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: EventAdded, URL: url, Event: event})
+		case eventContent(previous) != eventContent(event):
+			changes = append(changes, Change{Type: EventUpdated, URL: url, Event: event, Previous: previous})
+		}
+	}
+	for key, event := range old {
+		if _, ok := new[key]; !ok {
+			changes = append(changes, Change{Type: EventRemoved, URL: url, Event: event})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].Event.GetStart().Equal(changes[j].Event.GetStart()) {
+			return changes[i].Event.GetStart().Before(changes[j].Event.GetStart())
+		}
+		return eventKey(changes[i].Event) < eventKey(changes[j].Event)
+	})
+	return changes
+}
@@ -0,0 +1,225 @@
+package ics
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const subscriptionTestCal = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nX-WR-CALNAME:Feed\r\n%s" +
+	"BEGIN:VEVENT\r\nUID:daily\r\nDTSTART:20200102T100000Z\r\nDTEND:20200102T110000Z\r\nSUMMARY:Daily\r\nRRULE:FREQ=DAILY;COUNT=2\r\nEND:VEVENT\r\n" +
+	"%s" +
+	"END:VCALENDAR\r\n"
+
+// a fetcher of the "feed" scheme returning the content set by the test
+type feedFetcher struct {
+	mutex   sync.Mutex
+	content string
+	err     error
+}
+
+func (f *feedFetcher) set(content string, err error) {
+	f.mutex.Lock()
+	f.content, f.err = content, err
+	f.mutex.Unlock()
+}
+
+func (f *feedFetcher) Fetch(url string) (io.ReadCloser, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return ioutil.NopCloser(strings.NewReader(f.content)), nil
+}
+
+func feedContent(header, events string) string {
+	return strings.Replace(strings.Replace(subscriptionTestCal, "%s", header, 1), "%s", events, 1)
+}
+
+// returns the changes sent, as "type uid summary"
+func readChanges(s *Subscription) []string {
+	found := []string{}
+	for {
+		select {
+		case change := <-s.Changes():
+			found = append(found, string(change.Type)+" "+change.Event.GetImportedID()+" "+change.Event.GetSummary())
+		default:
+			return found
+		}
+	}
+}
+
+func TestSubscriptionChanges(t *testing.T) {
+	feed := &feedFetcher{}
+	parser := New()
+	parser.RegisterFetcher("feed", feed)
+	s := parser.Subscribe()
+	s.Add("feed://cal", 0)
+
+	once := "BEGIN:VEVENT\r\nUID:once\r\nDTSTART:20200105T100000Z\r\nDTEND:20200105T110000Z\r\nDTSTAMP:20200101T000000Z\r\nSUMMARY:Once\r\nEND:VEVENT\r\n"
+	steps := []struct {
+		content  string
+		expected []string
+	}{
+		{feedContent("", once), []string{"added daily Daily", "added daily Daily", "added once Once"}},
+		// only the DTSTAMP changes
+		{feedContent("", strings.Replace(once, "20200101T000000Z", "20200201T000000Z", 1)), []string{}},
+		{feedContent("", strings.Replace(once, "SUMMARY:Once", "SUMMARY:Moved", 1)), []string{"updated once Moved"}},
+		{feedContent("", ""), []string{"removed once Moved"}},
+	}
+	for i, step := range steps {
+		feed.set(step.content, nil)
+		if err := s.Refresh("feed://cal"); err != nil {
+			t.Fatalf("Expected no error, found %s", err)
+		}
+		found := readChanges(s)
+		if strings.Join(found, "\n") != strings.Join(step.expected, "\n") {
+			t.Errorf("Expected the changes of step %d\n%s\nfound\n%s", i, strings.Join(step.expected, "\n"), strings.Join(found, "\n"))
+		}
+	}
+
+	// a failed refresh keeps the last calendars
+	feed.set("", errors.New("offline"))
+	if err := s.Refresh("feed://cal"); err == nil || err.Error() != "offline" {
+		t.Errorf("Expected the error of the fetch, found %v", err)
+	}
+	if found := readChanges(s); len(found) != 0 {
+		t.Errorf("Expected no changes of a failed refresh, found %v", found)
+	}
+	if calendars := s.GetCalendars(); len(calendars) != 1 || len(calendars[0].GetEvents()) != 2 {
+		t.Errorf("Expected the last calendar with 2 events, found %d calendars", len(calendars))
+	}
+	if health, ok := parser.GetHealth("feed://cal"); !ok || health.ConsecutiveFailures != 1 {
+		t.Errorf("Expected the failure in the health of the parser, found %#v", health)
+	}
+
+	s.Remove("feed://cal")
+	if found := readChanges(s); len(found) != 2 || found[0] != "removed daily Daily" {
+		t.Errorf("Expected the removal of the 2 daily events, found %v", found)
+	}
+	if err := s.Refresh("feed://cal"); err == nil {
+		t.Errorf("Expected an error refreshing an url not subscribed")
+	}
+	if calendars, _ := parser.GetCalendars(); len(calendars) != 0 {
+		t.Errorf("Expected no calendars in the parser, found %d", len(calendars))
+	}
+}
+
+func TestSubscriptionIntervals(t *testing.T) {
+	feed := &feedFetcher{}
+	parser := New()
+	parser.RegisterFetcher("feed", feed)
+	s := parser.Subscribe()
+	cases := []struct {
+		header   string
+		interval time.Duration
+		expected time.Duration
+	}{
+		{"", 0, time.Hour},
+		{"X-PUBLISHED-TTL:P1W\r\n", 0, 7 * 24 * time.Hour},
+		{"X-PUBLISHED-TTL:PT6H\r\nREFRESH-INTERVAL;VALUE=DURATION:PT2H\r\n", 0, 2 * time.Hour},
+		{"X-PUBLISHED-TTL:PT10S\r\n", 0, time.Minute},
+		{"X-PUBLISHED-TTL:P1W\r\n", 5 * time.Minute, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		feed.set(feedContent(c.header, ""), nil)
+		s.Add("feed://cal", c.interval)
+		before := time.Now()
+		if err := s.Refresh("feed://cal"); err != nil {
+			t.Fatalf("Expected no error, found %s", err)
+		}
+		next, _ := s.GetNextRefresh("feed://cal")
+		if d := next.Sub(before); d < c.expected || d > c.expected+time.Second {
+			t.Errorf("Expected the refresh of %q in %s, found %s", c.header, c.expected, d)
+		}
+	}
+	readChanges(s)
+}
+
+func TestSubscriptionStart(t *testing.T) {
+	feed := &feedFetcher{}
+	feed.set(feedContent("", ""), nil)
+	parser := New()
+	parser.RegisterFetcher("feed", feed)
+	s := parser.Subscribe()
+	s.MinInterval = 0
+	s.Add("feed://cal", 10*time.Millisecond)
+	s.Start()
+	defer s.Stop()
+
+	timeout := time.After(5 * time.Second)
+	next := func() ChangeType {
+		select {
+		case change := <-s.Changes():
+			return change.Type
+		case <-timeout:
+			t.Fatalf("Expected a change of the background refresh")
+		}
+		return ""
+	}
+	if first, second := next(), next(); first != EventAdded || second != EventAdded {
+		t.Errorf("Expected 2 added events, found %s and %s", first, second)
+	}
+	feed.set(strings.Replace(feedContent("", ""), "UID:daily", "UID:renamed", 1), nil)
+	found := map[ChangeType]int{}
+	for i := 0; i < 4; i++ {
+		found[next()]++
+	}
+	if found[EventAdded] != 2 || found[EventRemoved] != 2 {
+		t.Errorf("Expected 2 added and 2 removed events, found %v", found)
+	}
+}
+
+// a fetcher waiting for its gate
+type gatedFetcher struct {
+	feedFetcher
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (f *gatedFetcher) Fetch(url string) (io.ReadCloser, error) {
+	f.entered <- struct{}{}
+	<-f.gate
+	return f.feedFetcher.Fetch(url)
+}
+
+func TestSubscriptionRemoveDuringRefresh(t *testing.T) {
+	feed := &gatedFetcher{entered: make(chan struct{}, 1), gate: make(chan struct{}, 1)}
+	feed.set(feedContent("", ""), nil)
+	parser := New()
+	parser.RegisterFetcher("feed", feed)
+	s := parser.Subscribe()
+	s.Add("feed://cal", 0)
+	feed.gate <- struct{}{}
+	s.Refresh("feed://cal")
+	<-feed.entered
+	readChanges(s)
+
+	feed.set(strings.Replace(feedContent("", ""), "UID:daily", "UID:renamed", 1), nil)
+	refreshed := make(chan error)
+	go func() { refreshed <- s.Refresh("feed://cal") }()
+	<-feed.entered
+	removed := make(chan struct{})
+	go func() {
+		s.Remove("feed://cal")
+		close(removed)
+	}()
+	for len(s.GetURLs()) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	feed.gate <- struct{}{}
+	if err := <-refreshed; err == nil {
+		t.Errorf("Expected an error refreshing an url removed during the fetch")
+	}
+	<-removed
+
+	expected := "removed daily Daily\nremoved daily Daily"
+	if found := strings.Join(readChanges(s), "\n"); found != expected {
+		t.Errorf("Expected only the removal of the events\n%s\nfound\n%s", expected, found)
+	}
+}
@@ -0,0 +1,322 @@
+// Package svg renders a week of events of github.com/PuloV/ics-golang
+// calendars as a SVG image for dashboards and PDFs.
+package svg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+// DefaultPalette is the colors of the calendars without a color in Colors
+var DefaultPalette = []string{"#3a87ad", "#d9534f", "#5cb85c", "#f0ad4e", "#8e44ad", "#16a085", "#7f8c8d", "#c0392b"}
+
+// Week renders the seven days starting at Start
+type Week struct {
+	// the first day of the week, the grid is drawn in its location
+	Start time.Time
+	// the width of the image in pixels
+	Width int
+	// the height of an hour in pixels
+	HourHeight int
+	// the visible hours, from FirstHour to LastHour (24 is midnight)
+	FirstHour int
+	LastHour  int
+	// the colors of the calendars by name
+	Colors map[string]string
+	// the colors of the other calendars by their index
+	Palette []string
+
+	calendars []*ics.Calendar
+}
+
+// NewWeek creates the week of the calendars starting at the midnight of start
+func NewWeek(start time.Time, calendars ...*ics.Calendar) *Week {
+	return &Week{
+		Start:      time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()),
+		Width:      800,
+		HourHeight: 40,
+		FirstHour:  0,
+		LastHour:   24,
+		Colors:     map[string]string{},
+		Palette:    DefaultPalette,
+		calendars:  calendars,
+	}
+}
+
+// the sizes of the parts of the image
+const (
+	gutter       = 48
+	headerHeight = 24
+	laneHeight   = 20
+	padding      = 2
+)
+
+// block is an event placed in the image
+type block struct {
+	event *ics.Event
+	color string
+	start time.Time
+	end   time.Time
+	// the column and the number of columns of the overlapping group
+	column  int
+	columns int
+	// the first and last day in the band
+	first int
+	last  int
+	lane  int
+}
+
+// Render writes the SVG image of the week
+func (w *Week) Render(out io.Writer) error {
+	loc := w.Start.Location()
+	days := make([]time.Time, 7)
+	for i := range days {
+		days[i] = w.Start.AddDate(0, 0, i)
+	}
+
+	timed := make([][]*block, 7)
+	band := []*block{}
+	inBand := map[*ics.Event]*block{}
+	for ci, cal := range w.calendars {
+		color := w.Colors[cal.GetName()]
+		if color == "" && len(w.Palette) > 0 {
+			color = w.Palette[ci%len(w.Palette)]
+		}
+		for d, day := range days {
+			for _, event := range w.eventsOn(cal, day) {
+				start, end := event.Span(loc)
+				if event.IsWholeDay() || end.Sub(start) >= 24*time.Hour {
+					if b, ok := inBand[event]; ok {
+						b.last = d
+						continue
+					}
+					b := &block{event: event, color: color, start: start, end: end, first: d, last: d}
+					inBand[event] = b
+					band = append(band, b)
+					continue
+				}
+				// the parts of events over midnight are drawn in each day
+				if start.Before(day) {
+					start = day
+				}
+				if next := day.AddDate(0, 0, 1); end.After(next) {
+					end = next
+				}
+				timed[d] = append(timed[d], &block{event: event, color: color, start: start, end: end})
+			}
+		}
+	}
+
+	lanes := packBand(band)
+	for _, blocks := range timed {
+		packColumns(blocks)
+	}
+
+	dayWidth := float64(w.Width-gutter) / 7
+	gridTop := headerHeight + lanes*laneHeight + padding
+	height := gridTop + (w.LastHour-w.FirstHour)*w.HourHeight
+
+	b := bufio.NewWriter(out)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		w.Width, height, w.Width, height)
+	fmt.Fprintf(b, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", w.Width, height)
+	fmt.Fprintf(b, `<text x="%d" y="16" fill="#666" text-anchor="end">%s</text>`+"\n", gutter-4, escape(w.Start.Format("MST")))
+
+	// day headers and columns
+	for d, day := range days {
+		x := float64(gutter) + float64(d)*dayWidth
+		fmt.Fprintf(b, `<text x="%.1f" y="16" text-anchor="middle" font-weight="bold">%s</text>`+"\n", x+dayWidth/2, escape(day.Format("Mon 2 Jan")))
+		fmt.Fprintf(b, `<line x1="%.1f" y1="0" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n", x, x, height)
+	}
+
+	// hour grid, the lines are at the hours of the wall clock in the location
+	for hour := w.FirstHour; hour <= w.LastHour; hour++ {
+		y := gridTop + (hour-w.FirstHour)*w.HourHeight
+		fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#eee"/>`+"\n", gutter, y, w.Width, y)
+		if hour < w.LastHour {
+			fmt.Fprintf(b, `<text x="%d" y="%d" fill="#666" text-anchor="end">%02d:00</text>`+"\n", gutter-4, y+11, hour)
+		}
+	}
+
+	// all day band
+	for _, bl := range band {
+		x := float64(gutter) + float64(bl.first)*dayWidth
+		y := headerHeight + bl.lane*laneHeight
+		width := float64(bl.last-bl.first+1) * dayWidth
+		w.writeBlock(b, bl, x+padding, float64(y), width-2*padding, laneHeight-padding)
+	}
+
+	// timed events
+	for d, blocks := range timed {
+		for _, bl := range blocks {
+			top := w.hourOffset(bl.start, days[d])
+			bottom := w.hourOffset(bl.end, days[d])
+			if bl.end.Equal(days[d].AddDate(0, 0, 1)) {
+				bottom = 24
+			}
+			top, bottom = clamp(top, float64(w.FirstHour), float64(w.LastHour)), clamp(bottom, float64(w.FirstHour), float64(w.LastHour))
+			if bottom <= top && !(bl.start.Equal(bl.end) && top < float64(w.LastHour)) {
+				continue
+			}
+			columnWidth := dayWidth / float64(bl.columns)
+			x := float64(gutter) + float64(d)*dayWidth + float64(bl.column)*columnWidth
+			y := float64(gridTop) + (top-float64(w.FirstHour))*float64(w.HourHeight)
+			h := (bottom - top) * float64(w.HourHeight)
+			// short events stay readable
+			if h < 14 {
+				h = 14
+			}
+			w.writeBlock(b, bl, x+padding, y+1, columnWidth-2*padding, h-2)
+		}
+	}
+
+	fmt.Fprintln(b, "</svg>")
+	return b.Flush()
+}
+
+// returns the events of the calendar on the day. The index of the calendar
+// is by the days of its own time zone so the neighbour days are looked up
+// as well and the events not on the day in the location are left out.
+func (w *Week) eventsOn(cal *ics.Calendar, day time.Time) []*ics.Event {
+	loc := day.Location()
+	events := []*ics.Event{}
+	seen := map[*ics.Event]bool{}
+	for _, lookup := range []time.Time{day.AddDate(0, 0, -1), day, day.AddDate(0, 0, 1)} {
+		found, err := cal.GetEventsByDate(lookup)
+		if err != nil {
+			continue
+		}
+		for _, event := range found {
+			if seen[event] {
+				continue
+			}
+			seen[event] = true
+			for _, d := range event.Days(loc) {
+				if d.Equal(day) {
+					events = append(events, event)
+					break
+				}
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].GetStart().Before(events[j].GetStart())
+	})
+	return events
+}
+
+// returns the hours of the wall clock of t since the midnight of day
+func (w *Week) hourOffset(t, day time.Time) float64 {
+	t = t.In(day.Location())
+	if t.Before(day) {
+		return 0
+	}
+	return float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+}
+
+// writes the rectangle of the event with its clipped summary
+func (w *Week) writeBlock(b *bufio.Writer, bl *block, x, y, width, height float64) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	summary := escape(strings.Replace(bl.event.GetPlainSummary(), "\n", " ", -1))
+	fmt.Fprintf(b, `<g><title>%s</title>`, summary)
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="3" fill="%s"/>`, x, y, width, height, escape(bl.color))
+	// the nested svg clips the text to the rectangle
+	fmt.Fprintf(b, `<svg x="%.1f" y="%.1f" width="%.1f" height="%.1f"><text x="3" y="12" fill="#fff">%s</text></svg></g>`+"\n",
+		x, y, width, height, summary)
+}
+
+// puts the overlapping events of a day side by side: the events are split in
+// groups of transitively overlapping events and each event takes the first
+// column of its group that is free at its start
+func packColumns(blocks []*block) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if !blocks[i].start.Equal(blocks[j].start) {
+			return blocks[i].start.Before(blocks[j].start)
+		}
+		return blocks[i].end.After(blocks[j].end)
+	})
+	group := []*block{}
+	columnEnds := []time.Time{}
+	var groupEnd time.Time
+	closeGroup := func() {
+		for _, bl := range group {
+			bl.columns = len(columnEnds)
+		}
+		group = group[:0]
+		columnEnds = columnEnds[:0]
+	}
+	for _, bl := range blocks {
+		end := bl.end
+		if end.Equal(bl.start) {
+			// instants take a minute so they can share columns
+			end = end.Add(time.Minute)
+		}
+		if len(group) > 0 && !bl.start.Before(groupEnd) {
+			closeGroup()
+		}
+		column := 0
+		for column < len(columnEnds) && columnEnds[column].After(bl.start) {
+			column++
+		}
+		if column == len(columnEnds) {
+			columnEnds = append(columnEnds, end)
+		} else {
+			columnEnds[column] = end
+		}
+		bl.column = column
+		group = append(group, bl)
+		if len(group) == 1 || end.After(groupEnd) {
+			groupEnd = end
+		}
+	}
+	closeGroup()
+}
+
+// puts the events of the band in lanes, each event in the first free lane,
+// and returns the number of lanes
+func packBand(band []*block) int {
+	sort.SliceStable(band, func(i, j int) bool {
+		if band[i].first != band[j].first {
+			return band[i].first < band[j].first
+		}
+		return band[i].last > band[j].last
+	})
+	ends := []int{}
+	for _, bl := range band {
+		lane := 0
+		for lane < len(ends) && ends[lane] >= bl.first {
+			lane++
+		}
+		if lane == len(ends) {
+			ends = append(ends, -1)
+		}
+		ends[lane] = bl.last
+		bl.lane = lane
+	}
+	return len(ends)
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func escape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
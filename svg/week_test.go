@@ -0,0 +1,107 @@
+package svg
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/PuloV/ics-golang"
+)
+
+func TestPackColumns(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 10, hour, minute, 0, 0, time.UTC)
+	}
+	blocks := []*block{
+		{start: at(9, 0), end: at(10, 0)},
+		{start: at(9, 30), end: at(11, 0)},
+		{start: at(10, 0), end: at(10, 30)},
+		{start: at(12, 0), end: at(13, 0)},
+	}
+	expected := [][2]int{{0, 2}, {1, 2}, {0, 2}, {0, 1}}
+	packColumns(blocks)
+	for i, bl := range blocks {
+		if bl.column != expected[i][0] || bl.columns != expected[i][1] {
+			t.Errorf("Expected block %d in column %d of %d, found %d of %d", i, expected[i][0], expected[i][1], bl.column, bl.columns)
+		}
+	}
+
+	band := []*block{{first: 0, last: 2}, {first: 1, last: 1}, {first: 3, last: 4}}
+	if lanes := packBand(band); lanes != 2 || band[1].lane != 1 || band[2].lane != 0 {
+		t.Errorf("Expected 2 lanes with the second event in the second, found %d lanes", lanes)
+	}
+}
+
+func TestRender(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	review, _ := ics.NewEventBuilder().UID("review@example.com").Summary("Design review").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, paris), paris).Duration(time.Hour).Build()
+	lunch, _ := ics.NewEventBuilder().UID("lunch@example.com").Summary("Lunch & learn").
+		Start(time.Date(2024, 6, 10, 9, 30, 0, 0, paris), paris).Duration(time.Hour).Build()
+	offsite, _ := ics.NewEventBuilder().UID("offsite@example.com").Summary("Offsite").
+		AllDay(time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC), 2).Build()
+	team, _ := ics.NewCalendarBuilder().Name("Team").Event(review).Event(offsite).Build()
+	// the events of this calendar are in UTC, the grid is in Paris
+	call, _ := ics.NewEventBuilder().UID("call@example.com").Summary("Call").
+		Start(time.Date(2024, 6, 11, 6, 0, 0, 0, time.UTC), time.UTC).Duration(30 * time.Minute).Build()
+	other, _ := ics.NewCalendarBuilder().Name("Other").Event(lunch).Event(call).Build()
+
+	week := NewWeek(time.Date(2024, 6, 10, 0, 0, 0, 0, paris), team, other)
+	week.Colors["Other"] = "#123456"
+	week.Width = 804
+	week.FirstHour = 8
+	week.LastHour = 18
+	var b bytes.Buffer
+	if err := week.Render(&b); err != nil {
+		t.Fatalf("Failed to render ( %s )", err)
+	}
+	image := b.String()
+
+	// gutter 48, 108 pixels a day, one lane in the band so the grid starts at 46
+	for _, fragment := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="804" height="446"`,
+		`>CEST</text>`,
+		`>Mon 10 Jun</text>`,
+		`<text x="44" y="57" fill="#666" text-anchor="end">08:00</text>`,
+		`<title>Offsite</title><rect x="266.0" y="24.0" width="212.0" height="18.0" rx="3" fill="#3a87ad"/>`,
+		`<title>Design review</title><rect x="50.0" y="87.0" width="50.0" height="38.0" rx="3" fill="#3a87ad"/>`,
+		`<title>Lunch &amp; learn</title><rect x="104.0" y="107.0" width="50.0" height="38.0" rx="3" fill="#123456"/>`,
+		`<title>Call</title><rect x="158.0" y="47.0" width="104.0" height="18.0" rx="3" fill="#123456"/>`,
+	} {
+		if !strings.Contains(image, fragment) {
+			t.Errorf("Expected the image to contain %s, found:\n%s", fragment, image)
+		}
+	}
+	if rects := regexp.MustCompile(`<rect x=`).FindAllString(image, -1); len(rects) != 4 {
+		t.Errorf("Expected 4 events, found %d", len(rects))
+	}
+}
+
+func TestBlockSummary(t *testing.T) {
+	parser := ics.New()
+	parser.Load("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:texts@example.com\r\nDTSTART:20240610T090000Z\r\n" +
+		"SUMMARY:Design\\, review\\nand lunch\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	calendars, _ := parser.GetCalendars()
+	parsed := calendars[0].GetEvents()[0]
+
+	week := NewWeek(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC))
+	for _, test := range []struct {
+		event    *ics.Event
+		expected string
+	}{
+		// the summaries of the events that were never escaped used to lose their backslashes
+		{ics.NewEvent().SetSummary(`regex \d+`), `<title>regex \d+</title>`},
+		{&parsed, `<title>Design, review and lunch</title>`},
+	} {
+		var b bytes.Buffer
+		w := bufio.NewWriter(&b)
+		week.writeBlock(w, &block{event: test.event, color: "#fff"}, 0, 0, 10, 10)
+		w.Flush()
+		if !strings.Contains(b.String(), test.expected) {
+			t.Errorf("Expected the block to contain %s, found %s", test.expected, b.String())
+		}
+	}
+}
@@ -2,7 +2,7 @@ package ics
 
 import (
 	"fmt"
-	// "io/ioutil"
+	"io/ioutil"
 	"strings"
 	// "errors"
 	"io"
@@ -41,42 +41,44 @@ const YmdHis = "2006-01-02 15:04:05"
 // ics date format ( describes a whole day)
 const IcsFormatWholeDay = "20060102"
 
-// downloads the calendar before parsing it
-func downloadFromUrl(url string) (string, error) {
-	// split the url to get the name of the file (like basic.ics)
-	tokens := strings.Split(url, "/")
-
-	// create the name of the file
-	fileName := fmt.Sprintf("%s%s_%s", FilePath, time.Now().Format(uts), tokens[len(tokens)-1])
+// downloads the calendar with the send function before parsing it
+func downloadFromUrl(send func(*http.Request) (*http.Response, error), request *http.Request) (string, error) {
+	// split the url path to get the name of the file (like basic.ics)
+	tokens := strings.Split(request.URL.Path, "/")
 
 	// creates the path
 	os.MkdirAll(FilePath, 0777)
 
-	// creates the file in the path folder
-	output, err := os.Create(fileName)
+	// get the URL
+	response, err := send(request)
 
 	if err != nil {
 
 		return "", err
 	}
-	// close the file
-	defer output.Close()
+	// close the response body
+	defer response.Body.Close()
 
-	// get the URL
-	response, err := http.Get(url)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("Failed to download %s: %s", request.URL, response.Status)
+	}
+
+	// creates the file in the path folder, the random part keeps parallel downloads of the same name apart
+	output, err := ioutil.TempFile(FilePath, fmt.Sprintf("%s_*_%s", time.Now().Format(uts), tokens[len(tokens)-1]))
 
 	if err != nil {
 
 		return "", err
 	}
-	// close the response body
-	defer response.Body.Close()
+	fileName := output.Name()
+	// close the file
+	defer output.Close()
 
 	// copy the response from the url to the temp local file
 	_, err = io.Copy(output, response.Body)
 
 	if err != nil {
-
+		os.Remove(fileName)
 		return "", err
 	}
 
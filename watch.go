@@ -0,0 +1,293 @@
+package ics
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the kinds of the changes of the files of a watched directory
+const (
+	FileCreated  ChangeType = "created"
+	FileModified ChangeType = "modified"
+	FileDeleted  ChangeType = "deleted"
+)
+
+// DirChange is a change of a calendar file of a watched directory
+type DirChange struct {
+	Type ChangeType
+	Path string
+	// the calendars of the file, the last ones for FileDeleted
+	Calendars []*Calendar
+	// the error reading the file, its last calendars are kept
+	Err error
+}
+
+// the PollInterval of the watchers without a positive one
+const defaultPollInterval = 10 * time.Second
+
+// DirWatcher parses the calendar files of a directory matching its patterns
+// and keeps them up to date. The files are checked every PollInterval, and
+// on linux as soon as they change too.
+type DirWatcher struct {
+	// 10 seconds when it is not positive
+	PollInterval time.Duration
+
+	parser   *Parser
+	dir      string
+	patterns []string
+	changes  chan DirChange
+	mutex    sync.Mutex
+	files    map[string]*watchedFile
+	notifier dirNotifier
+	stop     chan struct{}
+	// one scan at a time
+	scan sync.Mutex
+}
+
+// watchedFile is the state of a file of a watched directory
+type watchedFile struct {
+	modTime   time.Time
+	size      int64
+	calendars []*Calendar
+}
+
+// dirNotifier signals the changes of the directories added to it
+type dirNotifier interface {
+	add(dir string) error
+	events() <-chan struct{}
+	close() error
+}
+
+// WatchDir creates a watcher of the files of the directory matching the
+// patterns, "*.ics" by default. The patterns are matched against the path
+// relative to the directory with filepath.Match, "*/*.ics" matches the
+// files of the subdirectories. The files are read directly with the limits
+// of the parser, its fetchers and fetch policy are for the urls.
+func (p *Parser) WatchDir(dir string, patterns ...string) (*DirWatcher, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*.ics"}
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	return &DirWatcher{
+		PollInterval: defaultPollInterval,
+		parser:       p,
+		dir:          filepath.Clean(dir),
+		patterns:     patterns,
+		changes:      make(chan DirChange, 100),
+		files:        map[string]*watchedFile{},
+	}, nil
+}
+
+// Changes returns the chan of the changes, it must be read for the scans
+// to go on
+func (w *DirWatcher) Changes() <-chan DirChange {
+	return w.changes
+}
+
+// GetCalendars returns the calendars of the files, ordered by path
+func (w *DirWatcher) GetCalendars() []*Calendar {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	paths := []string{}
+	for path := range w.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	calendars := []*Calendar{}
+	for _, path := range paths {
+		calendars = append(calendars, w.files[path].calendars...)
+	}
+	return calendars
+}
+
+// reports whether the path relative to the directory matches a pattern
+func (w *DirWatcher) matches(rel string) bool {
+	for _, pattern := range w.patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan reads the files created or modified since the last scan and sends
+// their changes. It returns the error of reading the directory.
+func (w *DirWatcher) Scan() error {
+	w.scan.Lock()
+	defer w.scan.Unlock()
+
+	found := map[string]os.FileInfo{}
+	err := filepath.Walk(w.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == w.dir {
+				return err
+			}
+			// removed during the walk
+			return nil
+		}
+		if info.IsDir() {
+			w.mutex.Lock()
+			notifier := w.notifier
+			w.mutex.Unlock()
+			if notifier != nil {
+				notifier.add(path)
+			}
+			return nil
+		}
+		if rel, err := filepath.Rel(w.dir, path); err == nil && w.matches(rel) {
+			found[path] = info
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	paths := []string{}
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	changes := []DirChange{}
+	for _, path := range paths {
+		info := found[path]
+		w.mutex.Lock()
+		old, ok := w.files[path]
+		w.mutex.Unlock()
+		if ok && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+			continue
+		}
+		change := DirChange{Type: FileCreated, Path: path}
+		if ok {
+			change.Type = FileModified
+		}
+		file := &watchedFile{modTime: info.ModTime(), size: info.Size()}
+		file.calendars, change.Err = w.parseFile(path)
+		if change.Err != nil && ok {
+			file.calendars = old.calendars
+		}
+		change.Calendars = file.calendars
+		w.mutex.Lock()
+		w.files[path] = file
+		w.mutex.Unlock()
+		changes = append(changes, change)
+	}
+
+	w.mutex.Lock()
+	deleted := []string{}
+	for path := range w.files {
+		if _, ok := found[path]; !ok {
+			deleted = append(deleted, path)
+		}
+	}
+	sort.Strings(deleted)
+	for _, path := range deleted {
+		changes = append(changes, DirChange{Type: FileDeleted, Path: path, Calendars: w.files[path].calendars})
+		delete(w.files, path)
+	}
+	w.mutex.Unlock()
+
+	for _, change := range changes {
+		w.changes <- change
+	}
+	return nil
+}
+
+// returns the calendars of the file, of every file of a zip archive
+func (w *DirWatcher) parseFile(path string) ([]*Calendar, error) {
+	open := FetcherFunc(func(path string) (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+	files, _, err := w.parser.readICal(open, path)
+	if err != nil {
+		return nil, err
+	}
+	calendars := []*Calendar{}
+	for _, file := range files {
+		cal, _, err := w.parser.parseCalendar(file.content, path)
+		if err != nil {
+			return nil, err
+		}
+		cal.SetArchivePath(file.path)
+		calendars = append(calendars, cal)
+	}
+	return calendars, nil
+}
+
+// Start scans the directory, then scans it in the background every
+// PollInterval and on the changes of its files where they are notified
+func (w *DirWatcher) Start() error {
+	w.mutex.Lock()
+	if w.stop != nil {
+		w.mutex.Unlock()
+		return nil
+	}
+	// without a notifier the directory is only polled
+	notifier, _ := newDirNotifier()
+	w.notifier = notifier
+	w.stop = make(chan struct{})
+	stop := w.stop
+	w.mutex.Unlock()
+
+	if err := w.Scan(); err != nil {
+		w.Stop()
+		return err
+	}
+	go w.run(stop, notifier)
+	return nil
+}
+
+// Stop ends the background scans
+func (w *DirWatcher) Stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	w.stop = nil
+	if w.notifier != nil {
+		w.notifier.close()
+		w.notifier = nil
+	}
+}
+
+func (w *DirWatcher) run(stop chan struct{}, notifier dirNotifier) {
+	interval := w.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var events <-chan struct{}
+	if notifier != nil {
+		events = notifier.events()
+	}
+	// the notified changes are scanned once the writes settle
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			settle = time.After(50 * time.Millisecond)
+		case <-settle:
+			settle = nil
+			w.Scan()
+		case <-ticker.C:
+			w.Scan()
+		}
+	}
+}
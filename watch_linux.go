@@ -0,0 +1,62 @@
+//go:build linux
+// +build linux
+
+package ics
+
+import (
+	"os"
+	"syscall"
+)
+
+// inotifyNotifier signals the changes of the directories with inotify
+type inotifyNotifier struct {
+	file   *os.File
+	fd     int
+	notify chan struct{}
+}
+
+// returns the inotify notifier
+func newDirNotifier() (dirNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	n := &inotifyNotifier{
+		// the non blocking file is read with the poller, closing it ends the read
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		notify: make(chan struct{}, 1),
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotifyNotifier) add(dir string) error {
+	// adding a directory twice keeps its single watch
+	_, err := syscall.InotifyAddWatch(n.fd, dir, syscall.IN_CREATE|syscall.IN_CLOSE_WRITE|syscall.IN_DELETE|
+		syscall.IN_MOVED_FROM|syscall.IN_MOVED_TO|syscall.IN_ATTRIB)
+	return err
+}
+
+func (n *inotifyNotifier) events() <-chan struct{} {
+	return n.notify
+}
+
+func (n *inotifyNotifier) close() error {
+	return n.file.Close()
+}
+
+// signals every read of events, the scan finds what changed
+func (n *inotifyNotifier) read() {
+	defer close(n.notify)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			return
+		}
+		select {
+		case n.notify <- struct{}{}:
+		default:
+		}
+	}
+}
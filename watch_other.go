@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package ics
+
+import "errors"
+
+// the directories are only polled out of linux
+func newDirNotifier() (dirNotifier, error) {
+	return nil, errors.New("The changes of the files are not notified on this system")
+}
@@ -0,0 +1,160 @@
+package ics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// returns the changes sent, as "type file calendar"
+func readDirChanges(w *DirWatcher) []string {
+	found := []string{}
+	for {
+		select {
+		case change := <-w.Changes():
+			name := ""
+			if len(change.Calendars) > 0 {
+				name = change.Calendars[0].GetName()
+			}
+			if change.Err != nil {
+				name = "error"
+			}
+			found = append(found, string(change.Type)+" "+filepath.Base(change.Path)+" "+name)
+		default:
+			return found
+		}
+	}
+}
+
+func writeCal(t *testing.T, path, name string) {
+	if err := ioutil.WriteFile(path, []byte(strings.Replace(fetchTestCal, "Fetched", name, 1)), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchDirScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "team"), 0777)
+	writeCal(t, filepath.Join(dir, "a.ics"), "A")
+	writeCal(t, filepath.Join(dir, "team", "b.ics"), "B")
+	writeCal(t, filepath.Join(dir, "deep.ics.txt"), "Ignored")
+
+	if _, err := New().WatchDir(dir, "[.ics"); err == nil {
+		t.Errorf("Expected an error of the bad pattern")
+	}
+	w, err := New().SetLimits(Limits{MaxBytes: 4096}).WatchDir(dir, "*.ics", "team/*.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		change   func()
+		expected []string
+	}{
+		{func() {}, []string{"created a.ics A", "created b.ics B"}},
+		{func() {}, []string{}},
+		{func() {
+			writeCal(t, filepath.Join(dir, "a.ics"), "Renamed")
+			// the modification times of the file systems can be coarse
+			os.Chtimes(filepath.Join(dir, "a.ics"), time.Now(), time.Now().Add(time.Hour))
+			writeCal(t, filepath.Join(dir, "c.ics"), "C")
+		}, []string{"modified a.ics Renamed", "created c.ics C"}},
+		{func() {
+			ioutil.WriteFile(filepath.Join(dir, "c.ics"), []byte(strings.Repeat("X-FILLER:0\r\n", 1000)), 0666)
+			os.Chtimes(filepath.Join(dir, "c.ics"), time.Now(), time.Now().Add(2*time.Hour))
+		}, []string{"modified c.ics error"}},
+		{func() {
+			os.Remove(filepath.Join(dir, "team", "b.ics"))
+		}, []string{"deleted b.ics B"}},
+	}
+	for i, step := range steps {
+		step.change()
+		if err := w.Scan(); err != nil {
+			t.Fatal(err)
+		}
+		found := readDirChanges(w)
+		if strings.Join(found, "\n") != strings.Join(step.expected, "\n") {
+			t.Errorf("Expected the changes of step %d\n%s\nfound\n%s", i, strings.Join(step.expected, "\n"), strings.Join(found, "\n"))
+		}
+	}
+
+	// the file over the limits keeps its last calendar
+	names := []string{}
+	for _, cal := range w.GetCalendars() {
+		names = append(names, cal.GetName())
+	}
+	if strings.Join(names, ",") != "Renamed,C" {
+		t.Errorf("Expected the calendars Renamed,C found %s", strings.Join(names, ","))
+	}
+
+	os.RemoveAll(dir)
+	if err := w.Scan(); err == nil {
+		t.Errorf("Expected an error of the removed directory")
+	}
+}
+
+func TestWatchDirStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeCal(t, filepath.Join(dir, "a.ics"), "A")
+
+	w, _ := New().WatchDir(dir)
+	w.PollInterval = 20 * time.Millisecond
+	if runtime.GOOS == "linux" {
+		// the changes are notified by inotify
+		w.PollInterval = time.Hour
+	}
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	timeout := time.After(5 * time.Second)
+	for _, expected := range []string{"created a.ics A", "created b.ics B", "deleted a.ics A"} {
+		switch expected {
+		case "created b.ics B":
+			writeCal(t, filepath.Join(dir, "b.ics"), "B")
+		case "deleted a.ics A":
+			os.Remove(filepath.Join(dir, "a.ics"))
+		}
+		select {
+		case change := <-w.Changes():
+			found := string(change.Type) + " " + filepath.Base(change.Path) + " " + change.Calendars[0].GetName()
+			if found != expected {
+				t.Errorf("Expected the change %s, found %s", expected, found)
+			}
+		case <-timeout:
+			t.Fatalf("Expected the change %s", expected)
+		}
+	}
+}
+
+func TestWatchDirUnderPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ics-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeCal(t, filepath.Join(dir, "a.ics"), "A")
+
+	// the local files denied to the urls are still watched
+	w, _ := New().SetFetchPolicy(NewSafeFetchPolicy()).WatchDir(dir)
+	w.PollInterval = 0
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if found := readDirChanges(w); len(found) != 1 || found[0] != "created a.ics A" {
+		t.Errorf("Expected the file to be parsed, found %v", found)
+	}
+}
@@ -0,0 +1,341 @@
+package ics
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// XML namespace of xCal documents
+const XCalNamespace = "urn:ietf:params:xml:ns:icalendar-2.0"
+
+// value types of the parameters, the others are text
+var paramTypes = map[string]string{
+	"ALTREP":         "uri",
+	"DIR":            "uri",
+	"MEMBER":         "cal-address",
+	"DELEGATED-TO":   "cal-address",
+	"DELEGATED-FROM": "cal-address",
+	"SENT-BY":        "cal-address",
+}
+
+// element names of the structured property values
+var structuredParts = map[string][]string{
+	"GEO":            {"latitude", "longitude"},
+	"REQUEST-STATUS": {"code", "description", "data"},
+}
+
+// xmlNode is a generic XML element of a xCal document
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",chardata"`
+	Nodes   []xmlNode  `xml:",any"`
+}
+
+func newXMLNode(name, content string) xmlNode {
+	return xmlNode{XMLName: xml.Name{Local: name}, Content: content}
+}
+
+// returns the first child with the given name
+func (n *xmlNode) child(name string) *xmlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// ======================== XCAL ENCODING ===================
+
+// builds the xCal element of the component
+func (c *component) xcal() xmlNode {
+	node := newXMLNode(strings.ToLower(c.name), "")
+	if len(c.props) > 0 {
+		props := newXMLNode("properties", "")
+		for _, prop := range c.props {
+			props.Nodes = append(props.Nodes, prop.xcal())
+		}
+		node.Nodes = append(node.Nodes, props)
+	}
+	if len(c.comps) > 0 {
+		comps := newXMLNode("components", "")
+		for _, sub := range c.comps {
+			comps.Nodes = append(comps.Nodes, sub.xcal())
+		}
+		node.Nodes = append(node.Nodes, comps)
+	}
+	return node
+}
+
+// builds the xCal element of the property
+func (p *property) xcal() xmlNode {
+	node := newXMLNode(strings.ToLower(p.name), "")
+
+	params := newXMLNode("parameters", "")
+	for _, prm := range p.params {
+		if prm.name == "VALUE" {
+			continue
+		}
+		paramType, ok := paramTypes[prm.name]
+		if !ok {
+			paramType = "text"
+		}
+		values := []string{prm.value}
+		if multiValueParams[prm.name] {
+			values = strings.Split(prm.value, ",")
+		}
+		paramNode := newXMLNode(strings.ToLower(prm.name), "")
+		for _, value := range values {
+			paramNode.Nodes = append(paramNode.Nodes, newXMLNode(paramType, value))
+		}
+		params.Nodes = append(params.Nodes, paramNode)
+	}
+	if len(params.Nodes) > 0 {
+		node.Nodes = append(node.Nodes, params)
+	}
+
+	valueType := p.valueType()
+	for _, parts := range p.values() {
+		if names, ok := structuredParts[p.name]; ok {
+			for i, part := range parts {
+				if i < len(names) {
+					node.Nodes = append(node.Nodes, newXMLNode(names[i], extendedValue(valueType, part)))
+				}
+			}
+			continue
+		}
+		node.Nodes = append(node.Nodes, xcalValue(valueType, parts[0]))
+	}
+	return node
+}
+
+// builds the value element of a single value
+func xcalValue(valueType, value string) xmlNode {
+	switch valueType {
+	case "recur":
+		recur := newXMLNode("recur", "")
+		for _, part := range strings.Split(value, ";") {
+			pair := strings.SplitN(part, "=", 2)
+			if len(pair) != 2 {
+				continue
+			}
+			name := strings.ToLower(pair[0])
+			for _, item := range strings.Split(pair[1], ",") {
+				if name == "until" && len(item) == 8 {
+					item = extendedDate(item)
+				} else if name == "until" {
+					item = extendedDateTime(item)
+				}
+				recur.Nodes = append(recur.Nodes, newXMLNode(name, item))
+			}
+		}
+		return recur
+	case "period":
+		period := newXMLNode("period", "")
+		parts := strings.SplitN(value, "/", 2)
+		period.Nodes = append(period.Nodes, newXMLNode("start", extendedDateTime(parts[0])))
+		if len(parts) == 2 {
+			if isDuration(parts[1]) {
+				period.Nodes = append(period.Nodes, newXMLNode("duration", parts[1]))
+			} else {
+				period.Nodes = append(period.Nodes, newXMLNode("end", extendedDateTime(parts[1])))
+			}
+		}
+		return period
+	case "boolean":
+		return newXMLNode(valueType, strings.ToLower(value))
+	}
+	return newXMLNode(valueType, extendedValue(valueType, value))
+}
+
+// ======================== XCAL DECODING ===================
+
+// builds a component from its xCal element
+func componentFromXCal(node *xmlNode) (*component, error) {
+	c := newComponent(strings.ToUpper(node.XMLName.Local))
+	for i := range node.Nodes {
+		child := &node.Nodes[i]
+		switch child.XMLName.Local {
+		case "properties":
+			for j := range child.Nodes {
+				c.props = append(c.props, propertyFromXCal(&child.Nodes[j]))
+			}
+		case "components":
+			for j := range child.Nodes {
+				sub, err := componentFromXCal(&child.Nodes[j])
+				if err != nil {
+					return nil, err
+				}
+				c.comps = append(c.comps, sub)
+			}
+		default:
+			return nil, fmt.Errorf("Unexpected element <%s> in <%s>", child.XMLName.Local, node.XMLName.Local)
+		}
+	}
+	return c, nil
+}
+
+// builds a property from its xCal element
+func propertyFromXCal(node *xmlNode) *property {
+	prop := &property{name: strings.ToUpper(node.XMLName.Local)}
+
+	valueType := ""
+	values := []string{}
+	structured := map[string]string{}
+	for i := range node.Nodes {
+		child := &node.Nodes[i]
+		name := child.XMLName.Local
+		switch {
+		case name == "parameters":
+			for _, prm := range child.Nodes {
+				items := make([]string, len(prm.Nodes))
+				for j, item := range prm.Nodes {
+					items[j] = item.Content
+				}
+				prop.params = append(prop.params, param{strings.ToUpper(prm.XMLName.Local), strings.Join(items, ",")})
+			}
+		case structuredParts[prop.name] != nil:
+			structured[name] = child.Content
+		default:
+			valueType = name
+			values = append(values, icalValueFromXCal(child))
+		}
+	}
+
+	if names, ok := structuredParts[prop.name]; ok {
+		valueType = valueTypes[prop.name]
+		parts := []string{}
+		for _, name := range names {
+			if value, ok := structured[name]; ok {
+				parts = append(parts, basicValue(valueType, value))
+			}
+		}
+		values = append(values, strings.Join(parts, ";"))
+	}
+
+	defaultType, ok := valueTypes[prop.name]
+	if !ok {
+		defaultType = "unknown"
+	}
+	if valueType != "" && valueType != defaultType && valueType != "unknown" {
+		prop.params = append(prop.params, param{"VALUE", strings.ToUpper(valueType)})
+	}
+	prop.value = strings.Join(values, ",")
+	return prop
+}
+
+// converts a value element to its iCalendar notation
+func icalValueFromXCal(node *xmlNode) string {
+	switch node.XMLName.Local {
+	case "recur":
+		parts := []string{}
+		index := map[string]int{}
+		for _, item := range node.Nodes {
+			name := strings.ToUpper(item.XMLName.Local)
+			value := item.Content
+			if name == "UNTIL" {
+				value = basicFormat(value)
+			}
+			// repeated elements are the items of a list
+			if i, ok := index[name]; ok {
+				parts[i] += "," + value
+				continue
+			}
+			index[name] = len(parts)
+			parts = append(parts, name+"="+value)
+		}
+		return strings.Join(parts, ";")
+	case "period":
+		value := ""
+		if start := node.child("start"); start != nil {
+			value = basicFormat(start.Content)
+		}
+		if end := node.child("end"); end != nil {
+			value += "/" + basicFormat(end.Content)
+		} else if duration := node.child("duration"); duration != nil {
+			value += "/" + duration.Content
+		}
+		return value
+	}
+	return basicValue(node.XMLName.Local, node.Content)
+}
+
+// ======================== PUBLIC API ===================
+
+// marshals the root element of a xCal document
+func marshalXCal(root xmlNode) ([]byte, error) {
+	// the namespace is written as attribute so the children don't repeat it
+	root.Attrs = []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: XCalNamespace}}
+	data, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// ICalToXCal converts an iCalendar stream to a xCal (RFC 6321) document.
+// All properties, parameters and sub components are kept.
+func ICalToXCal(iCalContent string) ([]byte, error) {
+	c, err := readComponent(iCalContent)
+	if err != nil {
+		return nil, err
+	}
+	root := newXMLNode("icalendar", "")
+	root.Nodes = append(root.Nodes, c.xcal())
+	return marshalXCal(root)
+}
+
+// XCalToICal converts a xCal (RFC 6321) document to an iCalendar stream.
+// The first calendar of the document is converted, a single component
+// other than vcalendar is wrapped in a calendar.
+func XCalToICal(data []byte) (string, error) {
+	var root xmlNode
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return "", err
+	}
+	node := &root
+	if root.XMLName.Local == "icalendar" {
+		if len(root.Nodes) == 0 {
+			return "", errors.New("xCal document has no calendar")
+		}
+		node = &root.Nodes[0]
+	}
+	c, err := componentFromXCal(node)
+	if err != nil {
+		return "", err
+	}
+	if c.name != "VCALENDAR" {
+		cal := newComponent("VCALENDAR")
+		cal.addProp("VERSION", "2.0")
+		cal.addProp("PRODID", ProdID)
+		cal.comps = append(cal.comps, c)
+		c = cal
+	}
+	return c.String(), nil
+}
+
+// MarshalXCal returns the calendar and its events as a xCal (RFC 6321) document
+func (c *Calendar) MarshalXCal() ([]byte, error) {
+	root := newXMLNode("icalendar", "")
+	root.Nodes = append(root.Nodes, c.component().xcal())
+	return marshalXCal(root)
+}
+
+// MarshalXCal returns the event as a xCal (RFC 6321) vevent element
+func (e *Event) MarshalXCal() ([]byte, error) {
+	return marshalXCal(e.component().xcal())
+}
+
+// LoadXCal parses a xCal (RFC 6321) document like Load does for the
+// iCalendar format
+func (p *Parser) LoadXCal(data []byte) error {
+	content, err := XCalToICal(data)
+	if err != nil {
+		return err
+	}
+	p.Load(content)
+	return nil
+}
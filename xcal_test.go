@@ -0,0 +1,121 @@
+package ics
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTestCalendar(t *testing.T, name string) string {
+	content, err := ioutil.ReadFile("testCalendars/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read %s ( %s )", name, err)
+	}
+	return string(content)
+}
+
+// normalizes the line endings and folding of an iCalendar stream
+func normalizeICal(content string) string {
+	return strings.Replace(unfoldICal(content), "\r\n", "\n", -1)
+}
+
+// the examples of RFC 6321 appendix B
+func TestXCalRFCExamples(t *testing.T) {
+	for _, example := range []string{"rfc6321-b1", "rfc6321-b2"} {
+		ical := readTestCalendar(t, example+".ics")
+		xcal := readTestCalendar(t, example+".xml")
+
+		back, err := XCalToICal([]byte(xcal))
+		if err != nil {
+			t.Fatalf("%s: failed to convert to iCalendar ( %s )", example, err)
+		}
+		if normalizeICal(back) != normalizeICal(ical) {
+			t.Errorf("%s: expected iCalendar:\n%s\nfound:\n%s", example, ical, back)
+		}
+
+		data, err := ICalToXCal(ical)
+		if err != nil {
+			t.Fatalf("%s: failed to convert to xCal ( %s )", example, err)
+		}
+		back, err = XCalToICal(data)
+		if err != nil {
+			t.Fatalf("%s: failed to convert back to iCalendar ( %s )", example, err)
+		}
+		if normalizeICal(back) != normalizeICal(ical) {
+			t.Errorf("%s: expected lossless round trip:\n%s\nfound:\n%s", example, ical, back)
+		}
+	}
+}
+
+func TestXCalValueTypes(t *testing.T) {
+	data, err := ICalToXCal(valueTypesICal)
+	if err != nil {
+		t.Fatalf("Failed to convert to xCal ( %s )", err)
+	}
+	// the fragments are compared without the indentation
+	xcal := regexp.MustCompile(`>\s+<`).ReplaceAllString(string(data), "><")
+	for _, fragment := range []string{
+		`<icalendar xmlns="urn:ietf:params:xml:ns:icalendar-2.0">`,
+		"<tzoffsetto><utc-offset>+02:00</utc-offset>",
+		"<recur><freq>WEEKLY</freq><until>2024-07-01T00:00:00Z</until><byday>MO</byday><byday>WE</byday>",
+		"<summary><parameters><language><text>en</text>",
+		"<text>Review; part 1&#xA;bring notes</text>",
+		"<latitude>37.386013</latitude><longitude>-122.082932</longitude>",
+		"<member><cal-address>mailto:dev@example.com</cal-address>",
+		"<code>2.0</code><description>Success</description>",
+		"<x-custom><parameters><x-param><text>yes</text></x-param></parameters><unknown>raw\\,value</unknown>",
+		"<trigger><date-time>2024-06-10T05:45:00Z</date-time>",
+	} {
+		if !strings.Contains(xcal, fragment) {
+			t.Errorf("Expected xCal to contain %q, found:\n%s", fragment, xcal)
+		}
+	}
+
+	back, err := XCalToICal(data)
+	if err != nil {
+		t.Fatalf("Failed to convert to iCalendar ( %s )", err)
+	}
+	if unfold(back) != valueTypesICal {
+		t.Errorf("Expected lossless round trip:\n%s\nfound:\n%s", valueTypesICal, back)
+	}
+}
+
+func TestXCalCalendarRoundTrip(t *testing.T) {
+	event, err := NewEventBuilder().
+		UID("review@example.com").
+		Summary("Design review").
+		Start(time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC), nil).
+		Duration(time.Hour).
+		Organizer("Boss", "boss@example.com").
+		Attendee(NewAttendee().SetName("John Smith").SetEmail("j.smith@example.com")).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build event ( %s )", err)
+	}
+	cal, err := NewCalendarBuilder().Name("Reviews").Event(event).Build()
+	if err != nil {
+		t.Fatalf("Failed to build calendar ( %s )", err)
+	}
+
+	data, err := cal.MarshalXCal()
+	if err != nil {
+		t.Fatalf("Failed to marshal xCal ( %s )", err)
+	}
+	parser := New()
+	if err := parser.LoadXCal(data); err != nil {
+		t.Fatalf("Failed to load xCal ( %s )", err)
+	}
+	calendars, _ := parser.GetCalendars()
+	if len(calendars) != 1 {
+		t.Fatalf("Expected 1 calendar, found %d calendars", len(calendars))
+	}
+	if calendars[0].Serialize() != cal.Serialize() {
+		t.Errorf("Expected the same calendar after the round trip:\n%s\nfound:\n%s", cal.Serialize(), calendars[0].Serialize())
+	}
+
+	if err := parser.LoadXCal([]byte(`<icalendar><vcalendar><unknown/></vcalendar></icalendar>`)); err == nil {
+		t.Errorf("Expected error for invalid xCal")
+	}
+}